@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditRole is one role an account holds; SpaceID 0 is the global space.
+type AuditRole struct {
+	SpaceID int64  `json:"spaceID"`
+	Role    string `json:"role"`
+}
+
+// AuditTarget is an account affected by an operation, with its roles as
+// read from metad before and after the operation ran.
+type AuditTarget struct {
+	User        string      `json:"user"`
+	RolesBefore []AuditRole `json:"rolesBefore"`
+	RolesAfter  []AuditRole `json:"rolesAfter"`
+}
+
+type AuditEvent struct {
+	Time       time.Time     `json:"time"`
+	Operation  string        `json:"operation"`
+	Caller     string        `json:"caller"`
+	Account    string        `json:"account,omitempty"`
+	InstanceID string        `json:"instanceID"`
+	Space      string        `json:"space,omitempty"`
+	Role       string        `json:"role,omitempty"`
+	Snapshot   string        `json:"snapshot,omitempty"`
+	Targets    []AuditTarget `json:"targets,omitempty"`
+	Result     string        `json:"result"`
+	Status     int           `json:"status"`
+	Code       int           `json:"code"`
+
+	// Config changes record the flag, as MODULE/name, and its values.
+	Config        string `json:"config,omitempty"`
+	Value         string `json:"value,omitempty"`
+	PreviousValue string `json:"previousValue,omitempty"`
+}
+
+type AuditResponse struct {
+	Code   int
+	Events []AuditEvent
+}
+
+// AuditFilter selects audit events; zero fields match everything. It is
+// passed to PathAudit as query parameters of the same (lower case) names.
+type AuditFilter struct {
+	InstanceID string
+	Operation  string
+	Caller     string
+	User       string
+	Result     string
+	Since      time.Time
+	Until      time.Time
+	// Limit keeps only the most recent events.
+	Limit int
+}
+
+func (f *AuditFilter) Match(event *AuditEvent) bool {
+	if f.InstanceID != "" && event.InstanceID != f.InstanceID {
+		return false
+	}
+	if f.Operation != "" && event.Operation != f.Operation {
+		return false
+	}
+	if f.Caller != "" && event.Caller != f.Caller {
+		return false
+	}
+	if f.Result != "" && event.Result != f.Result {
+		return false
+	}
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.Time.Before(f.Until) {
+		return false
+	}
+	if f.User != "" {
+		found := event.Account == f.User
+		for _, target := range event.Targets {
+			found = found || target.User == f.User
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *AuditFilter) Query() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("instanceID", f.InstanceID)
+	set("operation", f.Operation)
+	set("caller", f.Caller)
+	set("user", f.User)
+	set("result", f.Result)
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		q.Set("until", f.Until.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return q
+}
+
+func ParseAuditFilter(q url.Values) (*AuditFilter, error) {
+	f := &AuditFilter{
+		InstanceID: q.Get("instanceID"),
+		Operation:  q.Get("operation"),
+		Caller:     q.Get("caller"),
+		User:       q.Get("user"),
+		Result:     q.Get("result"),
+	}
+
+	for _, param := range []struct {
+		key string
+		t   *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if value := q.Get(param.key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", param.key, err)
+			}
+			*param.t = parsed
+		}
+	}
+
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("limit: must be a non-negative integer")
+		}
+		f.Limit = limit
+	}
+
+	return f, nil
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseAuditFilter(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    AuditFilter
+		wantErr bool
+	}{
+		{"none", "", AuditFilter{}, false},
+		{"since", "since=2026-03-01T00:00:00Z", AuditFilter{Since: since}, false},
+		{"until", "until=2026-03-02T00:00:00Z", AuditFilter{Until: until}, false},
+		// Both at the same time must still set both.
+		{"same time", "since=2026-03-01T00:00:00Z&until=2026-03-01T00:00:00Z", AuditFilter{Since: since, Until: since}, false},
+		{"all", "instanceID=nebula&operation=CreateUser&caller=console&user=root&result=success&since=2026-03-01T00:00:00Z&until=2026-03-02T00:00:00Z&limit=10",
+			AuditFilter{InstanceID: "nebula", Operation: "CreateUser", Caller: "console", User: "root", Result: AuditResultSuccess,
+				Since: since, Until: until, Limit: 10}, false},
+		{"not RFC 3339", "until=2026-03-02", AuditFilter{}, true},
+		{"negative limit", "limit=-1", AuditFilter{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := ParseAuditFilter(q)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseAuditFilter = %+v, want an error", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *f != test.want {
+				t.Errorf("ParseAuditFilter = %+v, want %+v", *f, test.want)
+			}
+			if again, err := ParseAuditFilter(f.Query()); err != nil || *again != *f {
+				t.Errorf("ParseAuditFilter(Query()) = %+v, %v, want %+v", again, err, *f)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+const (
+	BalanceInProgress = "IN_PROGRESS"
+	BalanceSucceeded  = "SUCCEEDED"
+	BalanceFailed     = "FAILED"
+)
+
+type BalanceRequest struct {
+	InstanceID string `validate:"required,instance"`
+}
+
+// BalanceStatusRequest asks after the data balance plan PlanID, or the one
+// last started through any replica of the wrapper if it is 0.
+type BalanceStatusRequest struct {
+	InstanceID string `validate:"required,instance"`
+	PlanID     int64  `validate:"min=0"`
+}
+
+// BalanceTask moves one partition replica; its ID names the partition and
+// the hosts it moves between.
+type BalanceTask struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+}
+
+// BalancePlan is a data balance plan and the progress of its tasks. Status
+// is IN_PROGRESS while any task is yet to finish, then FAILED if any task
+// failed and SUCCEEDED otherwise.
+type BalancePlan struct {
+	ID         int64         `json:"id"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Succeeded  int           `json:"succeeded"`
+	Failed     int           `json:"failed"`
+	InProgress int           `json:"inProgress"`
+	Tasks      []BalanceTask `json:"tasks"`
+}
+
+// BalanceResponse carries the plan started, stopped or asked after.
+// Balanced is set, and Plan empty, when starting a data balance found the
+// partitions balanced already.
+type BalanceResponse struct {
+	Code     int
+	Balanced bool
+	Plan     BalancePlan
+}
+
+type BalanceLeaderResponse struct {
+	Code int
+}
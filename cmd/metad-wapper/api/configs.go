@@ -0,0 +1,54 @@
+package api
+
+// ConfigItem is a flag of a Nebula component registered with metad. Value is
+// rendered as text whatever its Type: INT64, DOUBLE, BOOL, STRING, or NESTED
+// for a JSON object of options. Only MUTABLE flags can be set while the
+// component runs.
+type ConfigItem struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Mode   string `json:"mode"`
+	Value  string `json:"value"`
+}
+
+// ListConfigsRequest lists the mutable flags of Module, of every module if
+// it is empty, and the others too if All is set.
+type ListConfigsRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Module     string `validate:"module"`
+	All        bool
+}
+
+type ListConfigsResponse struct {
+	Code    int
+	Configs []ConfigItem
+}
+
+type GetConfigRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Module     string `validate:"required,module"`
+	Name       string `validate:"required,name,max=128"`
+}
+
+type GetConfigResponse struct {
+	Code   int
+	Config ConfigItem
+}
+
+// SetConfigRequest sets a mutable flag. Value must parse as the type the
+// flag is registered with.
+type SetConfigRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Module     string `validate:"required,module"`
+	Name       string `validate:"required,name,max=128"`
+	Value      string `validate:"max=4096"`
+}
+
+// SetConfigResponse carries the flag as set and the value it had before, so
+// the change can be reverted.
+type SetConfigResponse struct {
+	Code          int
+	Config        ConfigItem
+	PreviousValue string
+}
@@ -0,0 +1,27 @@
+package api
+
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// DependencyStatus is the outcome of checking one dependency of the wrapper,
+// or the metad of one instance.
+type DependencyStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Latency is how long the check took, in seconds.
+	Latency float64 `json:"latency"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// StatusResponse reports the wrapper StatusOK when every dependency is, and
+// StatusDegraded otherwise. Instances is only filled in when the metad sweep
+// was asked for.
+type StatusResponse struct {
+	Code         int
+	Status       string
+	Dependencies []DependencyStatus
+	Instances    []DependencyStatus `json:",omitempty"`
+}
@@ -0,0 +1,37 @@
+package api
+
+type StorageHostsRequest struct {
+	InstanceID string `validate:"required,instance"`
+}
+
+// SpacePartitions is the share of a space's partitions a host holds.
+type SpacePartitions struct {
+	Space string `json:"space"`
+	// Partitions counts the partitions the host holds a replica of, Leaders
+	// those it leads and Lost those whose replica on it metad counts lost.
+	Partitions int `json:"partitions"`
+	Leaders    int `json:"leaders"`
+	Lost       int `json:"lost"`
+}
+
+// StorageHost is a storaged host registered with metad, with the pod and
+// node it runs on if it is a pod of the instance.
+type StorageHost struct {
+	Host   string `json:"host"`
+	Status string `json:"status"`
+	// LastSeenOnline is when the replica of the wrapper answering last saw
+	// metad list the host online, in RFC3339; metad doesn't tell the time of
+	// heartbeats. It is kept in memory, so empty if that replica never saw
+	// the host online since it started.
+	LastSeenOnline string            `json:"lastSeenOnline,omitempty"`
+	Pod            string            `json:"pod,omitempty"`
+	Node           string            `json:"node,omitempty"`
+	Leaders        int               `json:"leaders"`
+	Partitions     int               `json:"partitions"`
+	Spaces         []SpacePartitions `json:"spaces"`
+}
+
+type StorageHostsResponse struct {
+	Code  int
+	Hosts []StorageHost
+}
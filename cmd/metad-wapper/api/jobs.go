@@ -0,0 +1,66 @@
+package api
+
+// Commands of the admin jobs the wrapper submits. metad 1.x runs no stats
+// job, so space statistics aren't offered.
+const (
+	JobCompact = "compact"
+	JobFlush   = "flush"
+)
+
+type SubmitJobRequest struct {
+	InstanceID string `validate:"required,instance"`
+	SpaceName  string `validate:"required,name,max=64"`
+	Command    string `validate:"required,job"`
+}
+
+type SubmitJobResponse struct {
+	Code  int
+	JobID int32
+}
+
+// ListJobsRequest lists the admin jobs of an instance, only those of
+// SpaceName if it is set.
+type ListJobsRequest struct {
+	InstanceID string `validate:"required,instance"`
+	SpaceName  string `validate:"name,max=64"`
+}
+
+// Job is an admin job. Times are RFC3339 and left out until the job starts
+// or stops.
+type Job struct {
+	ID        int32  `json:"id"`
+	Command   string `json:"command"`
+	Space     string `json:"space"`
+	Status    string `json:"status"`
+	StartTime string `json:"startTime,omitempty"`
+	StopTime  string `json:"stopTime,omitempty"`
+}
+
+type ListJobsResponse struct {
+	Code int
+	Jobs []Job
+}
+
+type JobRequest struct {
+	InstanceID string `validate:"required,instance"`
+	JobID      int32  `validate:"min=1"`
+}
+
+// JobTask is the part of a job one storage host runs.
+type JobTask struct {
+	ID        int32  `json:"id"`
+	Host      string `json:"host"`
+	Status    string `json:"status"`
+	StartTime string `json:"startTime,omitempty"`
+	StopTime  string `json:"stopTime,omitempty"`
+}
+
+type JobResponse struct {
+	Code  int
+	Job   Job
+	Tasks []JobTask
+}
+
+type StopJobResponse struct {
+	Code int
+}
@@ -0,0 +1,23 @@
+package api
+
+// Limits bounds how hard callers can drive the wrapper, and through it the
+// metad of each instance. A zero rate or concurrency means no limit.
+type Limits struct {
+	// CallerRate is the sustained requests per second allowed to each
+	// caller, CallerBurst how many it may send at once. A caller is the one
+	// its bearer token names, or else the address it connects from.
+	CallerRate  float64 `json:"callerRate"`
+	CallerBurst int     `json:"callerBurst"`
+	// InstanceRate and InstanceBurst bound requests to one instance, from
+	// all callers together.
+	InstanceRate  float64 `json:"instanceRate"`
+	InstanceBurst int     `json:"instanceBurst"`
+	// InstanceConcurrency caps the requests working on one instance's metad
+	// at the same time.
+	InstanceConcurrency int `json:"instanceConcurrency"`
+}
+
+type LimitsResponse struct {
+	Code   int
+	Limits Limits
+}
@@ -0,0 +1,4257 @@
+package api
+
+// OpenAPISpec is the OpenAPI 3 document of the API, served at PathOpenAPI.
+const OpenAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "metad-wapper",
+    "description": "HTTP API wrapping the Nebula metad service of each instance.",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/metadwapper/list/spaces": {
+      "post": {
+        "operationId": "listSpaces",
+        "summary": "List the spaces a user can access.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/CacheControl"
+          }
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListSpaceRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListSpaceResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListSpaceResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/list/users": {
+      "post": {
+        "operationId": "listUsers",
+        "summary": "List users holding a role in any space.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/CacheControl"
+          }
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListUsersRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUsersResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUsersResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/create/spaces": {
+      "post": {
+        "operationId": "createSpace",
+        "summary": "Create a space.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CreateSpaceRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "The operation succeeded."
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed."
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/create/users": {
+      "post": {
+        "operationId": "createUser",
+        "summary": "Create a user and grant it a role in a space.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CreateUserRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/clusterCost": {
+      "get": {
+        "operationId": "clusterCost",
+        "summary": "Report resource usage and cost of the cluster and its instances.",
+        "parameters": [
+          {
+            "name": "start",
+            "in": "query",
+            "description": "Start of the billing window; the beginning of the current month, UTC, if not set.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "end",
+            "in": "query",
+            "description": "End of the billing window, not in the future; now if not set.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ClusterCostResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The request could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ClusterCostResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/changeGod": {
+      "post": {
+        "operationId": "changeGod",
+        "summary": "Transfer the GOD role to a new user. 40026 if a user already holding the new name, or the old GOD, could not be dropped.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TransferGodUserRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TransferGodUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TransferGodUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/delete/users": {
+      "post": {
+        "operationId": "revokeUser",
+        "summary": "Revoke a user's role in a space.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/RevokeUserRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RevokeUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RevokeUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/initialize": {
+      "post": {
+        "operationId": "initialize",
+        "summary": "Create the initial GOD user of an instance.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/InitializeRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/list/spaces/users": {
+      "post": {
+        "operationId": "listSpaceUsers",
+        "summary": "List users and roles of a space visible to the operator.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/CacheControl"
+          }
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListUserRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/list/rootspaces/users": {
+      "post": {
+        "operationId": "listRootSpaceUsers",
+        "summary": "List users holding a global role.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/CacheControl"
+          }
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListRootUserRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUserResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListUserResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/instance/version": {
+      "post": {
+        "operationId": "instanceVersion",
+        "summary": "Report component versions and disk usage of an instance.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/InstanceInfoRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/InstanceInfoResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/InstanceInfoResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/storage/hosts": {
+      "post": {
+        "operationId": "storageHosts",
+        "summary": "List the storage hosts metad knows, with their pods and partition distribution.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/StorageHostsRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StorageHostsResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StorageHostsResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/balance/data": {
+      "post": {
+        "operationId": "balanceData",
+        "summary": "Start a data balance plan moving partitions evenly across the storage hosts. Refused with 40019 while metad runs a plan, reported if it was started through the wrapper.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/BalanceRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/balance/leader": {
+      "post": {
+        "operationId": "balanceLeader",
+        "summary": "Spread the partition leaders evenly across the storage hosts. Refused with 40019 while a data balance plan started through the wrapper is running, or if metad refuses.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/BalanceRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceLeaderResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceLeaderResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/balance/stop": {
+      "post": {
+        "operationId": "stopBalance",
+        "summary": "Stop the running data balance plan; the task in flight still finishes. 40020 if no plan is running.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/BalanceRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/balance/status": {
+      "post": {
+        "operationId": "balanceStatus",
+        "summary": "Report the progress of a data balance plan, task by task. 40020 if the plan is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/BalanceStatusRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/BalanceResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/jobs/submit": {
+      "post": {
+        "operationId": "submitJob",
+        "summary": "Submit a compact or flush job on a space. 40016 if the space is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/SubmitJobRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SubmitJobResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SubmitJobResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/jobs/list": {
+      "post": {
+        "operationId": "listJobs",
+        "summary": "List the admin jobs of an instance, newest first.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListJobsRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListJobsResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListJobsResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/jobs/show": {
+      "post": {
+        "operationId": "showJob",
+        "summary": "Show an admin job and the task each storage host runs for it. 40021 if the job is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/JobRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/JobResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/JobResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/jobs/stop": {
+      "post": {
+        "operationId": "stopJob",
+        "summary": "Stop a queued or running admin job. 40021 if the job is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/JobRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StopJobResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StopJobResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/snapshots/create": {
+      "post": {
+        "operationId": "createSnapshot",
+        "summary": "Take a snapshot of an instance, a checkpoint on every storage host.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CreateSnapshotRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateSnapshotResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreateSnapshotResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/snapshots/list": {
+      "post": {
+        "operationId": "listSnapshots",
+        "summary": "List the snapshots of an instance, newest first, with their hosts and the retention policy applied to them.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/SnapshotRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListSnapshotsResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListSnapshotsResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/snapshots/drop": {
+      "post": {
+        "operationId": "dropSnapshot",
+        "summary": "Drop a snapshot. 40022 if it is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/DropSnapshotRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/DropSnapshotResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/DropSnapshotResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/snapshots/policy": {
+      "post": {
+        "operationId": "setSnapshotPolicy",
+        "summary": "Replace the retention policy of the snapshots of an instance, enforced in the background; a zero policy keeps every snapshot.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/SetSnapshotPolicyRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SnapshotPolicyResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SnapshotPolicyResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/configs/list": {
+      "post": {
+        "operationId": "listConfigs",
+        "summary": "List the mutable flags of the Nebula components registered with metad, all of them if All is set.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ListConfigsRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListConfigsResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ListConfigsResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/configs/get": {
+      "post": {
+        "operationId": "getConfig",
+        "summary": "Show a flag of a Nebula component. 40023 if it is unknown.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/GetConfigRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GetConfigResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GetConfigResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/metadwapper/configs/set": {
+      "post": {
+        "operationId": "setConfig",
+        "summary": "Change a mutable flag; the value must parse as the type of the flag. 40023 if the flag is unknown, 40024 if it is not mutable. The previous value is returned and recorded in the audit trail.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/SetConfigRequest"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SetConfigResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The body could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The operation failed; Code carries the reason.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SetConfigResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "The instance is not one the wrapper discovered; Code is 40007.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller or instance is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/audit": {
+      "get": {
+        "operationId": "audit",
+        "summary": "Query the audit trail of mutating operations, recorded by every replica when the audit log is shared. Requires the bearer token of an admin.",
+        "security": [
+          {
+            "bearerToken": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "instanceID",
+            "in": "query",
+            "description": "Only events of this instance.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "operation",
+            "in": "query",
+            "description": "Only events of this operation, e.g. CreateUser.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "caller",
+            "in": "query",
+            "description": "Only events sent by this caller.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "user",
+            "in": "query",
+            "description": "Only events acting as or on this account.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "result",
+            "in": "query",
+            "description": "Only successful or failed events.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "success",
+                "failure"
+              ]
+            }
+          },
+          {
+            "name": "since",
+            "in": "query",
+            "description": "Only events at or after this time.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "until",
+            "in": "query",
+            "description": "Only events before this time.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Keep only the most recent events.",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AuditResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The request could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "No known bearer token was presented; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The token is not an admin's; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "404": {
+            "description": "Audit logging is disabled.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AuditResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "The audit log could not be read.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/AuditResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/status": {
+      "get": {
+        "operationId": "status",
+        "summary": "Report the reachability of the wrapper's dependencies.",
+        "parameters": [
+          {
+            "name": "instances",
+            "in": "query",
+            "description": "Also connect to the metad of every instance; only an admin may, within the limits of its caller.",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StatusResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The request could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "The instances were asked for without a known bearer token; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The instances were asked for with a token not an admin's; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "429": {
+            "description": "The caller is over its limits; retry after the number of seconds in the Retry-After header.",
+            "headers": {
+              "Retry-After": {
+                "description": "Seconds to wait before retrying.",
+                "schema": {
+                  "type": "integer"
+                }
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/limits": {
+      "get": {
+        "operationId": "limits",
+        "summary": "Report the rate and concurrency limits in force.",
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LimitsResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/limits/set": {
+      "post": {
+        "operationId": "setLimits",
+        "security": [
+          {
+            "bearerToken": []
+          }
+        ],
+        "summary": "Change the rate and concurrency limits without a restart; every replica applies them within seconds. Requires the bearer token of an admin.",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Limits"
+              }
+            }
+          },
+          "required": true
+        },
+        "responses": {
+          "200": {
+            "description": "Result; Code is 0 on success or one of the error codes.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LimitsResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "The request could not be decoded or failed validation.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "401": {
+            "description": "No known bearer token was presented; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "403": {
+            "description": "The token is not an admin's; Code is 40025.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          },
+          "500": {
+            "description": "The limits could not be saved for the other replicas, and were not applied.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LimitsResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerToken": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "A token of the wrapper's -tokens-file. It names the caller, which its rate limits and the audit trail go by; without one a caller is known by its address. Changing the limits and reading the audit trail take an admin's."
+      }
+    },
+    "parameters": {
+      "CacheControl": {
+        "name": "Cache-Control",
+        "in": "header",
+        "description": "no-cache makes the wrapper read metad rather than its metadata cache, which holds spaces, users and roles for a few seconds.",
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "schemas": {
+      "ListSpaceRequest": {
+        "description": "Lists the spaces UserName holds a role in, a page at a time, by name.",
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "UserName"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "UserName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Limit": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 1000,
+            "description": "Spaces per page, 100 if 0."
+          },
+          "Cursor": {
+            "type": "string",
+            "description": "NextCursor of the previous page; empty for the first page."
+          },
+          "Order": {
+            "type": "string",
+            "enum": [
+              "asc",
+              "desc"
+            ],
+            "description": "Order of the spaces by name, asc if empty."
+          },
+          "Prefix": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Keeps the spaces whose name starts with it."
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ],
+            "description": "Keeps the spaces UserName holds this role in."
+          }
+        }
+      },
+      "ListSpaceResponse": {
+        "description": "Spaces of the instance that UserName holds a role in.",
+        "type": "object",
+        "properties": {
+          "InstanceID": {
+            "type": "string"
+          },
+          "Spaces": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "Total": {
+            "type": "integer",
+            "description": "Number of spaces the filters keep, on every page."
+          },
+          "NextCursor": {
+            "type": "string",
+            "description": "Cursor of the next page; empty on the last page."
+          },
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "ListUsersRequest": {
+        "description": "Lists the users holding a role in any space, a page at a time, by name.",
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Limit": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 1000,
+            "description": "Users per page, 100 if 0."
+          },
+          "Cursor": {
+            "type": "string",
+            "description": "NextCursor of the previous page; empty for the first page."
+          },
+          "Order": {
+            "type": "string",
+            "enum": [
+              "asc",
+              "desc"
+            ],
+            "description": "Order of the users by name, asc if empty."
+          },
+          "Prefix": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Keeps the users whose name starts with it."
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ],
+            "description": "Keeps the users holding this role in some space."
+          }
+        }
+      },
+      "SpaceRole": {
+        "description": "A role held in a space.",
+        "type": "object",
+        "properties": {
+          "Space": {
+            "type": "string",
+            "description": "Space the role is held in; empty for a global role, as GOD is."
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ]
+          }
+        }
+      },
+      "User": {
+        "description": "A user and every role they hold.",
+        "type": "object",
+        "properties": {
+          "Name": {
+            "type": "string"
+          },
+          "Roles": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SpaceRole"
+            }
+          }
+        }
+      },
+      "ListUsersResponse": {
+        "description": "Each user once, with their roles.",
+        "type": "object",
+        "properties": {
+          "Users": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/User"
+            }
+          },
+          "Total": {
+            "type": "integer",
+            "description": "Number of users the filters keep, on every page."
+          },
+          "NextCursor": {
+            "type": "string",
+            "description": "Cursor of the next page; empty on the last page."
+          },
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "InstanceInfoRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          }
+        }
+      },
+      "InstanceInfo": {
+        "description": "Build of one replica of a component.",
+        "type": "object",
+        "properties": {
+          "diskUsage": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "totalDiskSpace": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "component": {
+            "type": "string"
+          },
+          "pod": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "description": "Status the component reports, such as running; the pod phase if it isn't running, or unreachable."
+          },
+          "version": {
+            "type": "string",
+            "description": "Version the component reports, or the tag of its image."
+          },
+          "commitID": {
+            "type": "string"
+          },
+          "buildTime": {
+            "type": "string"
+          },
+          "versionSkew": {
+            "type": "boolean",
+            "description": "The replica runs another build than most replicas of its component; when builds tie, every replica of the component is flagged."
+          },
+          "error": {
+            "type": "string",
+            "description": "Why the status endpoint couldn't be read."
+          }
+        }
+      },
+      "InstanceInfoResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "data": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/InstanceInfo"
+            }
+          },
+          "versionSkew": {
+            "type": "boolean",
+            "description": "Some replica is flagged with versionSkew."
+          }
+        }
+      },
+      "StorageHostsRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          }
+        }
+      },
+      "SpacePartitions": {
+        "description": "The share of a space's partitions a host holds.",
+        "type": "object",
+        "properties": {
+          "space": {
+            "type": "string"
+          },
+          "partitions": {
+            "type": "integer",
+            "description": "Partitions the host holds a replica of."
+          },
+          "leaders": {
+            "type": "integer",
+            "description": "Partitions the host leads."
+          },
+          "lost": {
+            "type": "integer",
+            "description": "Partitions whose replica on the host metad counts lost."
+          }
+        }
+      },
+      "StorageHost": {
+        "description": "A storaged host registered with metad.",
+        "type": "object",
+        "properties": {
+          "host": {
+            "type": "string",
+            "description": "Address metad knows the host by, ip:port."
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "ONLINE",
+              "OFFLINE",
+              "UNKNOWN"
+            ]
+          },
+          "lastSeenOnline": {
+            "type": "string",
+            "format": "date-time",
+            "description": "When the replica of the wrapper answering last saw metad list the host online; metad doesn't tell the time of heartbeats. Kept in memory, so missing if that replica never saw the host online since it started."
+          },
+          "pod": {
+            "type": "string",
+            "description": "Pod of the instance with the host's IP."
+          },
+          "node": {
+            "type": "string",
+            "description": "Node the pod runs on."
+          },
+          "leaders": {
+            "type": "integer"
+          },
+          "partitions": {
+            "type": "integer"
+          },
+          "spaces": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SpacePartitions"
+            }
+          }
+        }
+      },
+      "StorageHostsResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Hosts": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/StorageHost"
+            }
+          }
+        }
+      },
+      "BalanceRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          }
+        }
+      },
+      "BalanceStatusRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "PlanID": {
+            "type": "integer",
+            "format": "int64",
+            "minimum": 0,
+            "description": "Plan to report; 0 for the one last started through any replica of the wrapper."
+          }
+        }
+      },
+      "BalanceTask": {
+        "description": "One move of a partition replica.",
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "Partition replica moved and the hosts it moves between."
+          },
+          "result": {
+            "type": "string",
+            "enum": [
+              "SUCCEEDED",
+              "FAILED",
+              "IN_PROGRESS",
+              "INVALID"
+            ]
+          }
+        }
+      },
+      "BalancePlan": {
+        "description": "A data balance plan and the progress of its tasks.",
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "IN_PROGRESS",
+              "SUCCEEDED",
+              "FAILED"
+            ],
+            "description": "IN_PROGRESS while any task is yet to finish, then FAILED if any task failed and SUCCEEDED otherwise."
+          },
+          "total": {
+            "type": "integer"
+          },
+          "succeeded": {
+            "type": "integer"
+          },
+          "failed": {
+            "type": "integer"
+          },
+          "inProgress": {
+            "type": "integer"
+          },
+          "tasks": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/BalanceTask"
+            }
+          }
+        }
+      },
+      "BalanceResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Balanced": {
+            "type": "boolean",
+            "description": "The partitions were balanced already, so no plan was started."
+          },
+          "Plan": {
+            "$ref": "#/components/schemas/BalancePlan"
+          }
+        }
+      },
+      "BalanceLeaderResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "SubmitJobRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "SpaceName",
+          "Command"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "SpaceName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Command": {
+            "type": "string",
+            "enum": [
+              "compact",
+              "flush"
+            ]
+          }
+        }
+      },
+      "SubmitJobResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "JobID": {
+            "type": "integer",
+            "format": "int32"
+          }
+        }
+      },
+      "ListJobsRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "SpaceName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Only jobs on this space."
+          }
+        }
+      },
+      "Job": {
+        "description": "An admin job; times are left out until the job starts or stops.",
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int32"
+          },
+          "command": {
+            "type": "string"
+          },
+          "space": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "QUEUE",
+              "RUNNING",
+              "FINISHED",
+              "FAILED",
+              "STOPPED",
+              "INVALID"
+            ]
+          },
+          "startTime": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "stopTime": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "ListJobsResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Jobs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Job"
+            }
+          }
+        }
+      },
+      "JobRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "JobID": {
+            "type": "integer",
+            "format": "int32",
+            "minimum": 1
+          }
+        }
+      },
+      "JobTask": {
+        "description": "The part of a job one storage host runs.",
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer",
+            "format": "int32"
+          },
+          "host": {
+            "type": "string",
+            "description": "Storage host running the task, ip:port."
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "QUEUE",
+              "RUNNING",
+              "FINISHED",
+              "FAILED",
+              "STOPPED",
+              "INVALID"
+            ]
+          },
+          "startTime": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "stopTime": {
+            "type": "string",
+            "format": "date-time"
+          }
+        }
+      },
+      "JobResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Job": {
+            "$ref": "#/components/schemas/Job"
+          },
+          "Tasks": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/JobTask"
+            }
+          }
+        }
+      },
+      "StopJobResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "CreateSnapshotRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          }
+        }
+      },
+      "SnapshotRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          }
+        }
+      },
+      "DropSnapshotRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "Snapshot"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Snapshot": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          }
+        }
+      },
+      "SnapshotHost": {
+        "description": "A storage host a snapshot has a checkpoint on.",
+        "type": "object",
+        "properties": {
+          "host": {
+            "type": "string",
+            "description": "Storage host, ip:port."
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "VALID",
+              "INVALID"
+            ],
+            "description": "Status of the snapshot; metad marks it INVALID if any host failed its checkpoint."
+          },
+          "hostStatus": {
+            "type": "string",
+            "enum": [
+              "ONLINE",
+              "OFFLINE",
+              "UNKNOWN"
+            ],
+            "description": "How the host is doing now; UNKNOWN if metad no longer lists it."
+          }
+        }
+      },
+      "Snapshot": {
+        "description": "A point-in-time checkpoint of an instance.",
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "VALID",
+              "INVALID"
+            ]
+          },
+          "createTime": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Read from the name metad gives the snapshot; left out if the name doesn't carry it."
+          },
+          "hosts": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/SnapshotHost"
+            }
+          }
+        }
+      },
+      "SnapshotPolicy": {
+        "description": "Retention of the snapshots of an instance, enforced in the background by one replica of the wrapper. The newest VALID snapshot is always kept.",
+        "type": "object",
+        "properties": {
+          "keepLast": {
+            "type": "integer",
+            "description": "Newest VALID snapshots kept, along with any INVALID ones newer than the oldest of them; 0 for no limit."
+          },
+          "maxAgeSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Age in seconds past which snapshots are dropped; 0 for no limit."
+          }
+        }
+      },
+      "CreateSnapshotResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Snapshot": {
+            "$ref": "#/components/schemas/Snapshot"
+          }
+        }
+      },
+      "ListSnapshotsResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Snapshots": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Snapshot"
+            }
+          },
+          "Policy": {
+            "$ref": "#/components/schemas/SnapshotPolicy"
+          }
+        }
+      },
+      "DropSnapshotResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "SetSnapshotPolicyRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "KeepLast": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 1000
+          },
+          "MaxAgeSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "minimum": 0
+          }
+        }
+      },
+      "SnapshotPolicyResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Policy": {
+            "$ref": "#/components/schemas/SnapshotPolicy"
+          }
+        }
+      },
+      "ConfigItem": {
+        "description": "A flag of a Nebula component registered with metad.",
+        "type": "object",
+        "properties": {
+          "module": {
+            "type": "string",
+            "enum": [
+              "GRAPH",
+              "META",
+              "STORAGE"
+            ]
+          },
+          "name": {
+            "type": "string"
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "INT64",
+              "DOUBLE",
+              "BOOL",
+              "STRING",
+              "NESTED"
+            ]
+          },
+          "mode": {
+            "type": "string",
+            "enum": [
+              "IMMUTABLE",
+              "REBOOT",
+              "MUTABLE",
+              "IGNORED"
+            ]
+          },
+          "value": {
+            "type": "string",
+            "description": "The value as text; a JSON object of options for NESTED flags."
+          }
+        }
+      },
+      "ListConfigsRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Module": {
+            "type": "string",
+            "enum": [
+              "GRAPH",
+              "META",
+              "STORAGE"
+            ],
+            "description": "Only flags of this module."
+          },
+          "All": {
+            "type": "boolean",
+            "description": "List the flags that can't be changed too."
+          }
+        }
+      },
+      "ListConfigsResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Configs": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ConfigItem"
+            }
+          }
+        }
+      },
+      "GetConfigRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "Module",
+          "Name"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Module": {
+            "type": "string",
+            "enum": [
+              "GRAPH",
+              "META",
+              "STORAGE"
+            ]
+          },
+          "Name": {
+            "type": "string",
+            "maxLength": 128,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          }
+        }
+      },
+      "GetConfigResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Config": {
+            "$ref": "#/components/schemas/ConfigItem"
+          }
+        }
+      },
+      "SetConfigRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "Module",
+          "Name"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Module": {
+            "type": "string",
+            "enum": [
+              "GRAPH",
+              "META",
+              "STORAGE"
+            ]
+          },
+          "Name": {
+            "type": "string",
+            "maxLength": 128,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Value": {
+            "type": "string",
+            "maxLength": 4096,
+            "description": "New value, as text of the flag's type."
+          }
+        }
+      },
+      "SetConfigResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Config": {
+            "$ref": "#/components/schemas/ConfigItem"
+          },
+          "PreviousValue": {
+            "type": "string",
+            "description": "Value the flag had before, to revert the change with."
+          }
+        }
+      },
+      "CreateSpaceRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "SpaceName"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "SpaceName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          }
+        }
+      },
+      "InitializeRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "UserName"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "UserName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          }
+        }
+      },
+      "CreateUserRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "UserName",
+          "Role",
+          "SpaceName",
+          "Account"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "UserName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ]
+          },
+          "SpaceName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Account": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Account performing the grant."
+          }
+        }
+      },
+      "CreateUserResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "TransferGodUserRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "UserName",
+          "OldName"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "UserName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "OldName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          }
+        }
+      },
+      "TransferGodUserResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "ListUserRequest": {
+        "description": "Lists the users holding a role in SpaceName, as seen by Operator, a page at a time, by name.",
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "SpaceName",
+          "Operator"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "SpaceName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Operator": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Limit": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 1000,
+            "description": "Users per page, 100 if 0."
+          },
+          "Cursor": {
+            "type": "string",
+            "description": "NextCursor of the previous page; empty for the first page."
+          },
+          "Order": {
+            "type": "string",
+            "enum": [
+              "asc",
+              "desc"
+            ],
+            "description": "Order of the users by name, asc if empty."
+          },
+          "Prefix": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Keeps the users whose name starts with it."
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ],
+            "description": "Keeps the users holding this role."
+          }
+        }
+      },
+      "ListRootUserRequest": {
+        "description": "Lists the users holding a global role, a page at a time, by name.",
+        "type": "object",
+        "required": [
+          "InstanceID"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "Limit": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 1000,
+            "description": "Users per page, 100 if 0."
+          },
+          "Cursor": {
+            "type": "string",
+            "description": "NextCursor of the previous page; empty for the first page."
+          },
+          "Order": {
+            "type": "string",
+            "enum": [
+              "asc",
+              "desc"
+            ],
+            "description": "Order of the users by name, asc if empty."
+          },
+          "Prefix": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Keeps the users whose name starts with it."
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ],
+            "description": "Keeps the users holding this global role."
+          }
+        }
+      },
+      "UserRole": {
+        "description": "A user and the role they hold.",
+        "type": "object",
+        "properties": {
+          "User": {
+            "type": "string"
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ]
+          }
+        }
+      },
+      "ListUserResponse": {
+        "type": "object",
+        "properties": {
+          "Users": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/UserRole"
+            },
+            "description": "The page, in order."
+          },
+          "UserRoles": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string",
+              "enum": [
+                "GOD",
+                "ADMIN",
+                "DBA",
+                "USER",
+                "GUEST"
+              ]
+            }
+          },
+          "Total": {
+            "type": "integer",
+            "description": "Number of users the filters keep, on every page."
+          },
+          "NextCursor": {
+            "type": "string",
+            "description": "Cursor of the next page; empty on the last page."
+          },
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "RevokeUserRequest": {
+        "type": "object",
+        "required": [
+          "InstanceID",
+          "UserName",
+          "Space",
+          "Role",
+          "Account"
+        ],
+        "properties": {
+          "InstanceID": {
+            "type": "string",
+            "maxLength": 63,
+            "pattern": "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+            "description": "Kubernetes namespace of the Nebula instance."
+          },
+          "UserName": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Space": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$"
+          },
+          "Role": {
+            "type": "string",
+            "enum": [
+              "GOD",
+              "ADMIN",
+              "DBA",
+              "USER",
+              "GUEST"
+            ]
+          },
+          "Account": {
+            "type": "string",
+            "maxLength": 64,
+            "pattern": "^[a-zA-Z_][a-zA-Z0-9_]*$",
+            "description": "Account performing the revoke."
+          }
+        }
+      },
+      "RevokeUserResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          }
+        }
+      },
+      "Machine": {
+        "type": "object",
+        "properties": {
+          "duration": {
+            "type": "string",
+            "description": "How long the resource has existed, as text such as 2h3m4.5s; use durationSeconds.",
+            "deprecated": true
+          },
+          "durationSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "description": "How long the resource has existed, in seconds."
+          },
+          "cpu": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "memory": {
+            "type": "integer",
+            "format": "int64"
+          }
+        }
+      },
+      "Disk": {
+        "type": "object",
+        "properties": {
+          "duration": {
+            "type": "string",
+            "description": "How long the resource has existed, as text such as 2h3m4.5s; use durationSeconds.",
+            "deprecated": true
+          },
+          "durationSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "description": "How long the resource has existed, in seconds."
+          },
+          "size": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "usage": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "diskName": {
+            "type": "string"
+          },
+          "storageClass": {
+            "type": "string"
+          }
+        }
+      },
+      "LoadBalacer": {
+        "description": "A load balancer Service.",
+        "type": "object",
+        "properties": {
+          "duration": {
+            "type": "string",
+            "description": "How long the resource has existed, as text such as 2h3m4.5s; use durationSeconds.",
+            "deprecated": true
+          },
+          "durationSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "description": "How long the resource has existed, in seconds."
+          },
+          "band": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Bandwidth units the load balancer is provisioned with."
+          }
+        }
+      },
+      "ComponentResources": {
+        "description": "Resources of the replicas of one component of an instance, counted as for the instance.",
+        "type": "object",
+        "properties": {
+          "component": {
+            "type": "string",
+            "enum": [
+              "metad",
+              "storaged",
+              "graphd"
+            ]
+          },
+          "replicas": {
+            "type": "integer"
+          },
+          "cpu": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "cpuLimit": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "cpuUsage": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "memory": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "memoryLimit": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "memoryUsage": {
+            "type": "integer",
+            "format": "int64"
+          }
+        }
+      },
+      "Instance": {
+        "description": "Resources of the pods of an instance, and their cost over the billing window.",
+        "type": "object",
+        "properties": {
+          "instanceName": {
+            "type": "string"
+          },
+          "cpu": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Millicores the containers of the instance request."
+          },
+          "cpuLimit": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Millicores the containers may use at most, leaving out those without a limit."
+          },
+          "cpuUsage": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Millicores in use."
+          },
+          "memoryUsage": {
+            "type": "integer",
+            "format": "int64",
+            "description": "MiB in use."
+          },
+          "memory": {
+            "type": "integer",
+            "format": "int64",
+            "description": "MiB the containers request."
+          },
+          "memoryLimit": {
+            "type": "integer",
+            "format": "int64",
+            "description": "MiB the containers may use at most, leaving out those without a limit."
+          },
+          "components": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ComponentResources"
+            }
+          },
+          "disks": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Disk"
+            }
+          },
+          "loadBalacer": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LoadBalacer"
+            }
+          },
+          "cost": {
+            "$ref": "#/components/schemas/Cost"
+          }
+        }
+      },
+      "ClusterCost": {
+        "type": "object",
+        "properties": {
+          "clusterName": {
+            "type": "string"
+          },
+          "machines": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Machine"
+            }
+          },
+          "disks": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Disk"
+            }
+          },
+          "loadBalacer": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LoadBalacer"
+            }
+          },
+          "instances": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Instance"
+            }
+          },
+          "currency": {
+            "type": "string",
+            "description": "Currency of the costs, as set in the price sheet."
+          },
+          "start": {
+            "type": "string",
+            "format": "date-time",
+            "description": "Start of the billing window."
+          },
+          "end": {
+            "type": "string",
+            "format": "date-time",
+            "description": "End of the billing window."
+          },
+          "windowSeconds": {
+            "type": "integer",
+            "format": "int64",
+            "description": "Length of the billing window, in seconds."
+          },
+          "cost": {
+            "$ref": "#/components/schemas/Cost"
+          }
+        },
+        "description": "Resources of the cluster and its instances; cost sums the costs of the instances."
+      },
+      "Cost": {
+        "description": "What resources cost over the billing window: CPU and memory by what the containers request, storage by PVC capacity, and load balancers and their bandwidth by the hour. A pod is billed from when its replica started, the earliest creation of the PVCs it mounts or else of its StatefulSet, so a pod replaced during the window is billed for all of it. Resources deleted during the window are not counted.",
+        "type": "object",
+        "properties": {
+          "cpu": {
+            "type": "number",
+            "format": "double"
+          },
+          "memory": {
+            "type": "number",
+            "format": "double"
+          },
+          "storage": {
+            "type": "number",
+            "format": "double"
+          },
+          "loadBalancer": {
+            "type": "number",
+            "format": "double"
+          },
+          "bandwidth": {
+            "type": "number",
+            "format": "double"
+          },
+          "total": {
+            "type": "number",
+            "format": "double"
+          }
+        }
+      },
+      "ClusterCostResponse": {
+        "type": "object",
+        "properties": {
+          "code": {
+            "type": "integer"
+          },
+          "clusterCost": {
+            "$ref": "#/components/schemas/ClusterCost"
+          }
+        }
+      },
+      "FieldError": {
+        "type": "object",
+        "properties": {
+          "Field": {
+            "type": "string"
+          },
+          "Rule": {
+            "type": "string"
+          },
+          "Message": {
+            "type": "string"
+          }
+        }
+      },
+      "ErrorResponse": {
+        "description": "Returned when the request body cannot be decoded or fails validation, or when the request is over the rate limits.",
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Errors": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/FieldError"
+            }
+          }
+        }
+      },
+      "AuditRole": {
+        "description": "A role held by an account; spaceID 0 is the global space.",
+        "type": "object",
+        "properties": {
+          "spaceID": {
+            "type": "integer",
+            "format": "int64"
+          },
+          "role": {
+            "type": "string"
+          }
+        }
+      },
+      "AuditTarget": {
+        "type": "object",
+        "properties": {
+          "user": {
+            "type": "string"
+          },
+          "rolesBefore": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AuditRole"
+            }
+          },
+          "rolesAfter": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AuditRole"
+            }
+          }
+        }
+      },
+      "AuditEvent": {
+        "type": "object",
+        "properties": {
+          "time": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "operation": {
+            "type": "string"
+          },
+          "caller": {
+            "type": "string"
+          },
+          "account": {
+            "type": "string"
+          },
+          "instanceID": {
+            "type": "string"
+          },
+          "space": {
+            "type": "string"
+          },
+          "role": {
+            "type": "string"
+          },
+          "snapshot": {
+            "type": "string",
+            "description": "Snapshot dropped."
+          },
+          "targets": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AuditTarget"
+            }
+          },
+          "result": {
+            "type": "string",
+            "enum": [
+              "success",
+              "failure"
+            ]
+          },
+          "status": {
+            "type": "integer"
+          },
+          "code": {
+            "type": "integer"
+          },
+          "config": {
+            "type": "string",
+            "description": "Flag changed, as MODULE/name."
+          },
+          "value": {
+            "type": "string",
+            "description": "Value the flag was set to."
+          },
+          "previousValue": {
+            "type": "string",
+            "description": "Value the flag had before."
+          }
+        }
+      },
+      "AuditResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Events": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/AuditEvent"
+            }
+          }
+        }
+      },
+      "DependencyStatus": {
+        "description": "The outcome of checking one dependency, or the metad of one instance.",
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "ok",
+              "unavailable"
+            ]
+          },
+          "latency": {
+            "type": "number",
+            "format": "double",
+            "description": "How long the check took, in seconds."
+          },
+          "error": {
+            "type": "string"
+          }
+        }
+      },
+      "StatusResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Status": {
+            "type": "string",
+            "enum": [
+              "ok",
+              "degraded"
+            ]
+          },
+          "Dependencies": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DependencyStatus"
+            }
+          },
+          "Instances": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/DependencyStatus"
+            }
+          }
+        }
+      },
+      "Limits": {
+        "description": "Limits on callers and instances; a zero rate or concurrency means no limit.",
+        "type": "object",
+        "properties": {
+          "callerRate": {
+            "type": "number",
+            "format": "double"
+          },
+          "callerBurst": {
+            "type": "integer"
+          },
+          "instanceRate": {
+            "type": "number",
+            "format": "double"
+          },
+          "instanceBurst": {
+            "type": "integer"
+          },
+          "instanceConcurrency": {
+            "type": "integer"
+          }
+        }
+      },
+      "LimitsResponse": {
+        "type": "object",
+        "properties": {
+          "Code": {
+            "type": "integer"
+          },
+          "Limits": {
+            "$ref": "#/components/schemas/Limits"
+          }
+        }
+      }
+    }
+  }
+}
+`
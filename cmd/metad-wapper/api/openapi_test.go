@@ -0,0 +1,235 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type specSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*specSchema `json:"properties"`
+	Items                *specSchema            `json:"items"`
+	AdditionalProperties *specSchema            `json:"additionalProperties"`
+}
+
+type specMedia struct {
+	Schema specSchema `json:"schema"`
+}
+
+type specBody struct {
+	Content map[string]specMedia `json:"content"`
+}
+
+type specOperation struct {
+	RequestBody *specBody           `json:"requestBody"`
+	Responses   map[string]specBody `json:"responses"`
+}
+
+type spec struct {
+	Paths      map[string]map[string]specOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*specSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) *spec {
+	s := &spec{}
+	if err := json.Unmarshal([]byte(OpenAPISpec), s); err != nil {
+		t.Fatalf("OpenAPISpec is not valid JSON: %v", err)
+	}
+	return s
+}
+
+func bodyRef(body specBody) string {
+	return strings.TrimPrefix(body.Content["application/json"].Schema.Ref, "#/components/schemas/")
+}
+
+func TestSpecCoversEndpoints(t *testing.T) {
+	s := loadSpec(t)
+
+	if len(s.Paths) != len(Endpoints) {
+		t.Errorf("spec has %d paths, Endpoints has %d", len(s.Paths), len(Endpoints))
+	}
+
+	for _, ep := range Endpoints {
+		ops, ok := s.Paths[ep.Path]
+		if !ok {
+			t.Errorf("%s: missing from spec", ep.Path)
+			continue
+		}
+		op, ok := ops[strings.ToLower(ep.Method)]
+		if !ok || len(ops) != 1 {
+			t.Errorf("%s: spec should define exactly the %s operation", ep.Path, ep.Method)
+			continue
+		}
+
+		if ep.Request == nil {
+			if op.RequestBody != nil {
+				t.Errorf("%s: spec defines a request body, handler takes none", ep.Path)
+			}
+		} else {
+			if op.RequestBody == nil {
+				t.Errorf("%s: spec has no request body, handler takes %T", ep.Path, ep.Request)
+			} else if name := bodyRef(*op.RequestBody); name != reflect.TypeOf(ep.Request).Name() {
+				t.Errorf("%s: spec request is %q, handler takes %T", ep.Path, name, ep.Request)
+			}
+
+			if name := bodyRef(op.Responses["400"]); name != "ErrorResponse" {
+				t.Errorf("%s: 400 response is %q, want ErrorResponse", ep.Path, name)
+			}
+		}
+
+		ok200 := op.Responses["200"]
+		if ep.Response == nil {
+			if len(ok200.Content) != 0 {
+				t.Errorf("%s: spec defines a response body, handler returns none", ep.Path)
+			}
+		} else if name := bodyRef(ok200); name != reflect.TypeOf(ep.Response).Name() {
+			t.Errorf("%s: spec response is %q, handler returns %T", ep.Path, name, ep.Response)
+		}
+	}
+}
+
+func TestSpecSchemasMatchTypes(t *testing.T) {
+	s := loadSpec(t)
+
+	types := map[string]reflect.Type{}
+	var collect func(reflect.Type)
+	collect = func(typ reflect.Type) {
+		switch typ.Kind() {
+		case reflect.Slice, reflect.Map:
+			collect(typ.Elem())
+		case reflect.Struct:
+			if _, seen := types[typ.Name()]; seen || typ == timeType {
+				return
+			}
+			types[typ.Name()] = typ
+			for i := 0; i < typ.NumField(); i++ {
+				collect(typ.Field(i).Type)
+			}
+		}
+	}
+	collect(reflect.TypeOf(ErrorResponse{}))
+	for _, ep := range Endpoints {
+		if ep.Request != nil {
+			collect(reflect.TypeOf(ep.Request))
+		}
+		if ep.Response != nil {
+			collect(reflect.TypeOf(ep.Response))
+		}
+	}
+
+	for name := range s.Components.Schemas {
+		if _, ok := types[name]; !ok {
+			t.Errorf("schema %s does not correspond to any API type", name)
+		}
+	}
+
+	for name, typ := range types {
+		schema, ok := s.Components.Schemas[name]
+		if !ok {
+			t.Errorf("type %s has no schema", name)
+			continue
+		}
+		checkStruct(t, name, typ, schema)
+	}
+}
+
+func checkStruct(t *testing.T, name string, typ reflect.Type, schema *specSchema) {
+	if schema.Type != "object" {
+		t.Errorf("%s: schema type is %q, want object", name, schema.Type)
+	}
+
+	fields := map[string]bool{}
+	required := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		key := jsonName(field)
+		fields[key] = true
+
+		if strings.Contains(field.Tag.Get("validate"), "required") {
+			required = append(required, key)
+		}
+
+		prop, ok := schema.Properties[key]
+		if !ok {
+			t.Errorf("%s: field %s is serialized as %q, which the spec does not define", name, field.Name, key)
+			continue
+		}
+		checkType(t, name+"."+key, field.Type, prop)
+	}
+
+	for key := range schema.Properties {
+		if !fields[key] {
+			t.Errorf("%s: spec property %q has no field", name, key)
+		}
+	}
+
+	specRequired := append([]string{}, schema.Required...)
+	sort.Strings(required)
+	sort.Strings(specRequired)
+	if !reflect.DeepEqual(required, specRequired) && !(len(required) == 0 && len(specRequired) == 0) {
+		t.Errorf("%s: spec requires %v, validation requires %v", name, specRequired, required)
+	}
+}
+
+func checkType(t *testing.T, where string, typ reflect.Type, prop *specSchema) {
+	want := ""
+	switch typ.Kind() {
+	case reflect.String:
+		want = "string"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		want = "integer"
+	case reflect.Float64:
+		want = "number"
+	case reflect.Bool:
+		want = "boolean"
+	case reflect.Slice:
+		want = "array"
+		if prop.Items == nil {
+			t.Errorf("%s: array without items", where)
+		} else {
+			checkType(t, where+"[]", typ.Elem(), prop.Items)
+		}
+	case reflect.Map:
+		want = "object"
+		if prop.AdditionalProperties == nil {
+			t.Errorf("%s: map without additionalProperties", where)
+		} else {
+			checkType(t, where+"{}", typ.Elem(), prop.AdditionalProperties)
+		}
+	case reflect.Struct:
+		if typ == timeType {
+			want = "string"
+			break
+		}
+		if ref := strings.TrimPrefix(prop.Ref, "#/components/schemas/"); ref != typ.Name() {
+			t.Errorf("%s: spec refers to %q, field is %s", where, ref, typ.Name())
+		}
+		return
+	default:
+		t.Errorf("%s: unsupported kind %s", where, typ.Kind())
+		return
+	}
+
+	if prop.Type != want {
+		t.Errorf("%s: spec type is %q, field is %s", where, prop.Type, typ.Kind())
+	}
+}
+
+// jsonName returns the key encoding/json uses for a field.
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+// Package api holds the request and response types of the metad wrapper's
+// HTTP API, the paths they are served on and the OpenAPI document describing
+// them. It is shared by the server and by the Go client.
+package api
+
+const (
+	PathListSpaces         = "/metadwapper/list/spaces"
+	PathListUsers          = "/metadwapper/list/users"
+	PathCreateSpaces       = "/metadwapper/create/spaces"
+	PathCreateUsers        = "/metadwapper/create/users"
+	PathClusterCost        = "/metadwapper/clusterCost"
+	PathChangeGod          = "/metadwapper/changeGod"
+	PathDeleteUsers        = "/metadwapper/delete/users"
+	PathInitialize         = "/metadwapper/initialize"
+	PathListSpaceUsers     = "/metadwapper/list/spaces/users"
+	PathListRootSpaceUsers = "/metadwapper/list/rootspaces/users"
+	PathInstanceVersion    = "/metadwapper/instance/version"
+	PathStorageHosts       = "/metadwapper/storage/hosts"
+	PathBalanceData        = "/metadwapper/balance/data"
+	PathBalanceLeader      = "/metadwapper/balance/leader"
+	PathBalanceStop        = "/metadwapper/balance/stop"
+	PathBalanceStatus      = "/metadwapper/balance/status"
+	PathSubmitJob          = "/metadwapper/jobs/submit"
+	PathListJobs           = "/metadwapper/jobs/list"
+	PathShowJob            = "/metadwapper/jobs/show"
+	PathStopJob            = "/metadwapper/jobs/stop"
+	PathCreateSnapshot     = "/metadwapper/snapshots/create"
+	PathListSnapshots      = "/metadwapper/snapshots/list"
+	PathDropSnapshot       = "/metadwapper/snapshots/drop"
+	PathSnapshotPolicy     = "/metadwapper/snapshots/policy"
+	PathListConfigs        = "/metadwapper/configs/list"
+	PathGetConfig          = "/metadwapper/configs/get"
+	PathSetConfig          = "/metadwapper/configs/set"
+
+	PathAudit   = "/audit"
+	PathOpenAPI = "/openapi.json"
+	PathMetrics = "/metrics"
+	PathHealthz = "/healthz"
+	PathReadyz  = "/readyz"
+	PathStatus  = "/status"
+
+	PathLimits    = "/limits"
+	PathSetLimits = "/limits/set"
+)
+
+// Endpoint describes one operation: the path it is served on, the HTTP method
+// the client uses and the Go types of its bodies. A nil Request means the
+// operation takes no body, a nil Response means it returns none on success.
+type Endpoint struct {
+	Path     string
+	Method   string
+	Request  interface{}
+	Response interface{}
+}
+
+// Endpoints lists every operation of the API. The contract test checks it
+// against OpenAPISpec, so any change here must be reflected there.
+var Endpoints = []Endpoint{
+	{PathListSpaces, "POST", ListSpaceRequest{}, ListSpaceResponse{}},
+	{PathListUsers, "POST", ListUsersRequest{}, ListUsersResponse{}},
+	{PathCreateSpaces, "POST", CreateSpaceRequest{}, nil},
+	{PathCreateUsers, "POST", CreateUserRequest{}, CreateUserResponse{}},
+	{PathClusterCost, "GET", nil, ClusterCostResponse{}},
+	{PathChangeGod, "POST", TransferGodUserRequest{}, TransferGodUserResponse{}},
+	{PathDeleteUsers, "POST", RevokeUserRequest{}, RevokeUserResponse{}},
+	{PathInitialize, "POST", InitializeRequest{}, CreateUserResponse{}},
+	{PathListSpaceUsers, "POST", ListUserRequest{}, ListUserResponse{}},
+	{PathListRootSpaceUsers, "POST", ListRootUserRequest{}, ListUserResponse{}},
+	{PathInstanceVersion, "POST", InstanceInfoRequest{}, InstanceInfoResponse{}},
+	{PathStorageHosts, "POST", StorageHostsRequest{}, StorageHostsResponse{}},
+	{PathBalanceData, "POST", BalanceRequest{}, BalanceResponse{}},
+	{PathBalanceLeader, "POST", BalanceRequest{}, BalanceLeaderResponse{}},
+	{PathBalanceStop, "POST", BalanceRequest{}, BalanceResponse{}},
+	{PathBalanceStatus, "POST", BalanceStatusRequest{}, BalanceResponse{}},
+	{PathSubmitJob, "POST", SubmitJobRequest{}, SubmitJobResponse{}},
+	{PathListJobs, "POST", ListJobsRequest{}, ListJobsResponse{}},
+	{PathShowJob, "POST", JobRequest{}, JobResponse{}},
+	{PathStopJob, "POST", JobRequest{}, StopJobResponse{}},
+	{PathCreateSnapshot, "POST", CreateSnapshotRequest{}, CreateSnapshotResponse{}},
+	{PathListSnapshots, "POST", SnapshotRequest{}, ListSnapshotsResponse{}},
+	{PathDropSnapshot, "POST", DropSnapshotRequest{}, DropSnapshotResponse{}},
+	{PathSnapshotPolicy, "POST", SetSnapshotPolicyRequest{}, SnapshotPolicyResponse{}},
+	{PathListConfigs, "POST", ListConfigsRequest{}, ListConfigsResponse{}},
+	{PathGetConfig, "POST", GetConfigRequest{}, GetConfigResponse{}},
+	{PathSetConfig, "POST", SetConfigRequest{}, SetConfigResponse{}},
+	{PathAudit, "GET", nil, AuditResponse{}},
+	{PathStatus, "GET", nil, StatusResponse{}},
+	{PathLimits, "GET", nil, LimitsResponse{}},
+	{PathSetLimits, "POST", Limits{}, LimitsResponse{}},
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// HoursPerMonth is the length of the month storage prices are quoted for.
+const HoursPerMonth = 730
+
+// PriceSheet prices what an instance is billed for, in Currency. Storage is
+// priced per storage class, classes missing from StorageGiBMonth at
+// DefaultStorageGiBMonth. Bandwidth is priced per unit a load balancer is
+// provisioned with, per hour.
+type PriceSheet struct {
+	Currency               string             `json:"currency"`
+	VCPUHour               float64            `json:"vcpuHour"`
+	MemoryGiBHour          float64            `json:"memoryGiBHour"`
+	StorageGiBMonth        map[string]float64 `json:"storageGiBMonth,omitempty"`
+	DefaultStorageGiBMonth float64            `json:"defaultStorageGiBMonth"`
+	LoadBalancerHour       float64            `json:"loadBalancerHour"`
+	BandwidthUnitHour      float64            `json:"bandwidthUnitHour"`
+}
+
+// Validate checks the sheet names a currency and prices nothing below zero.
+func (p *PriceSheet) Validate() error {
+	if p.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	prices := map[string]float64{
+		"vcpuHour":               p.VCPUHour,
+		"memoryGiBHour":          p.MemoryGiBHour,
+		"defaultStorageGiBMonth": p.DefaultStorageGiBMonth,
+		"loadBalancerHour":       p.LoadBalancerHour,
+		"bandwidthUnitHour":      p.BandwidthUnitHour,
+	}
+	for class, price := range p.StorageGiBMonth {
+		prices["storageGiBMonth."+class] = price
+	}
+	for name, price := range prices {
+		if price < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Cost is what resources cost over a billing window, in the currency of the
+// price sheet. A pod is billed from when its replica started: the earliest
+// creation of the PVCs it mounts, or else of its StatefulSet, so replacing
+// the pod doesn't reset it.
+type Cost struct {
+	Cpu          float64 `json:"cpu"`
+	Memory       float64 `json:"memory"`
+	Storage      float64 `json:"storage"`
+	LoadBalancer float64 `json:"loadBalancer"`
+	Bandwidth    float64 `json:"bandwidth"`
+	Total        float64 `json:"total"`
+}
+
+// Add adds c to the cost.
+func (cost *Cost) Add(c Cost) {
+	cost.Cpu += c.Cpu
+	cost.Memory += c.Memory
+	cost.Storage += c.Storage
+	cost.LoadBalancer += c.LoadBalancer
+	cost.Bandwidth += c.Bandwidth
+	cost.Total += c.Total
+}
+
+// BillingWindow is the period PathClusterCost computes costs over, passed as
+// the start and end query parameters. Start defaults to the beginning of the
+// current month, UTC, and End to now.
+type BillingWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (w *BillingWindow) Query() url.Values {
+	q := url.Values{}
+	if !w.Start.IsZero() {
+		q.Set("start", w.Start.Format(time.RFC3339))
+	}
+	if !w.End.IsZero() {
+		q.Set("end", w.End.Format(time.RFC3339))
+	}
+	return q
+}
+
+// ParseBillingWindow reads the window from q, filling in the defaults as of
+// now. End may not be later than now, as costs to come aren't known.
+func ParseBillingWindow(q url.Values, now time.Time) (*BillingWindow, error) {
+	now = now.UTC()
+	w := &BillingWindow{
+		Start: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		End:   now,
+	}
+
+	for _, param := range []struct {
+		key string
+		t   *time.Time
+	}{{"start", &w.Start}, {"end", &w.End}} {
+		if value := q.Get(param.key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", param.key, err)
+			}
+			*param.t = parsed
+		}
+	}
+
+	if w.End.After(now) {
+		return nil, fmt.Errorf("end: must not be in the future")
+	}
+	if !w.Start.Before(w.End) {
+		return nil, fmt.Errorf("start: must be before end")
+	}
+	return w, nil
+}
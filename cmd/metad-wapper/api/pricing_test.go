@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseBillingWindow(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	monthStart := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"month so far", "", monthStart, now, false},
+		{"start", "start=2026-02-01T00:00:00Z", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), now, false},
+		{"end", "end=2026-03-10T00:00:00Z", monthStart, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), false},
+		{"offset", "start=2026-03-14T08:00:00%2B08:00&end=2026-03-15T08:00:00%2B08:00",
+			time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), false},
+		{"not RFC 3339", "start=2026-03-01", time.Time{}, time.Time{}, true},
+		{"end in the future", "end=2026-03-16T00:00:00Z", time.Time{}, time.Time{}, true},
+		{"start at end", "start=2026-03-10T00:00:00Z&end=2026-03-10T00:00:00Z", time.Time{}, time.Time{}, true},
+		{"start after end", "start=2026-03-11T00:00:00Z&end=2026-03-10T00:00:00Z", time.Time{}, time.Time{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := ParseBillingWindow(q, now)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseBillingWindow = %+v, want an error", w)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !w.Start.Equal(test.wantStart) || !w.End.Equal(test.wantEnd) {
+				t.Errorf("window = %s to %s, want %s to %s", w.Start, w.End, test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
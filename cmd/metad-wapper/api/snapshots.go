@@ -0,0 +1,71 @@
+package api
+
+type CreateSnapshotRequest struct {
+	InstanceID string `validate:"required,instance"`
+}
+
+type SnapshotRequest struct {
+	InstanceID string `validate:"required,instance"`
+}
+
+type DropSnapshotRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Snapshot   string `validate:"required,name,max=64"`
+}
+
+// SnapshotHost is a storage host a snapshot has a checkpoint on. metad
+// marks a snapshot INVALID if any host failed its checkpoint, so the
+// snapshot status holds for every host; HostStatus is how the host is
+// doing now, UNKNOWN if metad no longer lists it.
+type SnapshotHost struct {
+	Host       string `json:"host"`
+	Status     string `json:"status"`
+	HostStatus string `json:"hostStatus"`
+}
+
+// Snapshot is a point-in-time checkpoint of an instance. CreateTime is read
+// from the name metad gives it and left out if the name doesn't carry one.
+type Snapshot struct {
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	CreateTime string         `json:"createTime,omitempty"`
+	Hosts      []SnapshotHost `json:"hosts"`
+}
+
+// SnapshotPolicy is the retention of the snapshots of an instance, enforced
+// in the background: only the KeepLast newest VALID ones are kept, with the
+// INVALID ones newer than those, and those older than MaxAgeSeconds are
+// dropped. The newest VALID snapshot is kept whatever its age. Zero turns
+// either rule off.
+type SnapshotPolicy struct {
+	KeepLast      int   `json:"keepLast"`
+	MaxAgeSeconds int64 `json:"maxAgeSeconds"`
+}
+
+type CreateSnapshotResponse struct {
+	Code     int
+	Snapshot Snapshot
+}
+
+// ListSnapshotsResponse lists the snapshots of an instance, newest first,
+// with the retention policy applied to them.
+type ListSnapshotsResponse struct {
+	Code      int
+	Snapshots []Snapshot
+	Policy    SnapshotPolicy
+}
+
+type DropSnapshotResponse struct {
+	Code int
+}
+
+type SetSnapshotPolicyRequest struct {
+	InstanceID    string `validate:"required,instance"`
+	KeepLast      int    `validate:"min=0,max=1000"`
+	MaxAgeSeconds int64  `validate:"min=0"`
+}
+
+type SnapshotPolicyResponse struct {
+	Code   int
+	Policy SnapshotPolicy
+}
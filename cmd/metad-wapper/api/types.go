@@ -0,0 +1,296 @@
+package api
+
+import "time"
+
+// List requests return a page of up to Limit items (100 if 0), sorted by
+// name in Order, asc if empty. The next page is asked for with the
+// NextCursor of the previous one and the same Order and filters. Prefix
+// keeps the items whose name starts with it, Role those held with that
+// role; Total counts the items the filters keep, on every page.
+
+// ListSpaceRequest lists the spaces UserName holds a role in.
+type ListSpaceRequest struct {
+	InstanceID string `validate:"required,instance"`
+	UserName   string `validate:"required,name,max=64"`
+	Limit      int    `validate:"min=0,max=1000"`
+	Cursor     string
+	Order      string `validate:"order"`
+	Prefix     string `validate:"name,max=64"`
+	Role       string `validate:"role"`
+}
+
+type ListSpaceResponse struct {
+	InstanceID string
+	Spaces     []string
+	Total      int
+	NextCursor string
+	Code       int
+}
+
+// ListUsersRequest lists the users holding a role in any space. Role keeps
+// the users holding it in some space.
+type ListUsersRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Limit      int    `validate:"min=0,max=1000"`
+	Cursor     string
+	Order      string `validate:"order"`
+	Prefix     string `validate:"name,max=64"`
+	Role       string `validate:"role"`
+}
+
+// SpaceRole is a role held in a space. Space is empty for a global role,
+// which is how GOD is granted.
+type SpaceRole struct {
+	Space string
+	Role  string
+}
+
+type User struct {
+	Name  string
+	Roles []SpaceRole
+}
+
+// ListUsersResponse lists each user once, with every role they hold.
+// NextCursor is empty on the last page.
+type ListUsersResponse struct {
+	Users      []User
+	Total      int
+	NextCursor string
+	Code       int
+}
+
+type InstanceInfoRequest struct {
+	InstanceID string `validate:"required,instance"`
+}
+
+// InstanceInfo is the build a replica of a component runs, as read from its
+// status endpoint. Version falls back to the tag of its image when the
+// component doesn't report one. Error says why the endpoint couldn't be
+// read, and VersionSkew flags a replica whose build differs from the one
+// most replicas of its component run, or every replica of a component whose
+// builds tie.
+type InstanceInfo struct {
+	DiskUsage      int64  `json:"diskUsage,omitempty"`
+	TotalDiskSpace int64  `json:"totalDiskSpace,omitempty"`
+	Component      string `json:"component"`
+	Pod            string `json:"pod"`
+	Status         string `json:"status"`
+	Version        string `json:"version"`
+	CommitID       string `json:"commitID"`
+	BuildTime      string `json:"buildTime"`
+	VersionSkew    bool   `json:"versionSkew,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// InstanceInfoResponse lists every replica of the instance; VersionSkew is
+// set if any of them is flagged.
+type InstanceInfoResponse struct {
+	Code        int
+	Infos       []InstanceInfo `json:"data"`
+	VersionSkew bool           `json:"versionSkew"`
+}
+
+type CreateSpaceRequest struct {
+	InstanceID string `validate:"required,instance"`
+	SpaceName  string `validate:"required,name,max=64"`
+}
+
+type InitializeRequest struct {
+	InstanceID string `validate:"required,instance"`
+	UserName   string `validate:"required,name,max=64"`
+}
+
+type CreateUserRequest struct {
+	InstanceID string `validate:"required,instance"`
+	UserName   string `validate:"required,name,max=64"`
+	Role       string `validate:"required,role"`
+	SpaceName  string `validate:"required,name,max=64"`
+	Account    string `validate:"required,name,max=64"`
+}
+type CreateUserResponse struct {
+	Code int
+}
+
+type TransferGodUserRequest struct {
+	InstanceID string `validate:"required,instance"`
+	UserName   string `validate:"required,name,max=64"`
+	OldName    string `validate:"required,name,max=64"`
+}
+
+type TransferGodUserResponse struct {
+	Code int
+}
+
+// ListUserRequest lists the users holding a role in SpaceName, as seen by
+// Operator.
+type ListUserRequest struct {
+	InstanceID string `validate:"required,instance"`
+	SpaceName  string `validate:"required,name,max=64"`
+	Operator   string `validate:"required,name,max=64"`
+	Limit      int    `validate:"min=0,max=1000"`
+	Cursor     string
+	Order      string `validate:"order"`
+	Prefix     string `validate:"name,max=64"`
+	Role       string `validate:"role"`
+}
+
+// ListRootUserRequest lists the users holding a global role.
+type ListRootUserRequest struct {
+	InstanceID string `validate:"required,instance"`
+	Limit      int    `validate:"min=0,max=1000"`
+	Cursor     string
+	Order      string `validate:"order"`
+	Prefix     string `validate:"name,max=64"`
+	Role       string `validate:"role"`
+}
+
+type UserRole struct {
+	User string
+	Role string
+}
+
+// ListUserResponse holds the page in order in Users; UserRoles holds the
+// same page keyed by user.
+type ListUserResponse struct {
+	Users      []UserRole
+	UserRoles  map[string]string
+	Total      int
+	NextCursor string
+	Code       int
+}
+
+type RevokeUserRequest struct {
+	InstanceID string `validate:"required,instance"`
+	UserName   string `validate:"required,name,max=64"`
+	Space      string `validate:"required,name,max=64"`
+	Role       string `validate:"required,role"`
+	Account    string `validate:"required,name,max=64"`
+}
+
+type RevokeUserResponse struct {
+	Code int
+}
+
+// Durations are how long a resource has existed: Duration as text, such as
+// 2h3m4.5s, kept for older callers, and DurationSeconds in whole seconds.
+
+type Machine struct {
+	Duration        string `json:"duration,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+	Cpu             int64  `json:"cpu,omitempty"`
+	Memory          int64  `json:"memory,omitempty"`
+}
+
+type Disk struct {
+	Duration        string `json:"duration,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+	Size            int64  `json:"size,omitempty"`
+	Usage           int64  `json:"usage,omitempty"`
+	DiskName        string `json:"diskName,omitempty"`
+	StorageClass    string `json:"storageClass,omitempty"`
+}
+
+// LoadBalacer is a load balancer Service; Band is the bandwidth it is
+// provisioned with, in units of the price sheet.
+type LoadBalacer struct {
+	Duration        string `json:"duration,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+	Band            int64  `json:"band,omitempty"`
+}
+
+// ComponentResources is the share of an instance's resources taken by the
+// replicas of one of its components, counted as in Instance.
+type ComponentResources struct {
+	Component   string `json:"component"`
+	Replicas    int    `json:"replicas"`
+	Cpu         int64  `json:"cpu,omitempty"`
+	CpuLimit    int64  `json:"cpuLimit,omitempty"`
+	CpuUsage    int64  `json:"cpuUsage,omitempty"`
+	Memory      int64  `json:"memory,omitempty"`
+	MemoryLimit int64  `json:"memoryLimit,omitempty"`
+	MemoryUsage int64  `json:"memoryUsage,omitempty"`
+}
+
+// Instance sums the resources of the pods of the instance: CPU in
+// millicores and memory in MiB. Cpu and Memory are what the containers
+// request, the limits what they may use at most, leaving out containers
+// without one. Cost is what the instance is billed for over the billing
+// window.
+type Instance struct {
+	InstanceName string               `json:"instanceName,omitempty"`
+	Cpu          int64                `json:"cpu,omitempty"`
+	CpuLimit     int64                `json:"cpuLimit,omitempty"`
+	CpuUsage     int64                `json:"cpuUsage,omitempty"`
+	MemoryUsage  int64                `json:"memoryUsage,omitempty"`
+	Memory       int64                `json:"memory,omitempty"`
+	MemoryLimit  int64                `json:"memoryLimit,omitempty"`
+	Components   []ComponentResources `json:"components,omitempty"`
+	Disks        []Disk               `json:"disks,omitempty"`
+	LoadBalacer  []LoadBalacer        `json:"loadBalacer,omitempty"`
+	Cost         Cost                 `json:"cost"`
+}
+
+// ClusterCost reports the resources of the cluster and its instances, and
+// their cost from Start to End in Currency. Cost sums the costs of the
+// instances; LoadBalacer lists the load balancers of every namespace.
+type ClusterCost struct {
+	ClusterName   string        `json:"clusterName,omitempty"`
+	Machines      []Machine     `json:"machines,omitempty"`
+	Disks         []Disk        `json:"disks,omitempty"`
+	LoadBalacer   []LoadBalacer `json:"loadBalacer,omitempty"`
+	Instances     []Instance    `json:"instances,omitempty"`
+	Currency      string        `json:"currency"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	WindowSeconds int64         `json:"windowSeconds"`
+	Cost          Cost          `json:"cost"`
+}
+
+type ClusterCostResponse struct {
+	Code        int         `json:"code,omitempty"`
+	ClusterCost ClusterCost `json:"clusterCost,omitempty"`
+}
+
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+type ErrorResponse struct {
+	Code   int
+	Errors []FieldError `json:",omitempty"`
+}
+
+const (
+	ErrNotFound                = 40001
+	ErrIllegalMemory           = 40002
+	ErrIllegalCPU              = 40003
+	ErrNoResource              = 40004
+	ErrNoMoney                 = 40005
+	ErrS3NoStorage             = 40006
+	ErrNoInstance              = 40007
+	ErrEmptyInstanceID         = 40008
+	ErrInvalidRequestBody      = 40009
+	ErrEmptySpaceName          = 40010
+	ErrCloudProviderInnerError = 40011
+	ErrUserExisted             = 40012
+	ErrGrantRoleFailed         = 40013
+	ErrInitialUserFailed       = 40014
+	ErrInternalError           = 40015
+	ErrSpaceNotFound           = 40016
+	ErrTooManyRequests         = 40017
+	ErrInstanceUnavailable     = 40018
+	ErrBalanceRunning          = 40019
+	ErrNoBalancePlan           = 40020
+	ErrJobNotFound             = 40021
+	ErrSnapshotNotFound        = 40022
+	ErrConfigNotFound          = 40023
+	ErrConfigImmutable         = 40024
+	ErrUnauthorized            = 40025
+	ErrDropUserFailed          = 40026
+)
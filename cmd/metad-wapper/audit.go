@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+)
+
+// auditLog is where mutating handlers record what they did. It is set up in
+// main from the -audit-log flags.
+var auditLog *AuditLog
+
+// AuditLog appends events as JSON lines to a file, rotating it once it grows
+// past maxSize bytes and keeping at most maxBackups old files as path.1,
+// path.2, ... (path.1 being the most recent).
+//
+// A shared log lives in a directory every replica mounts, each replica
+// logging to a file of its own with the same extension, so that any of them
+// can answer for all.
+type AuditLog struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	shared     bool
+	file       *os.File
+	size       int64
+}
+
+func NewAuditLog(path string, maxSize int64, maxBackups int, shared bool) (*AuditLog, error) {
+	if shared && filepath.Ext(path) == "" {
+		return nil, fmt.Errorf("a shared audit log needs an extension telling the logs of the replicas apart")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &AuditLog{path: path, maxSize: maxSize, maxBackups: maxBackups, shared: shared}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(l.backupPath(l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		os.Rename(l.backupPath(i), l.backupPath(i+1))
+	}
+	if l.maxBackups > 0 {
+		if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+
+	return l.open()
+}
+
+func (l *AuditLog) backupPath(i int) string {
+	return l.path + "." + strconv.Itoa(i)
+}
+
+func (l *AuditLog) Record(event *api.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Query returns the events matching filter, oldest first, reading the
+// backups before the current file. The files are opened under the lock, so
+// a rotation can't move them underfoot, and read without it, so events are
+// still recorded meanwhile. A shared log also returns the events the other
+// replicas recorded.
+func (l *AuditLog) Query(filter *api.AuditFilter) ([]api.AuditEvent, error) {
+	readers, closeFiles, err := l.openFiles()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFiles()
+
+	events := []api.AuditEvent{}
+	for _, reader := range readers {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			event := api.AuditEvent{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			if filter.Match(&event) {
+				events = append(events, event)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if l.shared {
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+// openFiles opens the files Query reads, oldest first. The current file is
+// read only up to what was recorded when it was opened.
+func (l *AuditLog) openFiles() ([]io.Reader, func(), error) {
+	readers := []io.Reader{}
+	files := []*os.File{}
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+	open := func(path string) (*os.File, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		return file, nil
+	}
+
+	if l.shared {
+		peers, err := l.peerPaths()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range peers {
+			file, err := open(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			readers = append(readers, file)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, path := range l.logPaths(l.path) {
+		file, err := open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		if path == l.path {
+			readers = append(readers, io.LimitReader(file, l.size))
+		} else {
+			readers = append(readers, file)
+		}
+	}
+	return readers, closeFiles, nil
+}
+
+// logPaths returns the files of the log at path, oldest first.
+func (l *AuditLog) logPaths(path string) []string {
+	paths := []string{}
+	for i := l.maxBackups; i >= 1; i-- {
+		paths = append(paths, path+"."+strconv.Itoa(i))
+	}
+	return append(paths, path)
+}
+
+// peerPaths returns the files of the logs the other replicas keep in the
+// directory of a shared log: those with the same extension.
+func (l *AuditLog) peerPaths() ([]string, error) {
+	logs, err := filepath.Glob(filepath.Join(filepath.Dir(l.path), "*"+filepath.Ext(l.path)))
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, path := range logs {
+		if path != l.path {
+			paths = append(paths, l.logPaths(path)...)
+		}
+	}
+	return paths, nil
+}
+
+// auditRequest picks out of any mutating request the fields worth recording.
+type auditRequest struct {
+	InstanceID string
+	UserName   string
+	OldName    string
+	SpaceName  string
+	Space      string
+	Role       string
+	Account    string
+	Snapshot   string
+	Module     string
+	Name       string
+	Value      string
+}
+
+// audited wraps a mutating handler so that every call, whatever path it
+// returns through, leaves an event in the audit log. Roles of the affected
+// accounts are read from metad before and after the handler runs.
+func audited(operation string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auditLog == nil {
+			handler(w, r)
+			return
+		}
+
+		bodyData, err := requestBody(r)
+		if err != nil {
+			handler(w, r)
+			return
+		}
+
+		req := auditRequest{}
+		json.Unmarshal(bodyData, &req)
+
+		event := &api.AuditEvent{
+			Time:       time.Now().UTC(),
+			Operation:  operation,
+			Caller:     requestCaller(r),
+			Account:    req.Account,
+			InstanceID: req.InstanceID,
+			Space:      req.SpaceName,
+			Role:       req.Role,
+			Snapshot:   req.Snapshot,
+		}
+		if event.Space == "" {
+			event.Space = req.Space
+		}
+		if req.Module != "" && req.Name != "" {
+			event.Config = req.Module + "/" + req.Name
+			event.Value = req.Value
+		}
+		for _, user := range []string{req.UserName, req.OldName} {
+			if user != "" {
+				event.Targets = append(event.Targets, api.AuditTarget{User: user})
+			}
+		}
+
+		if req.InstanceID != "" {
+			for i := range event.Targets {
+				event.Targets[i].RolesBefore = auditRoles(r.Context(), req.InstanceID, event.Targets[i].User)
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		handler(recorder, r)
+
+		event.Status = recorder.status
+		event.Code = responseCode(recorder.body.Bytes())
+		if event.Config != "" {
+			event.PreviousValue = responsePreviousValue(recorder.body.Bytes())
+		}
+		if event.Status == http.StatusOK && event.Code == 0 {
+			event.Result = api.AuditResultSuccess
+		} else {
+			event.Result = api.AuditResultFailure
+		}
+
+		if req.InstanceID != "" {
+			for i := range event.Targets {
+				event.Targets[i].RolesAfter = auditRoles(r.Context(), req.InstanceID, event.Targets[i].User)
+			}
+		}
+
+		if err := auditLog.Record(event); err != nil {
+			logging.FromContext(r.Context()).Error(err, "Write audit event failed", "operation", operation)
+		}
+	}
+}
+
+// auditRoles reads the roles of user from metad, never from the role cache,
+// which could still hold roles from before the operation.
+func auditRoles(ctx context.Context, instanceID, user string) []api.AuditRole {
+	roles, err := utils.ListUserRoles(utils.WithoutCache(ctx), instanceID, user)
+	if err != nil {
+		return nil
+	}
+
+	res := []api.AuditRole{}
+	for _, role := range roles {
+		res = append(res, api.AuditRole{SpaceID: int64(role.SpaceID), Role: utils.RoleName(role.RoleType)})
+	}
+	return res
+}
+
+// responseCode extracts Code (or code) from a JSON response body.
+func responseCode(body []byte) int {
+	resp := map[string]interface{}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0
+	}
+	for _, key := range []string{"Code", "code"} {
+		if code, ok := resp[key].(float64); ok {
+			return int(code)
+		}
+	}
+	return 0
+}
+
+// responsePreviousValue extracts PreviousValue, the value a config change
+// replaced, from a JSON response body.
+func responsePreviousValue(body []byte) string {
+	resp := struct{ PreviousValue string }{}
+	json.Unmarshal(body, &resp)
+	return resp.PreviousValue
+}
+
+func AuditHandler(w http.ResponseWriter, r *http.Request) {
+	auditResponse := api.AuditResponse{}
+
+	if auditLog == nil {
+		auditResponse.Code = api.ErrNotFound
+		body, _ := json.Marshal(auditResponse)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+		return
+	}
+
+	filter, err := api.ParseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+
+	events, err := auditLog.Query(filter)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err, "Query audit log failed")
+		auditResponse.Code = api.ErrInternalError
+		body, _ := json.Marshal(auditResponse)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(body)
+		return
+	}
+
+	auditResponse.Events = events
+	body, _ := json.Marshal(auditResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
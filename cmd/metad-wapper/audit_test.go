@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestAuditLogShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Small enough that every event rotates the log of its replica.
+	one, err := NewAuditLog(filepath.Join(dir, "one.log"), 1, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer one.Close()
+	other, err := NewAuditLog(filepath.Join(dir, "other.log"), 1, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, l := range []*AuditLog{one, other, one, other, one} {
+		event := &api.AuditEvent{Time: start.Add(time.Duration(i) * time.Minute), Operation: "CreateUser", InstanceID: string(rune('a' + i))}
+		if err := l.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		log  *AuditLog
+		want string
+	}{
+		{"one", one, "abcde"},
+		{"other", other, "abcde"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := test.log.Query(&api.AuditFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			for _, event := range events {
+				got += event.InstanceID
+			}
+			if got != test.want {
+				t.Errorf("events of instances %q, want %q in time order", got, test.want)
+			}
+		})
+	}
+
+	events, err := other.Query(&api.AuditFilter{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].InstanceID != "d" || events[1].InstanceID != "e" {
+		t.Errorf("last 2 events = %+v, want those of d and e", events)
+	}
+}
+
+func TestAuditLogNotShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewAuditLog(filepath.Join(dir, "audit.log"), 1024, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, "unrelated.log"), []byte(`{"operation": "CreateUser"}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record(&api.AuditEvent{Operation: "DropSnapshot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := l.Query(&api.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Operation != "DropSnapshot" {
+		t.Errorf("events = %+v, want only the one recorded", events)
+	}
+
+	if _, err := NewAuditLog(filepath.Join(dir, "audit"), 1024, 1, true); err == nil {
+		t.Error("NewAuditLog made a shared log without an extension")
+	}
+}
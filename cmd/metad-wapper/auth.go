@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+)
+
+// tokenCaller is who a bearer token authenticates. Only admins may reach
+// the endpoints changing how the wrapper itself behaves or reading its
+// audit trail.
+type tokenCaller struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+// tokens maps the bearer tokens callers may authenticate with to who they
+// are. It is read in main from -tokens-file; with none every caller is
+// anonymous and the admin endpoints are refused.
+var tokens = map[string]tokenCaller{}
+
+// loadTokens reads the tokens file at path, a JSON object of token to
+// caller, e.g. {"s3cr3t": {"name": "console", "admin": true}}. A file
+// missing holds no tokens, as when the Secret it is mounted from wasn't made.
+func loadTokens(path string) (map[string]tokenCaller, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]tokenCaller{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	loaded := map[string]tokenCaller{}
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&loaded); err != nil {
+		return nil, err
+	}
+	for token, caller := range loaded {
+		if token == "" || caller.Name == "" {
+			return nil, fmt.Errorf("every token must be set and name its caller")
+		}
+	}
+	return loaded, nil
+}
+
+// authenticate returns the caller the bearer token of r authenticates,
+// false if r carries no token or one that isn't known.
+func authenticate(r *http.Request) (tokenCaller, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return tokenCaller{}, false
+	}
+	presented := []byte(strings.TrimPrefix(header, "Bearer "))
+	for token, caller := range tokens {
+		if subtle.ConstantTimeCompare(presented, []byte(token)) == 1 {
+			return caller, true
+		}
+	}
+	return tokenCaller{}, false
+}
+
+// requestCaller identifies who sent the request: the caller its token
+// authenticates, or else the host it came from. Nothing the caller merely
+// claims is taken, so a caller can't pass for another to dodge its limits or
+// in the audit trail.
+func requestCaller(r *http.Request) string {
+	if caller, ok := authenticate(r); ok {
+		return caller.Name
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// adminOnly refuses the requests not authenticated as an admin: with 401 if
+// they carry no known token, 403 if the caller isn't an admin.
+func adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := authenticate(r)
+		if ok && caller.Admin {
+			handler(w, r)
+			return
+		}
+
+		status, message := http.StatusUnauthorized, "an admin token is required"
+		if ok {
+			status, message = http.StatusForbidden, "caller "+caller.Name+" is not an admin"
+		}
+		logging.FromContext(r.Context()).Info("Request refused", "caller", requestCaller(r), "reason", message)
+		body, _ := json.Marshal(api.ErrorResponse{
+			Code:   api.ErrUnauthorized,
+			Errors: []api.FieldError{{Message: message}},
+		})
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metad-wapper"`)
+		}
+		w.WriteHeader(status)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func withTokens(t *testing.T, loaded map[string]tokenCaller) {
+	previous := tokens
+	tokens = loaded
+	t.Cleanup(func() { tokens = previous })
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]tokenCaller
+	}{
+		{"valid", `{"a": {"name": "console", "admin": true}, "b": {"name": "billing"}}`,
+			map[string]tokenCaller{"a": {Name: "console", Admin: true}, "b": {Name: "billing"}}},
+		{"none", `{}`, map[string]tokenCaller{}},
+		{"no name", `{"a": {"admin": true}}`, nil},
+		{"empty token", `{"": {"name": "console"}}`, nil},
+		{"unknown field", `{"a": {"name": "console", "role": "admin"}}`, nil},
+		{"not json", `a: console`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, "tokens.json")
+			if err := ioutil.WriteFile(path, []byte(test.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadTokens(path)
+			if test.want == nil {
+				if err == nil {
+					t.Errorf("loadTokens = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("loadTokens = %v, want %v", got, test.want)
+			}
+			for token, caller := range test.want {
+				if got[token] != caller {
+					t.Errorf("token %s = %+v, want %+v", token, got[token], caller)
+				}
+			}
+		})
+	}
+
+	// A file missing, as the optional Secret it is mounted from, holds none.
+	got, err := loadTokens(filepath.Join(dir, "missing.json"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("loadTokens = %v, %v, want no tokens", got, err)
+	}
+}
+
+func TestRequestCaller(t *testing.T) {
+	withTokens(t, map[string]tokenCaller{"s3cr3t": {Name: "console"}})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+	}{
+		{"token", "Bearer s3cr3t", "console"},
+		{"unknown token", "Bearer guess", "192.0.2.1"},
+		{"not bearer", "Basic s3cr3t", "192.0.2.1"},
+		{"anonymous", "", "192.0.2.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.RemoteAddr = "192.0.2.1:4711"
+			// Claiming a caller doesn't make one.
+			r.Header.Set("X-Caller", "console")
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			if got := requestCaller(r); got != test.want {
+				t.Errorf("requestCaller = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	withTokens(t, map[string]tokenCaller{
+		"admin": {Name: "console", Admin: true},
+		"user":  {Name: "billing"},
+	})
+	handler := adminOnly(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"admin", "Bearer admin", http.StatusOK},
+		{"not admin", "Bearer user", http.StatusForbidden},
+		{"unknown token", "Bearer guess", http.StatusUnauthorized},
+		{"anonymous", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != test.status {
+				t.Fatalf("status = %d, want %d", w.Code, test.status)
+			}
+			if test.status == http.StatusOK {
+				return
+			}
+			resp := api.ErrorResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response %q: %v", w.Body.String(), err)
+			}
+			if resp.Code != api.ErrUnauthorized {
+				t.Errorf("code = %d, want %d", resp.Code, api.ErrUnauthorized)
+			}
+		})
+	}
+}
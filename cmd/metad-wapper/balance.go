@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// Only metad's check is authoritative: it refuses a data balance with
+// E_BALANCER_RUNNING while a plan runs, whoever started it and through
+// whichever replica. The plan last started through the wrapper is kept in the
+// shared state, so any replica can report it when asked without a plan ID,
+// and refuse to balance leaders while it runs. Plans started around the
+// wrapper, from the console, aren't known to it.
+
+func balancePlanKey(instance string) string {
+	return "balance-plan." + instance
+}
+
+// lastBalancePlan returns the ID of the data balance plan last started on
+// instance through the wrapper.
+func lastBalancePlan(ctx context.Context, instance string) (int64, bool, error) {
+	var id int64
+	ok, err := sharedState.get(ctx, balancePlanKey(instance), &id)
+	return id, ok, err
+}
+
+// balancePlan sums up the progress of the tasks of plan id.
+func balancePlan(id int64, tasks []*nebula_metad.BalanceTask) api.BalancePlan {
+	plan := api.BalancePlan{ID: id, Tasks: []api.BalanceTask{}}
+	for _, task := range tasks {
+		plan.Tasks = append(plan.Tasks, api.BalanceTask{ID: task.Id, Result: task.Result_.String()})
+		switch task.Result_ {
+		case nebula_metad.TaskResult__SUCCEEDED:
+			plan.Succeeded++
+		case nebula_metad.TaskResult__FAILED:
+			plan.Failed++
+		default:
+			plan.InProgress++
+		}
+	}
+	plan.Total = len(plan.Tasks)
+
+	switch {
+	case plan.InProgress > 0:
+		plan.Status = api.BalanceInProgress
+	case plan.Failed > 0:
+		plan.Status = api.BalanceFailed
+	default:
+		plan.Status = api.BalanceSucceeded
+	}
+	return plan
+}
+
+// getBalancePlan asks metad after plan id; it returns the code to fail with
+// if that failed.
+func getBalancePlan(metadClient *utils.Client, logger logr.Logger, id int64) (api.BalancePlan, int) {
+	balanceReq := nebula_metad.NewBalanceReq()
+	balanceReq.Id = &id
+	balanceResp, err := metadClient.Balance(balanceReq)
+	if err != nil {
+		logger.Error(err, "Get balance plan failed", "planID", id)
+		return api.BalancePlan{}, metadErrorCode(err, api.ErrInternalError)
+	}
+	switch balanceResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+		return balancePlan(id, balanceResp.Tasks), 0
+	case nebula_metad.ErrorCode_E_NOT_FOUND, nebula_metad.ErrorCode_E_NO_RUNNING_BALANCE_PLAN:
+		logger.Info("Balance plan not found", "planID", id, "metadCode", balanceResp.Code.String())
+		return api.BalancePlan{}, api.ErrNoBalancePlan
+	}
+	logger.Info("Get balance plan failed", "planID", id, "metadCode", balanceResp.Code.String())
+	return api.BalancePlan{}, api.ErrInternalError
+}
+
+// checkNoBalanceRunning returns the plan last started on instance through
+// the wrapper and ErrBalanceRunning if it is still running, or a zero code.
+func checkNoBalanceRunning(ctx context.Context, metadClient *utils.Client, logger logr.Logger, instance string) (api.BalancePlan, int) {
+	id, ok, err := lastBalancePlan(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Read last balance plan failed")
+		return api.BalancePlan{}, api.ErrInternalError
+	}
+	if !ok {
+		return api.BalancePlan{}, 0
+	}
+	plan, code := getBalancePlan(metadClient, logger, id)
+	if code == api.ErrNoBalancePlan {
+		return api.BalancePlan{}, 0
+	}
+	if code != 0 {
+		return plan, code
+	}
+	if plan.Status == api.BalanceInProgress {
+		logger.Info("Balance plan still running", "planID", id, "inProgress", plan.InProgress)
+		return plan, api.ErrBalanceRunning
+	}
+	return plan, 0
+}
+
+func BalanceDataHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	balanceRequest := api.BalanceRequest{}
+	balanceResponse := api.BalanceResponse{}
+	if !readRequest(w, r, &balanceRequest) {
+		return
+	}
+	instance := balanceRequest.InstanceID
+
+	metadClient, err := makeMetadClient(r.Context(), instance)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	balanceResp, err := metadClient.Balance(nebula_metad.NewBalanceReq())
+	if err != nil {
+		logger.Error(err, "Start data balance failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+
+	switch balanceResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+	case nebula_metad.ErrorCode_E_BALANCED:
+		logger.Info("Partitions balanced already")
+		balanceResponse.Balanced = true
+		writeResponse(w, balanceResponse, false)
+		return
+	case nebula_metad.ErrorCode_E_BALANCER_RUNNING:
+		logger.Info("Start data balance refused, a plan is running")
+		balanceResponse.Code = api.ErrBalanceRunning
+		if plan, code := checkNoBalanceRunning(r.Context(), metadClient, logger, instance); code == api.ErrBalanceRunning {
+			balanceResponse.Plan = plan
+		}
+		writeResponse(w, balanceResponse, true)
+		return
+	default:
+		logger.Info("Start data balance failed", "metadCode", balanceResp.Code.String())
+		balanceResponse.Code = api.ErrInternalError
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+
+	logger.Info("Data balance started", "planID", balanceResp.Id)
+	if err := sharedState.set(r.Context(), balancePlanKey(instance), balanceResp.Id); err != nil {
+		// The plan runs all the same; only asking after it without its ID
+		// won't find it.
+		logger.Error(err, "Record balance plan failed", "planID", balanceResp.Id)
+	}
+
+	balanceResponse.Plan = balancePlan(balanceResp.Id, balanceResp.Tasks)
+	if len(balanceResp.Tasks) == 0 {
+		balanceResponse.Plan.Status = api.BalanceInProgress
+	}
+	writeResponse(w, balanceResponse, false)
+}
+
+func BalanceLeaderHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	balanceRequest := api.BalanceRequest{}
+	balanceResponse := api.BalanceLeaderResponse{}
+	if !readRequest(w, r, &balanceRequest) {
+		return
+	}
+	instance := balanceRequest.InstanceID
+
+	metadClient, err := makeMetadClient(r.Context(), instance)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	// Leaders moved while partitions move would be moved again.
+	if _, code := checkNoBalanceRunning(r.Context(), metadClient, logger, instance); code != 0 {
+		balanceResponse.Code = code
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+
+	balanceResp, err := metadClient.LeaderBalance(nebula_metad.NewLeaderBalanceReq())
+	if err != nil {
+		logger.Error(err, "Leader balance failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	switch balanceResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+	case nebula_metad.ErrorCode_E_BALANCER_RUNNING:
+		logger.Info("Leader balance refused, a balance is running")
+		balanceResponse.Code = api.ErrBalanceRunning
+		writeResponse(w, balanceResponse, true)
+		return
+	default:
+		logger.Info("Leader balance failed", "metadCode", balanceResp.Code.String())
+		balanceResponse.Code = api.ErrInternalError
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+
+	logger.Info("Leaders balanced")
+	writeResponse(w, balanceResponse, false)
+}
+
+func BalanceStopHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	balanceRequest := api.BalanceRequest{}
+	balanceResponse := api.BalanceResponse{}
+	if !readRequest(w, r, &balanceRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), balanceRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	stop := true
+	balanceReq := nebula_metad.NewBalanceReq()
+	balanceReq.Stop = &stop
+	balanceResp, err := metadClient.Balance(balanceReq)
+	if err != nil {
+		logger.Error(err, "Stop data balance failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	switch balanceResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+	case nebula_metad.ErrorCode_E_NO_RUNNING_BALANCE_PLAN:
+		logger.Info("Stop data balance failed, no plan is running")
+		balanceResponse.Code = api.ErrNoBalancePlan
+		writeResponse(w, balanceResponse, true)
+		return
+	default:
+		logger.Info("Stop data balance failed", "metadCode", balanceResp.Code.String())
+		balanceResponse.Code = api.ErrInternalError
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	logger.Info("Data balance stopped", "planID", balanceResp.Id)
+
+	// The task running when the plan is stopped still finishes, so the plan
+	// is reported as it stands.
+	plan, code := getBalancePlan(metadClient, logger, balanceResp.Id)
+	if code != 0 {
+		plan = api.BalancePlan{ID: balanceResp.Id, Tasks: []api.BalanceTask{}}
+	}
+	balanceResponse.Plan = plan
+	writeResponse(w, balanceResponse, false)
+}
+
+func BalanceStatusHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	balanceRequest := api.BalanceStatusRequest{}
+	balanceResponse := api.BalanceResponse{}
+	if !readRequest(w, r, &balanceRequest) {
+		return
+	}
+
+	id := balanceRequest.PlanID
+	if id == 0 {
+		last, ok, err := lastBalancePlan(r.Context(), balanceRequest.InstanceID)
+		if err != nil {
+			logger.Error(err, "Read last balance plan failed")
+			balanceResponse.Code = api.ErrInternalError
+			writeResponse(w, balanceResponse, true)
+			return
+		}
+		if !ok {
+			balanceResponse.Code = api.ErrNoBalancePlan
+			writeResponse(w, balanceResponse, true)
+			return
+		}
+		id = last
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), balanceRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		balanceResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, balanceResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	plan, code := getBalancePlan(metadClient, logger, id)
+	balanceResponse.Code = code
+	balanceResponse.Plan = plan
+	writeResponse(w, balanceResponse, code != 0)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestBalanceRunningElsewhere(t *testing.T) {
+	startFakeMetad(t, &fakeMetad{})
+	// A plan another replica started.
+	if err := sharedState.set(context.Background(), balancePlanKey("balancing"), int64(42)); err != nil {
+		t.Fatal(err)
+	}
+	defer sharedState.set(context.Background(), balancePlanKey("balancing"), nil)
+	body := `{"InstanceID": "balancing"}`
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		code    int
+	}{
+		// metad refuses, the plan running is reported.
+		{"data", BalanceDataHandler, http.StatusForbidden, api.ErrBalanceRunning},
+		// Refused before metad is asked to move leaders.
+		{"leader", BalanceLeaderHandler, http.StatusForbidden, api.ErrBalanceRunning},
+		{"status", BalanceStatusHandler, http.StatusOK, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			test.handler(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d", w.Code, test.status)
+			}
+			resp := api.BalanceResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response %q: %v", w.Body.String(), err)
+			}
+			if resp.Code != test.code {
+				t.Errorf("code = %d, want %d", resp.Code, test.code)
+			}
+			if test.name != "leader" && (resp.Plan.ID != 42 || resp.Plan.Status != api.BalanceInProgress) {
+				t.Errorf("plan = %+v, want plan 42 in progress", resp.Plan)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	"github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// withCacheTTL caches metadata for ttl until the test is over.
+func withCacheTTL(t *testing.T, ttl time.Duration) {
+	utils.SetCacheTTL(utils.CacheTTL{Spaces: ttl, Users: ttl, Roles: ttl})
+	t.Cleanup(func() { utils.SetCacheTTL(utils.CacheTTL{}) })
+}
+
+func dialFake(t *testing.T, ctx context.Context, instance string) *utils.Client {
+	c, err := utils.Dial(ctx, instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func listUsers(t *testing.T, c *utils.Client) {
+	if resp, err := c.ListUsers(nebula_metad.NewListUsersReq()); err != nil || resp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		t.Fatalf("ListUsers = %v, %v", resp, err)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	fake := &fakeMetad{}
+	startFakeMetad(t, fake)
+	c := dialFake(t, context.Background(), "cache-ttl")
+
+	// Not cached with a zero TTL.
+	listUsers(t, c)
+	listUsers(t, c)
+	if got := fake.called("ListUsers"); got != 2 {
+		t.Errorf("ListUsers reached metad %d times with a zero TTL, want 2", got)
+	}
+
+	withCacheTTL(t, time.Minute)
+	listUsers(t, c)
+	listUsers(t, c)
+	if got := fake.called("ListUsers"); got != 3 {
+		t.Errorf("ListUsers reached metad %d times, want 3", got)
+	}
+}
+
+func TestWithoutCache(t *testing.T) {
+	fake := &fakeMetad{}
+	startFakeMetad(t, fake)
+	withCacheTTL(t, time.Minute)
+
+	listUsers(t, dialFake(t, context.Background(), "without-cache"))
+	listUsers(t, dialFake(t, utils.WithoutCache(context.Background()), "without-cache"))
+	if got := fake.called("ListUsers"); got != 2 {
+		t.Errorf("ListUsers reached metad %d times bypassing the cache, want 2", got)
+	}
+	// What was read bypassing it is cached all the same.
+	listUsers(t, dialFake(t, context.Background(), "without-cache"))
+	if got := fake.called("ListUsers"); got != 2 {
+		t.Errorf("ListUsers reached metad %d times after the bypass, want 2", got)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	space := nebula.GraphSpaceID(1)
+	fake := &fakeMetad{spaces: []*nebula_metad.IdName{{Id: &nebula_metad.ID{SpaceID: &space}, Name: "nba"}}}
+	startFakeMetad(t, fake)
+	withCacheTTL(t, time.Minute)
+
+	roleItem := &nebula.RoleItem{User: "user", SpaceID: space, RoleType: nebula.RoleType_USER}
+	reads := map[string]func(c *utils.Client) error{
+		"ListUsers": func(c *utils.Client) error {
+			_, err := c.ListUsers(nebula_metad.NewListUsersReq())
+			return err
+		},
+		"GetUserRoles": func(c *utils.Client) error {
+			_, err := c.GetUserRoles(&nebula_metad.GetUserRolesReq{Account: "user"})
+			return err
+		},
+		"ListSpaces": func(c *utils.Client) error {
+			_, err := c.ListSpaces(nebula_metad.NewListSpacesReq())
+			return err
+		},
+		"GetSpace": func(c *utils.Client) error {
+			_, err := c.GetSpace(&nebula_metad.GetSpaceReq{SpaceName: "nba"})
+			return err
+		},
+	}
+	tests := []struct {
+		name  string
+		write func(c *utils.Client) (*nebula_metad.ExecResp, error)
+		stale []string
+	}{
+		{"CreateUser", func(c *utils.Client) (*nebula_metad.ExecResp, error) {
+			return c.CreateUser(&nebula_metad.CreateUserReq{Account: "user"})
+		}, []string{"ListUsers", "GetUserRoles"}},
+		{"DropUser", func(c *utils.Client) (*nebula_metad.ExecResp, error) {
+			return c.DropUser(&nebula_metad.DropUserReq{Account: "user"})
+		}, []string{"ListUsers", "GetUserRoles"}},
+		{"GrantRole", func(c *utils.Client) (*nebula_metad.ExecResp, error) {
+			return c.GrantRole(&nebula_metad.GrantRoleReq{RoleItem: roleItem})
+		}, []string{"GetUserRoles"}},
+		{"RevokeRole", func(c *utils.Client) (*nebula_metad.ExecResp, error) {
+			return c.RevokeRole(&nebula_metad.RevokeRoleReq{RoleItem: roleItem})
+		}, []string{"GetUserRoles"}},
+		{"CreateSpace", func(c *utils.Client) (*nebula_metad.ExecResp, error) {
+			return c.CreateSpace(&nebula_metad.CreateSpaceReq{Properties: &nebula_metad.SpaceProperties{SpaceName: "nba"}})
+		}, []string{"ListSpaces", "GetSpace"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := dialFake(t, context.Background(), "invalidation")
+			for method, read := range reads {
+				if err := read(c); err != nil {
+					t.Fatalf("%s: %v", method, err)
+				}
+			}
+			before := map[string]int{}
+			for method := range reads {
+				before[method] = fake.called(method)
+			}
+
+			if resp, err := test.write(c); err != nil || resp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+				t.Fatalf("%s = %v, %v", test.name, resp, err)
+			}
+
+			stale := map[string]bool{}
+			for _, method := range test.stale {
+				stale[method] = true
+			}
+			for method, read := range reads {
+				if err := read(c); err != nil {
+					t.Fatalf("%s: %v", method, err)
+				}
+				want := before[method]
+				if stale[method] {
+					want++
+				}
+				if got := fake.called(method); got != want {
+					t.Errorf("%s reached metad %d times after %s, want %d", method, got, test.name, want)
+				}
+			}
+		})
+	}
+}
+
+// A read racing with a write must not cache what it read before the write.
+func TestCacheGeneration(t *testing.T) {
+	gate := make(chan struct{})
+	fake := &fakeMetad{gates: map[string]chan struct{}{"ListUsers": gate}}
+	startFakeMetad(t, fake)
+	withCacheTTL(t, time.Minute)
+
+	read := make(chan error, 1)
+	go func() {
+		c, err := utils.Dial(context.Background(), "generation")
+		if err != nil {
+			read <- err
+			return
+		}
+		defer c.Close()
+		_, err = c.ListUsers(nebula_metad.NewListUsersReq())
+		read <- err
+	}()
+	// The read reached metad; the user is created before it is answered.
+	<-gate
+	c := dialFake(t, context.Background(), "generation")
+	if resp, err := c.CreateUser(&nebula_metad.CreateUserReq{Account: "user"}); err != nil || resp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		t.Fatalf("CreateUser = %v, %v", resp, err)
+	}
+	gate <- struct{}{}
+	if err := <-read; err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	fake.gates = nil
+	fake.mu.Unlock()
+	listUsers(t, c)
+	if got := fake.called("ListUsers"); got != 2 {
+		t.Errorf("ListUsers reached metad %d times, want 2: the read before the write was cached", got)
+	}
+}
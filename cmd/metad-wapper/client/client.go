@@ -0,0 +1,354 @@
+// Package client is a typed Go client for the metad wrapper's HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+// Error is returned when the wrapper answers with a non-zero Code.
+type Error struct {
+	StatusCode int
+	Code       int
+	Errors     []api.FieldError
+	// RetryAfter is how long the wrapper asked to wait before retrying a
+	// request turned away by its rate limits.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("metad-wapper: http %d, code %d", e.StatusCode, e.Code)
+	for _, fe := range e.Errors {
+		msg += "; " + fe.Error()
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf("; retry after %s", e.RetryAfter)
+	}
+	return msg
+}
+
+type Client struct {
+	// Endpoint is the base URL of the wrapper, e.g. http://metad-wapper:8880.
+	Endpoint   string
+	HTTPClient *http.Client
+	// NoCache asks the wrapper to read metad rather than its metadata cache.
+	NoCache bool
+	// Token is the bearer token the requests are sent with. It names the
+	// caller, and the admin endpoints require an admin's.
+	Token string
+}
+
+func New(endpoint string) *Client {
+	return &Client{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		HTTPClient: &http.Client{
+			Timeout: time.Second * 60,
+		},
+	}
+}
+
+// do sends req to path and decodes the reply into resp. code extracts the
+// Code field of resp so callers get an *Error for failed operations.
+func (c *Client) do(ctx context.Context, method, path string, req, resp interface{}, code func() int) error {
+	var body []byte
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	httpReq, err := http.NewRequest(method, c.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	if req != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.NoCache {
+		httpReq.Header.Set("Cache-Control", "no-cache")
+	}
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpResp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	respData, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch httpResp.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests:
+		errorResponse := api.ErrorResponse{}
+		if err := json.Unmarshal(respData, &errorResponse); err != nil {
+			return fmt.Errorf("metad-wapper: http %d: %s", httpResp.StatusCode, respData)
+		}
+		e := &Error{StatusCode: httpResp.StatusCode, Code: errorResponse.Code, Errors: errorResponse.Errors}
+		if seconds, err := strconv.Atoi(httpResp.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return e
+	}
+
+	if resp == nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return &Error{StatusCode: httpResp.StatusCode}
+		}
+		return nil
+	}
+
+	if err := json.Unmarshal(respData, resp); err != nil {
+		return fmt.Errorf("metad-wapper: http %d: decode %s: %v", httpResp.StatusCode, path, err)
+	}
+
+	if c := code(); c != 0 {
+		// Callers refused by the wrapper rather than metad get the reason
+		// in an ErrorResponse.
+		errorResponse := api.ErrorResponse{}
+		json.Unmarshal(respData, &errorResponse)
+		return &Error{StatusCode: httpResp.StatusCode, Code: c, Errors: errorResponse.Errors}
+	}
+	return nil
+}
+
+func (c *Client) ListSpaces(ctx context.Context, req api.ListSpaceRequest) (*api.ListSpaceResponse, error) {
+	resp := &api.ListSpaceResponse{}
+	err := c.do(ctx, "POST", api.PathListSpaces, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) ListUsers(ctx context.Context, req api.ListUsersRequest) (*api.ListUsersResponse, error) {
+	resp := &api.ListUsersResponse{}
+	err := c.do(ctx, "POST", api.PathListUsers, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) CreateSpace(ctx context.Context, req api.CreateSpaceRequest) error {
+	return c.do(ctx, "POST", api.PathCreateSpaces, req, nil, nil)
+}
+
+func (c *Client) CreateUser(ctx context.Context, req api.CreateUserRequest) (*api.CreateUserResponse, error) {
+	resp := &api.CreateUserResponse{}
+	err := c.do(ctx, "POST", api.PathCreateUsers, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// ClusterCost reports the resources of the cluster and its instances, and
+// their cost over window. Zero times take the wrapper's defaults.
+func (c *Client) ClusterCost(ctx context.Context, window api.BillingWindow) (*api.ClusterCostResponse, error) {
+	path := api.PathClusterCost
+	if q := window.Query().Encode(); q != "" {
+		path += "?" + q
+	}
+
+	resp := &api.ClusterCostResponse{}
+	err := c.do(ctx, "GET", path, nil, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) ChangeGod(ctx context.Context, req api.TransferGodUserRequest) (*api.TransferGodUserResponse, error) {
+	resp := &api.TransferGodUserResponse{}
+	err := c.do(ctx, "POST", api.PathChangeGod, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) RevokeUser(ctx context.Context, req api.RevokeUserRequest) (*api.RevokeUserResponse, error) {
+	resp := &api.RevokeUserResponse{}
+	err := c.do(ctx, "POST", api.PathDeleteUsers, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) Initialize(ctx context.Context, req api.InitializeRequest) (*api.CreateUserResponse, error) {
+	resp := &api.CreateUserResponse{}
+	err := c.do(ctx, "POST", api.PathInitialize, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) ListSpaceUsers(ctx context.Context, req api.ListUserRequest) (*api.ListUserResponse, error) {
+	resp := &api.ListUserResponse{}
+	err := c.do(ctx, "POST", api.PathListSpaceUsers, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) ListRootSpaceUsers(ctx context.Context, req api.ListRootUserRequest) (*api.ListUserResponse, error) {
+	resp := &api.ListUserResponse{}
+	err := c.do(ctx, "POST", api.PathListRootSpaceUsers, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) InstanceVersion(ctx context.Context, req api.InstanceInfoRequest) (*api.InstanceInfoResponse, error) {
+	resp := &api.InstanceInfoResponse{}
+	err := c.do(ctx, "POST", api.PathInstanceVersion, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) StorageHosts(ctx context.Context, req api.StorageHostsRequest) (*api.StorageHostsResponse, error) {
+	resp := &api.StorageHostsResponse{}
+	err := c.do(ctx, "POST", api.PathStorageHosts, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// BalanceData starts a data balance plan, moving partitions evenly across
+// the storage hosts.
+func (c *Client) BalanceData(ctx context.Context, req api.BalanceRequest) (*api.BalanceResponse, error) {
+	resp := &api.BalanceResponse{}
+	err := c.do(ctx, "POST", api.PathBalanceData, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) BalanceLeader(ctx context.Context, req api.BalanceRequest) (*api.BalanceLeaderResponse, error) {
+	resp := &api.BalanceLeaderResponse{}
+	err := c.do(ctx, "POST", api.PathBalanceLeader, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) StopBalance(ctx context.Context, req api.BalanceRequest) (*api.BalanceResponse, error) {
+	resp := &api.BalanceResponse{}
+	err := c.do(ctx, "POST", api.PathBalanceStop, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// BalanceStatus reports the progress of a data balance plan, task by task.
+func (c *Client) BalanceStatus(ctx context.Context, req api.BalanceStatusRequest) (*api.BalanceResponse, error) {
+	resp := &api.BalanceResponse{}
+	err := c.do(ctx, "POST", api.PathBalanceStatus, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// SubmitJob submits a compact or flush job on a space.
+func (c *Client) SubmitJob(ctx context.Context, req api.SubmitJobRequest) (*api.SubmitJobResponse, error) {
+	resp := &api.SubmitJobResponse{}
+	err := c.do(ctx, "POST", api.PathSubmitJob, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) ListJobs(ctx context.Context, req api.ListJobsRequest) (*api.ListJobsResponse, error) {
+	resp := &api.ListJobsResponse{}
+	err := c.do(ctx, "POST", api.PathListJobs, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// ShowJob reports a job and the tasks each storage host runs for it.
+func (c *Client) ShowJob(ctx context.Context, req api.JobRequest) (*api.JobResponse, error) {
+	resp := &api.JobResponse{}
+	err := c.do(ctx, "POST", api.PathShowJob, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) StopJob(ctx context.Context, req api.JobRequest) (*api.StopJobResponse, error) {
+	resp := &api.StopJobResponse{}
+	err := c.do(ctx, "POST", api.PathStopJob, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) CreateSnapshot(ctx context.Context, req api.CreateSnapshotRequest) (*api.CreateSnapshotResponse, error) {
+	resp := &api.CreateSnapshotResponse{}
+	err := c.do(ctx, "POST", api.PathCreateSnapshot, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// ListSnapshots lists the snapshots of an instance, newest first, and the
+// retention policy applied to them.
+func (c *Client) ListSnapshots(ctx context.Context, req api.SnapshotRequest) (*api.ListSnapshotsResponse, error) {
+	resp := &api.ListSnapshotsResponse{}
+	err := c.do(ctx, "POST", api.PathListSnapshots, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) DropSnapshot(ctx context.Context, req api.DropSnapshotRequest) (*api.DropSnapshotResponse, error) {
+	resp := &api.DropSnapshotResponse{}
+	err := c.do(ctx, "POST", api.PathDropSnapshot, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// SetSnapshotPolicy replaces the retention policy of an instance; a zero
+// policy keeps every snapshot.
+func (c *Client) SetSnapshotPolicy(ctx context.Context, req api.SetSnapshotPolicyRequest) (*api.SnapshotPolicyResponse, error) {
+	resp := &api.SnapshotPolicyResponse{}
+	err := c.do(ctx, "POST", api.PathSnapshotPolicy, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// ListConfigs lists the mutable flags registered with metad, all of them
+// if req.All is set.
+func (c *Client) ListConfigs(ctx context.Context, req api.ListConfigsRequest) (*api.ListConfigsResponse, error) {
+	resp := &api.ListConfigsResponse{}
+	err := c.do(ctx, "POST", api.PathListConfigs, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+func (c *Client) GetConfig(ctx context.Context, req api.GetConfigRequest) (*api.GetConfigResponse, error) {
+	resp := &api.GetConfigResponse{}
+	err := c.do(ctx, "POST", api.PathGetConfig, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// SetConfig sets a mutable flag, returning the value it replaced.
+func (c *Client) SetConfig(ctx context.Context, req api.SetConfigRequest) (*api.SetConfigResponse, error) {
+	resp := &api.SetConfigResponse{}
+	err := c.do(ctx, "POST", api.PathSetConfig, req, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// Audit queries the audit trail of mutating operations.
+func (c *Client) Audit(ctx context.Context, filter api.AuditFilter) (*api.AuditResponse, error) {
+	path := api.PathAudit
+	if q := filter.Query().Encode(); q != "" {
+		path += "?" + q
+	}
+
+	resp := &api.AuditResponse{}
+	err := c.do(ctx, "GET", path, nil, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// Status reports the reachability of the wrapper's dependencies and, if
+// instances is set, of the metad of every instance.
+func (c *Client) Status(ctx context.Context, instances bool) (*api.StatusResponse, error) {
+	path := api.PathStatus
+	if instances {
+		path += "?instances=true"
+	}
+
+	resp := &api.StatusResponse{}
+	err := c.do(ctx, "GET", path, nil, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// Limits reports the rate and concurrency limits in force.
+func (c *Client) Limits(ctx context.Context) (*api.LimitsResponse, error) {
+	resp := &api.LimitsResponse{}
+	err := c.do(ctx, "GET", api.PathLimits, nil, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// SetLimits replaces the rate and concurrency limits.
+func (c *Client) SetLimits(ctx context.Context, limits api.Limits) (*api.LimitsResponse, error) {
+	resp := &api.LimitsResponse{}
+	err := c.do(ctx, "POST", api.PathSetLimits, limits, resp, func() int { return resp.Code })
+	return resp, err
+}
+
+// OpenAPI fetches the OpenAPI document served by the wrapper.
+func (c *Client) OpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	resp := map[string]interface{}{}
+	err := c.do(ctx, "GET", api.PathOpenAPI, nil, &resp, func() int { return 0 })
+	return resp, err
+}
@@ -0,0 +1,393 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+var filledTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fill sets every field v holds to a value other than its zero one, so a
+// field lost on the way through JSON shows up in a comparison.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(3)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(3)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Map:
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(elem)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, elem)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(filledTime))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+// filled returns a copy of v with every field set. A pointer is filled
+// through, its Code left zero so the operation succeeds.
+func filled(v interface{}) interface{} {
+	value := reflect.New(reflect.TypeOf(v)).Elem()
+	fill(value)
+	if value.Kind() == reflect.Ptr {
+		if code := value.Elem().FieldByName("Code"); code.IsValid() {
+			code.SetInt(0)
+		}
+	}
+	return value.Interface()
+}
+
+// specOperations returns the operations of OpenAPISpec, by path and
+// lower-case method.
+func specOperations(t *testing.T) map[string]map[string]json.RawMessage {
+	spec := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	if err := json.Unmarshal([]byte(api.OpenAPISpec), &spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec.Paths
+}
+
+// recorded is a request as the wrapper saw it.
+type recorded struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// fakeWrapper serves reply to every request, recording the last one.
+func fakeWrapper(t *testing.T, status int, header http.Header, reply []byte) (*httptest.Server, *recorded) {
+	last := &recorded{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		*last = recorded{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, header: r.Header, body: body}
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		w.Write(reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, last
+}
+
+// TestClient calls every method against a fake wrapper, checking the
+// request sent matches the operation the spec and api.Endpoints describe
+// and the reply is decoded without losing a field.
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	window := api.BillingWindow{Start: filledTime, End: filledTime.Add(time.Hour)}
+	filter := filled(api.AuditFilter{}).(api.AuditFilter)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		query  string
+		// req is the body sent, nil if none.
+		req interface{}
+		// resp is the reply served, nil if the method returns none.
+		resp interface{}
+		call func(c *Client, req interface{}) (interface{}, error)
+	}{
+		{"ListSpaces", "POST", api.PathListSpaces, "", filled(api.ListSpaceRequest{}), filled(&api.ListSpaceResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListSpaces(ctx, req.(api.ListSpaceRequest))
+			}},
+		{"ListUsers", "POST", api.PathListUsers, "", filled(api.ListUsersRequest{}), filled(&api.ListUsersResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListUsers(ctx, req.(api.ListUsersRequest))
+			}},
+		{"CreateSpace", "POST", api.PathCreateSpaces, "", filled(api.CreateSpaceRequest{}), nil,
+			func(c *Client, req interface{}) (interface{}, error) {
+				return nil, c.CreateSpace(ctx, req.(api.CreateSpaceRequest))
+			}},
+		{"CreateUser", "POST", api.PathCreateUsers, "", filled(api.CreateUserRequest{}), filled(&api.CreateUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.CreateUser(ctx, req.(api.CreateUserRequest))
+			}},
+		{"ClusterCost", "GET", api.PathClusterCost, window.Query().Encode(), nil, filled(&api.ClusterCostResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.ClusterCost(ctx, window) }},
+		{"ChangeGod", "POST", api.PathChangeGod, "", filled(api.TransferGodUserRequest{}), filled(&api.TransferGodUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ChangeGod(ctx, req.(api.TransferGodUserRequest))
+			}},
+		{"RevokeUser", "POST", api.PathDeleteUsers, "", filled(api.RevokeUserRequest{}), filled(&api.RevokeUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.RevokeUser(ctx, req.(api.RevokeUserRequest))
+			}},
+		{"Initialize", "POST", api.PathInitialize, "", filled(api.InitializeRequest{}), filled(&api.CreateUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.Initialize(ctx, req.(api.InitializeRequest))
+			}},
+		{"ListSpaceUsers", "POST", api.PathListSpaceUsers, "", filled(api.ListUserRequest{}), filled(&api.ListUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListSpaceUsers(ctx, req.(api.ListUserRequest))
+			}},
+		{"ListRootSpaceUsers", "POST", api.PathListRootSpaceUsers, "", filled(api.ListRootUserRequest{}), filled(&api.ListUserResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListRootSpaceUsers(ctx, req.(api.ListRootUserRequest))
+			}},
+		{"InstanceVersion", "POST", api.PathInstanceVersion, "", filled(api.InstanceInfoRequest{}), filled(&api.InstanceInfoResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.InstanceVersion(ctx, req.(api.InstanceInfoRequest))
+			}},
+		{"StorageHosts", "POST", api.PathStorageHosts, "", filled(api.StorageHostsRequest{}), filled(&api.StorageHostsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.StorageHosts(ctx, req.(api.StorageHostsRequest))
+			}},
+		{"BalanceData", "POST", api.PathBalanceData, "", filled(api.BalanceRequest{}), filled(&api.BalanceResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.BalanceData(ctx, req.(api.BalanceRequest))
+			}},
+		{"BalanceLeader", "POST", api.PathBalanceLeader, "", filled(api.BalanceRequest{}), filled(&api.BalanceLeaderResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.BalanceLeader(ctx, req.(api.BalanceRequest))
+			}},
+		{"StopBalance", "POST", api.PathBalanceStop, "", filled(api.BalanceRequest{}), filled(&api.BalanceResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.StopBalance(ctx, req.(api.BalanceRequest))
+			}},
+		{"BalanceStatus", "POST", api.PathBalanceStatus, "", filled(api.BalanceStatusRequest{}), filled(&api.BalanceResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.BalanceStatus(ctx, req.(api.BalanceStatusRequest))
+			}},
+		{"SubmitJob", "POST", api.PathSubmitJob, "", filled(api.SubmitJobRequest{}), filled(&api.SubmitJobResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.SubmitJob(ctx, req.(api.SubmitJobRequest))
+			}},
+		{"ListJobs", "POST", api.PathListJobs, "", filled(api.ListJobsRequest{}), filled(&api.ListJobsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListJobs(ctx, req.(api.ListJobsRequest))
+			}},
+		{"ShowJob", "POST", api.PathShowJob, "", filled(api.JobRequest{}), filled(&api.JobResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.ShowJob(ctx, req.(api.JobRequest)) }},
+		{"StopJob", "POST", api.PathStopJob, "", filled(api.JobRequest{}), filled(&api.StopJobResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.StopJob(ctx, req.(api.JobRequest)) }},
+		{"CreateSnapshot", "POST", api.PathCreateSnapshot, "", filled(api.CreateSnapshotRequest{}), filled(&api.CreateSnapshotResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.CreateSnapshot(ctx, req.(api.CreateSnapshotRequest))
+			}},
+		{"ListSnapshots", "POST", api.PathListSnapshots, "", filled(api.SnapshotRequest{}), filled(&api.ListSnapshotsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListSnapshots(ctx, req.(api.SnapshotRequest))
+			}},
+		{"DropSnapshot", "POST", api.PathDropSnapshot, "", filled(api.DropSnapshotRequest{}), filled(&api.DropSnapshotResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.DropSnapshot(ctx, req.(api.DropSnapshotRequest))
+			}},
+		{"SetSnapshotPolicy", "POST", api.PathSnapshotPolicy, "", filled(api.SetSnapshotPolicyRequest{}), filled(&api.SnapshotPolicyResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.SetSnapshotPolicy(ctx, req.(api.SetSnapshotPolicyRequest))
+			}},
+		{"ListConfigs", "POST", api.PathListConfigs, "", filled(api.ListConfigsRequest{}), filled(&api.ListConfigsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.ListConfigs(ctx, req.(api.ListConfigsRequest))
+			}},
+		{"GetConfig", "POST", api.PathGetConfig, "", filled(api.GetConfigRequest{}), filled(&api.GetConfigResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.GetConfig(ctx, req.(api.GetConfigRequest))
+			}},
+		{"SetConfig", "POST", api.PathSetConfig, "", filled(api.SetConfigRequest{}), filled(&api.SetConfigResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) {
+				return c.SetConfig(ctx, req.(api.SetConfigRequest))
+			}},
+		{"Audit", "GET", api.PathAudit, filter.Query().Encode(), nil, filled(&api.AuditResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.Audit(ctx, filter) }},
+		{"Status", "GET", api.PathStatus, "instances=true", nil, filled(&api.StatusResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.Status(ctx, true) }},
+		{"Limits", "GET", api.PathLimits, "", nil, filled(&api.LimitsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.Limits(ctx) }},
+		{"SetLimits", "POST", api.PathSetLimits, "", filled(api.Limits{}), filled(&api.LimitsResponse{}),
+			func(c *Client, req interface{}) (interface{}, error) { return c.SetLimits(ctx, req.(api.Limits)) }},
+		{"OpenAPI", "GET", api.PathOpenAPI, "", nil, map[string]interface{}{"openapi": "3.0.3"},
+			func(c *Client, req interface{}) (interface{}, error) { return c.OpenAPI(ctx) }},
+	}
+
+	endpoints := map[string]api.Endpoint{}
+	for _, ep := range api.Endpoints {
+		endpoints[ep.Path] = ep
+	}
+	operations := specOperations(t)
+	covered := map[string]bool{}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			covered[test.path] = true
+			// The document describing the API is not among its operations.
+			if test.path != api.PathOpenAPI {
+				ep, ok := endpoints[test.path]
+				if !ok {
+					t.Fatalf("%s is not in api.Endpoints", test.path)
+				}
+				if ep.Method != test.method {
+					t.Errorf("api.Endpoints has %s %s, client sends %s", ep.Method, test.path, test.method)
+				}
+				if reflect.TypeOf(ep.Request) != reflect.TypeOf(test.req) {
+					t.Errorf("api.Endpoints takes %T, client sends %T", ep.Request, test.req)
+				}
+				if ep.Response != nil && reflect.PtrTo(reflect.TypeOf(ep.Response)) != reflect.TypeOf(test.resp) {
+					t.Errorf("api.Endpoints returns %T, client decodes %T", ep.Response, test.resp)
+				}
+				if _, ok := operations[test.path][strings.ToLower(test.method)]; !ok {
+					t.Errorf("spec has no %s %s", test.method, test.path)
+				}
+			}
+
+			reply := []byte("{}")
+			if test.resp != nil {
+				var err error
+				if reply, err = json.Marshal(test.resp); err != nil {
+					t.Fatal(err)
+				}
+			}
+			server, last := fakeWrapper(t, http.StatusOK, nil, reply)
+			c := New(server.URL + "/")
+			c.Token = "secret"
+			c.NoCache = true
+
+			got, err := test.call(c, test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if last.method != test.method || last.path != test.path {
+				t.Errorf("request = %s %s, want %s %s", last.method, last.path, test.method, test.path)
+			}
+			if last.query != test.query {
+				t.Errorf("query = %q, want %q", last.query, test.query)
+			}
+			if auth := last.header.Get("Authorization"); auth != "Bearer secret" {
+				t.Errorf("Authorization = %q, want the token", auth)
+			}
+			if cache := last.header.Get("Cache-Control"); cache != "no-cache" {
+				t.Errorf("Cache-Control = %q, want no-cache", cache)
+			}
+
+			if test.req == nil {
+				if len(last.body) != 0 {
+					t.Errorf("body = %s, want none", last.body)
+				}
+			} else {
+				if ct := last.header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+				sent := reflect.New(reflect.TypeOf(test.req))
+				if err := json.Unmarshal(last.body, sent.Interface()); err != nil {
+					t.Fatalf("body %s: %v", last.body, err)
+				}
+				if !reflect.DeepEqual(sent.Elem().Interface(), test.req) {
+					t.Errorf("body = %+v, want %+v", sent.Elem().Interface(), test.req)
+				}
+			}
+
+			if test.resp != nil && !reflect.DeepEqual(got, test.resp) {
+				t.Errorf("response = %+v, want %+v", got, test.resp)
+			}
+		})
+	}
+
+	for _, ep := range api.Endpoints {
+		if !covered[ep.Path] {
+			t.Errorf("%s %s: no client method tested", ep.Method, ep.Path)
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	fieldErrors := []api.FieldError{{Field: "InstanceID", Rule: "required", Message: "is required"}}
+	reply := func(code int, errors []api.FieldError) []byte {
+		data, _ := json.Marshal(api.ErrorResponse{Code: code, Errors: errors})
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		reply  []byte
+		// noBody calls CreateSpace, which decodes no reply.
+		noBody bool
+		want   *Error
+	}{
+		{"invalid request", http.StatusBadRequest, nil, reply(api.ErrInvalidRequestBody, fieldErrors), false,
+			&Error{StatusCode: http.StatusBadRequest, Code: api.ErrInvalidRequestBody, Errors: fieldErrors}},
+		{"unauthorized", http.StatusUnauthorized, nil, reply(api.ErrUnauthorized, nil), false,
+			&Error{StatusCode: http.StatusUnauthorized, Code: api.ErrUnauthorized}},
+		{"rate limited", http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}}, reply(api.ErrTooManyRequests, nil), false,
+			&Error{StatusCode: http.StatusTooManyRequests, Code: api.ErrTooManyRequests, RetryAfter: 3 * time.Second}},
+		{"metad failed", http.StatusForbidden, nil, reply(api.ErrInstanceUnavailable, nil), false,
+			&Error{StatusCode: http.StatusForbidden, Code: api.ErrInstanceUnavailable}},
+		{"non-zero code on 200", http.StatusOK, nil, reply(api.ErrNotFound, nil), false,
+			&Error{StatusCode: http.StatusOK, Code: api.ErrNotFound}},
+		{"no reply body", http.StatusForbidden, nil, nil, true,
+			&Error{StatusCode: http.StatusForbidden}},
+		{"not JSON", http.StatusInternalServerError, nil, []byte("internal error"), false, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := fakeWrapper(t, test.status, test.header, test.reply)
+			c := New(server.URL)
+
+			var err error
+			if test.noBody {
+				err = c.CreateSpace(context.Background(), api.CreateSpaceRequest{})
+			} else {
+				_, err = c.StorageHosts(context.Background(), api.StorageHostsRequest{})
+			}
+			if err == nil {
+				t.Fatal("succeeded, want an error")
+			}
+			got, ok := err.(*Error)
+			if test.want == nil {
+				if ok {
+					t.Errorf("err = %#v, want a decode error", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("err = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
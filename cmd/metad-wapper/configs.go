@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// metad keeps config values as bytes: numbers as the 8 little endian bytes
+// of an int64 or a float64, booleans as one byte, strings and nested
+// options as they are.
+
+// decodeConfigValue renders the value of a config of type t as text.
+func decodeConfigValue(t nebula_metad.ConfigType, value []byte) string {
+	switch t {
+	case nebula_metad.ConfigType_INT64:
+		if len(value) == 8 {
+			return strconv.FormatInt(int64(binary.LittleEndian.Uint64(value)), 10)
+		}
+	case nebula_metad.ConfigType_DOUBLE:
+		if len(value) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(value)), 'g', -1, 64)
+		}
+	case nebula_metad.ConfigType_BOOL:
+		if len(value) == 1 {
+			return strconv.FormatBool(value[0] != 0)
+		}
+	}
+	return string(value)
+}
+
+// encodeConfigValue parses text as a value of a config of type t.
+func encodeConfigValue(t nebula_metad.ConfigType, text string) ([]byte, error) {
+	value := make([]byte, 8)
+	switch t {
+	case nebula_metad.ConfigType_INT64:
+		i, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		binary.LittleEndian.PutUint64(value, uint64(i))
+		return value, nil
+	case nebula_metad.ConfigType_DOUBLE:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("must be a number")
+		}
+		binary.LittleEndian.PutUint64(value, math.Float64bits(f))
+		return value, nil
+	case nebula_metad.ConfigType_BOOL:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case nebula_metad.ConfigType_NESTED:
+		options := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(text), &options); err != nil {
+			return nil, fmt.Errorf("must be a JSON object of options")
+		}
+	}
+	return []byte(text), nil
+}
+
+func apiConfigItem(item *nebula_metad.ConfigItem) api.ConfigItem {
+	return api.ConfigItem{
+		Module: item.Module.String(),
+		Name:   item.Name,
+		Type:   item.Type.String(),
+		Mode:   item.Mode.String(),
+		Value:  decodeConfigValue(item.Type, item.Value),
+	}
+}
+
+// getConfig reads the config name of module, returning the code to fail with
+// if that failed.
+func getConfig(metadClient *utils.Client, logger logr.Logger, module, name string) (*nebula_metad.ConfigItem, int) {
+	item := nebula_metad.NewConfigItem()
+	item.Module = nebula_metad.ConfigModuleToValue[module]
+	item.Name = name
+	getConfigReq := nebula_metad.NewGetConfigReq()
+	getConfigReq.Item = item
+	getConfigResp, err := metadClient.GetConfig(getConfigReq)
+	if err != nil {
+		logger.Error(err, "Get config failed", "module", module, "name", name)
+		return nil, metadErrorCode(err, api.ErrInternalError)
+	}
+	switch getConfigResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+		if len(getConfigResp.Items) != 0 {
+			return getConfigResp.Items[0], 0
+		}
+	case nebula_metad.ErrorCode_E_NOT_FOUND:
+	default:
+		logger.Info("Get config failed", "module", module, "name", name, "metadCode", getConfigResp.Code.String())
+		return nil, api.ErrInternalError
+	}
+	logger.Info("Config not found", "module", module, "name", name)
+	return nil, api.ErrConfigNotFound
+}
+
+func ListConfigsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	listConfigsRequest := api.ListConfigsRequest{}
+	listConfigsResponse := api.ListConfigsResponse{}
+	if !readRequest(w, r, &listConfigsRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), listConfigsRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		listConfigsResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listConfigsResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	listConfigsReq := nebula_metad.NewListConfigsReq()
+	listConfigsReq.Module = nebula_metad.ConfigModule_ALL
+	if listConfigsRequest.Module != "" {
+		listConfigsReq.Module = nebula_metad.ConfigModuleToValue[listConfigsRequest.Module]
+	}
+	listConfigsResp, err := metadClient.ListConfigs(listConfigsReq)
+	if err != nil {
+		logger.Error(err, "List configs failed")
+		listConfigsResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listConfigsResponse, true)
+		return
+	}
+	if listConfigsResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List configs failed", "metadCode", listConfigsResp.Code.String())
+		listConfigsResponse.Code = api.ErrInternalError
+		writeResponse(w, listConfigsResponse, true)
+		return
+	}
+
+	listConfigsResponse.Configs = []api.ConfigItem{}
+	for _, item := range listConfigsResp.Items {
+		if !listConfigsRequest.All && item.Mode != nebula_metad.ConfigMode_MUTABLE {
+			continue
+		}
+		listConfigsResponse.Configs = append(listConfigsResponse.Configs, apiConfigItem(item))
+	}
+	sort.Slice(listConfigsResponse.Configs, func(i, j int) bool {
+		a, b := listConfigsResponse.Configs[i], listConfigsResponse.Configs[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Name < b.Name
+	})
+	writeResponse(w, listConfigsResponse, false)
+}
+
+func GetConfigHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	getConfigRequest := api.GetConfigRequest{}
+	getConfigResponse := api.GetConfigResponse{}
+	if !readRequest(w, r, &getConfigRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), getConfigRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		getConfigResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, getConfigResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	item, code := getConfig(metadClient, logger, getConfigRequest.Module, getConfigRequest.Name)
+	if code != 0 {
+		getConfigResponse.Code = code
+		writeResponse(w, getConfigResponse, true)
+		return
+	}
+	getConfigResponse.Config = apiConfigItem(item)
+	writeResponse(w, getConfigResponse, false)
+}
+
+func SetConfigHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	setConfigRequest := api.SetConfigRequest{}
+	setConfigResponse := api.SetConfigResponse{}
+	if !readRequest(w, r, &setConfigRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), setConfigRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		setConfigResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, setConfigResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	// The value is checked against the type the flag is registered with,
+	// which metad doesn't do itself.
+	item, code := getConfig(metadClient, logger, setConfigRequest.Module, setConfigRequest.Name)
+	if code != 0 {
+		setConfigResponse.Code = code
+		writeResponse(w, setConfigResponse, true)
+		return
+	}
+	setConfigResponse.PreviousValue = decodeConfigValue(item.Type, item.Value)
+	if item.Mode != nebula_metad.ConfigMode_MUTABLE {
+		logger.Info("Config is not mutable", "module", setConfigRequest.Module, "name", setConfigRequest.Name, "mode", item.Mode.String())
+		setConfigResponse.Code = api.ErrConfigImmutable
+		writeResponse(w, setConfigResponse, true)
+		return
+	}
+	value, err := encodeConfigValue(item.Type, setConfigRequest.Value)
+	if err != nil {
+		writeRequestError(w, r, ValidationErrors{{Field: "Value", Rule: "type",
+			Message: err.Error() + ", as " + setConfigRequest.Name + " is " + item.Type.String()}})
+		return
+	}
+
+	newItem := nebula_metad.NewConfigItem()
+	newItem.Module = item.Module
+	newItem.Name = item.Name
+	newItem.Type = item.Type
+	newItem.Mode = item.Mode
+	newItem.Value = value
+	setConfigReq := nebula_metad.NewSetConfigReq()
+	setConfigReq.Item = newItem
+	setConfigResp, err := metadClient.SetConfig(setConfigReq)
+	if err != nil {
+		logger.Error(err, "Set config failed")
+		setConfigResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, setConfigResponse, true)
+		return
+	}
+	switch setConfigResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+	case nebula_metad.ErrorCode_E_CONFIG_IMMUTABLE:
+		setConfigResponse.Code = api.ErrConfigImmutable
+		writeResponse(w, setConfigResponse, true)
+		return
+	case nebula_metad.ErrorCode_E_NOT_FOUND:
+		setConfigResponse.Code = api.ErrConfigNotFound
+		writeResponse(w, setConfigResponse, true)
+		return
+	default:
+		logger.Info("Set config failed", "metadCode", setConfigResp.Code.String())
+		setConfigResponse.Code = api.ErrInternalError
+		writeResponse(w, setConfigResponse, true)
+		return
+	}
+
+	setConfigResponse.Config = apiConfigItem(newItem)
+	logger.Info("Config set", "module", setConfigRequest.Module, "name", setConfigRequest.Name,
+		"value", setConfigResponse.Config.Value, "previousValue", setConfigResponse.PreviousValue)
+	writeResponse(w, setConfigResponse, false)
+}
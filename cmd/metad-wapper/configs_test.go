@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+func TestConfigValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		configType nebula_metad.ConfigType
+		text       string
+		want       string
+		wantErr    bool
+	}{
+		{"int64", nebula_metad.ConfigType_INT64, "42", "42", false},
+		{"negative int64", nebula_metad.ConfigType_INT64, "-9223372036854775808", "-9223372036854775808", false},
+		{"int64 spaced", nebula_metad.ConfigType_INT64, " 7 ", "7", false},
+		{"not an int64", nebula_metad.ConfigType_INT64, "4.2", "", true},
+		{"int64 overflow", nebula_metad.ConfigType_INT64, "9223372036854775808", "", true},
+		{"double", nebula_metad.ConfigType_DOUBLE, "0.25", "0.25", false},
+		{"double exponent", nebula_metad.ConfigType_DOUBLE, "1e21", "1e+21", false},
+		{"not a double", nebula_metad.ConfigType_DOUBLE, "fast", "", true},
+		{"NaN", nebula_metad.ConfigType_DOUBLE, "NaN", "", true},
+		{"infinity", nebula_metad.ConfigType_DOUBLE, "+Inf", "", true},
+		{"true", nebula_metad.ConfigType_BOOL, "true", "true", false},
+		{"false", nebula_metad.ConfigType_BOOL, "0", "false", false},
+		{"not a bool", nebula_metad.ConfigType_BOOL, "yes", "", true},
+		{"nested", nebula_metad.ConfigType_NESTED, `{"max_bytes_for_level_base": "268435456"}`, `{"max_bytes_for_level_base": "268435456"}`, false},
+		{"nested not an object", nebula_metad.ConfigType_NESTED, `["a"]`, "", true},
+		{"nested not JSON", nebula_metad.ConfigType_NESTED, `a=1`, "", true},
+		{"string", nebula_metad.ConfigType_STRING, "info", "info", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := encodeConfigValue(test.configType, test.text)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("encodeConfigValue = %v, want an error", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decodeConfigValue(test.configType, value); got != test.want {
+				t.Errorf("decodeConfigValue(encodeConfigValue(%q)) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigValueLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		configType nebula_metad.ConfigType
+		value      []byte
+		want       string
+	}{
+		{"int64", nebula_metad.ConfigType_INT64, []byte{1, 0, 0, 0, 0, 0, 0, 0}, "1"},
+		{"bool", nebula_metad.ConfigType_BOOL, []byte{1}, "true"},
+		// Of a length not the type's, the value is shown as sent.
+		{"short int64", nebula_metad.ConfigType_INT64, []byte("12"), "12"},
+		{"long double", nebula_metad.ConfigType_DOUBLE, []byte("0.5 or so"), "0.5 or so"},
+		{"long bool", nebula_metad.ConfigType_BOOL, []byte("true"), "true"},
+		{"empty", nebula_metad.ConfigType_INT64, nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := decodeConfigValue(test.configType, test.value); got != test.want {
+				t.Errorf("decodeConfigValue(%v) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+)
+
+// metadHandlers are the handlers of the endpoints metad serves, by path.
+var metadHandlers = map[string]http.HandlerFunc{
+	api.PathListSpaces:         ListSpaceHandler,
+	api.PathListUsers:          ListUsersHandler,
+	api.PathCreateSpaces:       CreateSpaceHandler,
+	api.PathCreateUsers:        CreateUserHandler,
+	api.PathChangeGod:          changeGod,
+	api.PathDeleteUsers:        revokeUsersHandler,
+	api.PathInitialize:         InitializeHandler,
+	api.PathListSpaceUsers:     ListSpaceUsersHandler,
+	api.PathListRootSpaceUsers: ListRootSpaceUsersHandler,
+	api.PathStorageHosts:       StorageHostsHandler,
+	api.PathBalanceData:        BalanceDataHandler,
+	api.PathBalanceLeader:      BalanceLeaderHandler,
+	api.PathBalanceStop:        BalanceStopHandler,
+	api.PathBalanceStatus:      BalanceStatusHandler,
+	api.PathSubmitJob:          SubmitJobHandler,
+	api.PathListJobs:           ListJobsHandler,
+	api.PathShowJob:            ShowJobHandler,
+	api.PathStopJob:            StopJobHandler,
+	api.PathCreateSnapshot:     CreateSnapshotHandler,
+	api.PathListSnapshots:      ListSnapshotsHandler,
+	api.PathDropSnapshot:       DropSnapshotHandler,
+	api.PathListConfigs:        ListConfigsHandler,
+	api.PathGetConfig:          GetConfigHandler,
+	api.PathSetConfig:          SetConfigHandler,
+}
+
+// validRequestOf returns a request of typ that passes validation, its
+// required fields set to values their rules accept.
+func validRequestOf(typ reflect.Type) interface{} {
+	req := reflect.New(typ)
+	for i := 0; i < typ.NumField(); i++ {
+		field := req.Elem().Field(i)
+		rules := strings.Split(typ.Field(i).Tag.Get("validate"), ",")
+		for _, rule := range rules {
+			switch {
+			case field.Kind() == reflect.String && rules[0] == "required":
+				switch rule {
+				case "instance":
+					field.SetString("contract")
+				case "name":
+					field.SetString("nebula")
+				case "role":
+					field.SetString("GOD")
+				case "order":
+					field.SetString("asc")
+				case "job":
+					field.SetString(api.JobFlush)
+				case "module":
+					field.SetString("STORAGE")
+				case "required":
+					field.SetString("value")
+				}
+			case strings.HasPrefix(rule, "min="):
+				if min, _ := strconv.ParseInt(strings.TrimPrefix(rule, "min="), 10, 64); min > 0 {
+					field.SetInt(min)
+				}
+			}
+		}
+	}
+	return req.Interface()
+}
+
+// TestHandlersFailWithSpecStatus drives every metad backed handler with
+// metad down, checking the failure is reported with a status the spec
+// lists for the operation rather than 200.
+func TestHandlersFailWithSpecStatus(t *testing.T) {
+	spec := struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+	}{}
+	if err := json.Unmarshal([]byte(api.OpenAPISpec), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing listens on the address of a listener closed.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	utils.SetMetadAddress(listener.Addr().String())
+	utils.SetRetryPolicy(utils.RetryPolicy{Attempts: 1})
+	utils.SetBreakerPolicy(utils.BreakerPolicy{})
+	utils.SetCacheTTL(utils.CacheTTL{})
+
+	for _, ep := range api.Endpoints {
+		handler, ok := metadHandlers[ep.Path]
+		if !ok {
+			continue
+		}
+		t.Run(ep.Path, func(t *testing.T) {
+			req := validRequestOf(reflect.TypeOf(ep.Request))
+			if err := Validate(req); err != nil {
+				t.Fatalf("request %#v is not valid: %v", req, err)
+			}
+			body, _ := json.Marshal(req)
+
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(ep.Method, ep.Path, bytes.NewReader(body)))
+			if w.Code == http.StatusOK {
+				t.Fatalf("status = 200 with metad down, body %s", w.Body.String())
+			}
+			responses := spec.Paths[ep.Path][strings.ToLower(ep.Method)].Responses
+			if _, ok := responses[strconv.Itoa(w.Code)]; !ok {
+				t.Errorf("status = %d, not a response the spec lists", w.Code)
+			}
+			if code := responseCode(w.Body.Bytes()); ep.Response != nil && code == 0 {
+				t.Errorf("code = 0 with metad down, body %s", w.Body.String())
+			}
+		})
+	}
+}
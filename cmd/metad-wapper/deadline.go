@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+)
+
+// requestTimeout bounds the work done for one request. It is set in main
+// from -request-timeout and kept below the server's write timeout, so the
+// caller hears why its request failed.
+var requestTimeout = time.Minute
+
+// detachedContext carries the values of a request context, such as its
+// logger and span, but neither its deadline nor its cancellation.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// bounded cancels the metad and Kubernetes calls made for a request once it
+// has run for requestTimeout, or as soon as the caller goes away.
+func bounded(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+		runBounded(handler, w, r.WithContext(ctx))
+	}
+}
+
+// committed bounds a mutating operation by requestTimeout but lets it run on
+// if the caller goes away: an operation made of several metad calls, such
+// as changeGod, would otherwise be left half done.
+func committed(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(detachedContext{r.Context()}, requestTimeout)
+		defer cancel()
+		runBounded(handler, w, r.WithContext(ctx))
+	}
+}
+
+func runBounded(handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	handler(w, r)
+
+	switch r.Context().Err() {
+	case context.DeadlineExceeded:
+		logging.FromContext(r.Context()).Info("Request deadline exceeded", "timeout", requestTimeout.Seconds())
+	case context.Canceled:
+		logging.FromContext(r.Context()).Info("Request cancelled by the caller")
+	}
+}
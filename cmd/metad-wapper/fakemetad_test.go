@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	"github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// fakeMetad answers the RPCs the handler tests make. An RPC it doesn't
+// implement panics, failing the test binary.
+type fakeMetad struct {
+	nebula_metad.MetaService
+
+	mu sync.Mutex
+	// drop names the RPCs whose replies are cut off: the connection is
+	// closed instead, which the client takes as a transient failure.
+	drop map[string]bool
+	// dropping is set by an RPC being dropped, for the transport to act on.
+	dropping bool
+	// leaderChanges counts the calls of each RPC still to be answered with
+	// E_LEADER_CHANGED, naming leader, the fake's own address.
+	leaderChanges map[string]int
+	leader        *nebula.HostAddr
+	// connections counts the connections accepted.
+	connections int
+	// calls counts the calls of each RPC.
+	calls map[string]int
+	// gates hold the calls of an RPC: a call sends on the gate of its RPC
+	// once made, then waits to receive from it before it is answered.
+	gates map[string]chan struct{}
+	// roles holds the roles of every user, a user missing from it not
+	// found; if nil, every user is a GOD.
+	roles map[string][]*nebula.RoleItem
+	// spaces are the spaces ListSpaces lists.
+	spaces []*nebula_metad.IdName
+	// listUsersCode is the code ListUsers answers with.
+	listUsersCode nebula_metad.ErrorCode
+	// dropUserCodes holds the code DropUser answers for an account, if not
+	// success.
+	dropUserCodes map[string]nebula_metad.ErrorCode
+}
+
+// leaderChanged reports whether the call of method is to be answered with
+// E_LEADER_CHANGED.
+func (f *fakeMetad) leaderChanged(method string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.leaderChanges[method] == 0 {
+		return false
+	}
+	f.leaderChanges[method]--
+	return true
+}
+
+func (f *fakeMetad) accepted() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connections
+}
+
+// begin starts a call of method: it is counted, held at its gate if it has
+// one, and its reply marked to be dropped if it is to be.
+func (f *fakeMetad) begin(method string) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[method]++
+	gate := f.gates[method]
+	f.mu.Unlock()
+
+	if gate != nil {
+		gate <- struct{}{}
+		<-gate
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropping = f.drop[method]
+}
+
+// called returns the number of calls of method made so far.
+func (f *fakeMetad) called(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// takeDrop reports whether the reply being sent is to be dropped.
+func (f *fakeMetad) takeDrop() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dropping := f.dropping
+	f.dropping = false
+	return dropping
+}
+
+func (f *fakeMetad) GetUserRoles(req *nebula_metad.GetUserRolesReq) (*nebula_metad.ListRolesResp, error) {
+	f.begin("GetUserRoles")
+	if f.roles != nil {
+		roles, ok := f.roles[req.Account]
+		if !ok {
+			return &nebula_metad.ListRolesResp{Code: nebula_metad.ErrorCode_E_NOT_FOUND, Leader: nebula.NewHostAddr()}, nil
+		}
+		return &nebula_metad.ListRolesResp{Leader: nebula.NewHostAddr(), Roles: roles}, nil
+	}
+	return &nebula_metad.ListRolesResp{
+		Leader: nebula.NewHostAddr(),
+		Roles:  []*nebula.RoleItem{{User: req.Account, SpaceID: 0, RoleType: nebula.RoleType_GOD}},
+	}, nil
+}
+
+func (f *fakeMetad) ListUsers(req *nebula_metad.ListUsersReq) (*nebula_metad.ListUsersResp, error) {
+	f.begin("ListUsers")
+	if f.leaderChanged("ListUsers") {
+		return &nebula_metad.ListUsersResp{Code: nebula_metad.ErrorCode_E_LEADER_CHANGED, Leader: f.leader}, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listUsersCode != nebula_metad.ErrorCode_SUCCEEDED {
+		return &nebula_metad.ListUsersResp{Code: f.listUsersCode, Leader: nebula.NewHostAddr()}, nil
+	}
+	return &nebula_metad.ListUsersResp{Leader: nebula.NewHostAddr(), Users: map[string]string{"root": ""}}, nil
+}
+
+func (f *fakeMetad) ListSpaces(req *nebula_metad.ListSpacesReq) (*nebula_metad.ListSpacesResp, error) {
+	f.begin("ListSpaces")
+	return &nebula_metad.ListSpacesResp{Leader: nebula.NewHostAddr(), Spaces: f.spaces}, nil
+}
+
+func (f *fakeMetad) GetSpace(req *nebula_metad.GetSpaceReq) (*nebula_metad.GetSpaceResp, error) {
+	f.begin("GetSpace")
+	for _, space := range f.spaces {
+		if space.Name == req.SpaceName {
+			properties := nebula_metad.NewSpaceProperties()
+			properties.SpaceName = space.Name
+			return &nebula_metad.GetSpaceResp{Leader: nebula.NewHostAddr(), Item: &nebula_metad.SpaceItem{
+				SpaceID: space.Id.GetSpaceID(), Properties: properties,
+			}}, nil
+		}
+	}
+	return &nebula_metad.GetSpaceResp{Code: nebula_metad.ErrorCode_E_NOT_FOUND, Leader: nebula.NewHostAddr()}, nil
+}
+
+func (f *fakeMetad) CreateSpace(req *nebula_metad.CreateSpaceReq) (*nebula_metad.ExecResp, error) {
+	f.begin("CreateSpace")
+	return execResp(nebula_metad.ErrorCode_SUCCEEDED), nil
+}
+
+func (f *fakeMetad) CreateUser(req *nebula_metad.CreateUserReq) (*nebula_metad.ExecResp, error) {
+	f.begin("CreateUser")
+	return execResp(nebula_metad.ErrorCode_SUCCEEDED), nil
+}
+
+// execResp is an ExecResp with code, its ID, a union, set as it must be.
+func execResp(code nebula_metad.ErrorCode) *nebula_metad.ExecResp {
+	id := nebula_metad.NewID()
+	spaceID := nebula.GraphSpaceID(0)
+	id.SpaceID = &spaceID
+	return &nebula_metad.ExecResp{Code: code, Leader: nebula.NewHostAddr(), Id: id}
+}
+
+func (f *fakeMetad) DropUser(req *nebula_metad.DropUserReq) (*nebula_metad.ExecResp, error) {
+	f.begin("DropUser")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return execResp(f.dropUserCodes[req.Account]), nil
+}
+
+func (f *fakeMetad) GrantRole(req *nebula_metad.GrantRoleReq) (*nebula_metad.ExecResp, error) {
+	f.begin("GrantRole")
+	return execResp(nebula_metad.ErrorCode_SUCCEEDED), nil
+}
+
+func (f *fakeMetad) RevokeRole(req *nebula_metad.RevokeRoleReq) (*nebula_metad.ExecResp, error) {
+	f.begin("RevokeRole")
+	return execResp(nebula_metad.ErrorCode_SUCCEEDED), nil
+}
+
+// Balance refuses to start a plan, as if one were running, and reports any
+// plan asked after as running.
+func (f *fakeMetad) Balance(req *nebula_metad.BalanceReq) (*nebula_metad.BalanceResp, error) {
+	f.begin("Balance")
+	if req.Id == nil {
+		return &nebula_metad.BalanceResp{Code: nebula_metad.ErrorCode_E_BALANCER_RUNNING, Leader: nebula.NewHostAddr()}, nil
+	}
+	return &nebula_metad.BalanceResp{
+		Id:     *req.Id,
+		Leader: nebula.NewHostAddr(),
+		Tasks:  []*nebula_metad.BalanceTask{{Id: "[1, 1, a->b]", Result_: nebula_metad.TaskResult__IN_PROGRESS}},
+	}, nil
+}
+
+// droppingTransport holds replies back until they are flushed, closing the
+// connection instead of sending a reply the fake was told to drop.
+type droppingTransport struct {
+	thrift.Transport
+	fake  *fakeMetad
+	reply bytes.Buffer
+}
+
+func (t *droppingTransport) Write(p []byte) (int, error) {
+	return t.reply.Write(p)
+}
+
+func (t *droppingTransport) Flush() error {
+	defer t.reply.Reset()
+	if t.fake.takeDrop() {
+		t.Transport.Close()
+		return errors.New("reply dropped")
+	}
+	if _, err := t.Transport.Write(t.reply.Bytes()); err != nil {
+		return err
+	}
+	return t.Transport.Flush()
+}
+
+type droppingServerTransport struct {
+	thrift.ServerTransport
+	fake *fakeMetad
+}
+
+func (t *droppingServerTransport) Accept() (thrift.Transport, error) {
+	conn, err := t.ServerTransport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	t.fake.mu.Lock()
+	t.fake.connections++
+	t.fake.mu.Unlock()
+	return &droppingTransport{Transport: conn, fake: t.fake}, nil
+}
+
+// startFakeMetad serves f on a local port and points every instance at it
+// until the test ends. Retries don't wait and nothing is cached, so tests
+// see each RPC.
+func startFakeMetad(t *testing.T, f *fakeMetad) {
+	socket, err := thrift.NewServerSocket("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := socket.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	server := thrift.NewSimpleServer4(nebula_metad.NewMetaServiceProcessor(f),
+		&droppingServerTransport{ServerTransport: socket, fake: f},
+		thrift.NewTransportFactory(), thrift.NewBinaryProtocolFactoryDefault())
+	addr := socket.Addr().(*net.TCPAddr)
+	ip := addr.IP.To4()
+	f.leader = &nebula.HostAddr{
+		Ip:   nebula.IPv4(int32(ip[0])<<24 | int32(ip[1])<<16 | int32(ip[2])<<8 | int32(ip[3])),
+		Port: nebula.Port(addr.Port),
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Stop() })
+
+	utils.SetMetadAddress(socket.Addr().String())
+	utils.SetRetryPolicy(utils.RetryPolicy{Attempts: 3})
+	utils.SetCacheTTL(utils.CacheTTL{})
+}
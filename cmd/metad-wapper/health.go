@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+)
+
+// checkTimeout bounds every dependency check, so a hanging dependency makes
+// a probe fail instead of time out.
+const checkTimeout = 5 * time.Second
+
+// sweepConcurrency is the number of instances /status?instances=true
+// connects to at once.
+const sweepConcurrency = 8
+
+// dependency is something the wrapper needs to serve requests.
+type dependency struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+var (
+	kubernetesDependency = dependency{"kubernetes", func(ctx context.Context) error {
+		return client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+	}}
+
+	metricsDependency = dependency{"metrics", func(ctx context.Context) error {
+		return metricsClient.Discovery().RESTClient().Get().AbsPath("/apis/metrics.k8s.io/v1beta1").Do(ctx).Error()
+	}}
+
+	prometheusDependency = dependency{"prometheus", func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", prometheusURL+"/-/healthy", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := prometheusClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("prometheus answered %s", resp.Status)
+		}
+		return nil
+	}}
+)
+
+// readinessDependencies are those without which no endpoint works.
+var readinessDependencies = []dependency{kubernetesDependency, metricsDependency}
+
+// statusDependencies are reported by /status; Prometheus is only needed for
+// disk usage, so it degrades the wrapper without making it unready.
+var statusDependencies = []dependency{kubernetesDependency, metricsDependency, prometheusDependency}
+
+// checkAll runs the checks, concurrency at a time or all at once if it is
+// 0, and returns their outcome in order.
+func checkAll(ctx context.Context, deps []dependency, concurrency int) []api.DependencyStatus {
+	if concurrency <= 0 {
+		concurrency = len(deps)
+	}
+	slots := make(chan struct{}, concurrency)
+
+	res := make([]api.DependencyStatus, len(deps))
+	wg := sync.WaitGroup{}
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep dependency) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := dep.check(ctx)
+			res[i] = api.DependencyStatus{
+				Name:    dep.name,
+				Status:  api.StatusOK,
+				Latency: time.Since(start).Seconds(),
+			}
+			if err != nil {
+				res[i].Status = api.StatusUnavailable
+				res[i].Error = err.Error()
+			}
+		}(i, dep)
+	}
+	wg.Wait()
+	return res
+}
+
+func allOK(statuses []api.DependencyStatus) bool {
+	for _, status := range statuses {
+		if status.Status != api.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// instanceDependencies lists the metad of every instance as a dependency.
+func instanceDependencies(ctx context.Context) ([]dependency, error) {
+	names, err := instances.list(ctx, instances.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := []dependency{}
+	for _, name := range names {
+		instance := name
+		deps = append(deps, dependency{instance, func(ctx context.Context) error {
+			return utils.Ping(ctx, instance)
+		}})
+	}
+	return deps, nil
+}
+
+// HealthzHandler answers as long as the process is serving.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+// ReadyzHandler answers 200 once the Kubernetes and metrics APIs can be
+// reached, and 503 naming the failing ones otherwise.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := checkAll(r.Context(), readinessDependencies, 0)
+	if allOK(statuses) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok\n"))
+		return
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	for _, status := range statuses {
+		if status.Status != api.StatusOK {
+			fmt.Fprintf(w, "%s: %s\n", status.Name, status.Error)
+		}
+	}
+}
+
+// StatusHandler reports every dependency and, with ?instances=true, whether
+// the metad of each instance answers; guardedSweep keeps the latter to
+// admins.
+func StatusHandler(w http.ResponseWriter, r *http.Request) {
+	statusResponse := api.StatusResponse{}
+
+	sweep := false
+	if v := r.URL.Query().Get("instances"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeRequestError(w, r, ValidationErrors{{Field: "instances", Rule: "bool", Message: "must be true or false"}})
+			return
+		}
+		sweep = b
+	}
+
+	statusResponse.Dependencies = checkAll(r.Context(), statusDependencies, 0)
+	ok := allOK(statusResponse.Dependencies)
+
+	if sweep {
+		deps, err := instanceDependencies(r.Context())
+		if err != nil {
+			ok = false
+		} else {
+			statusResponse.Instances = checkAll(r.Context(), deps, sweepConcurrency)
+			ok = ok && allOK(statusResponse.Instances)
+		}
+	}
+
+	statusResponse.Status = api.StatusOK
+	if !ok {
+		statusResponse.Status = api.StatusDegraded
+	}
+
+	body, _ := json.Marshal(statusResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// guardedSweep has the requests of handler sweeping the instances, which
+// connect to the metad of every one, made by admins only and within the
+// limits of their caller. The others, as probes make, go straight through.
+func guardedSweep(handler http.HandlerFunc) http.HandlerFunc {
+	guarded := adminOnly(limited(handler))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sweep, err := strconv.ParseBool(r.URL.Query().Get("instances")); err == nil && sweep {
+			guarded(w, r)
+			return
+		}
+		handler(w, r)
+	}
+}
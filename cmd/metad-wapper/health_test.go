@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestCheckAllConcurrency(t *testing.T) {
+	mu := sync.Mutex{}
+	running, most := 0, 0
+	deps := []dependency{}
+	for i := 0; i < 10; i++ {
+		deps = append(deps, dependency{fmt.Sprint(i), func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			if running > most {
+				most = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}})
+	}
+
+	statuses := checkAll(context.Background(), deps, 3)
+	if most > 3 {
+		t.Errorf("%d checks ran at once, want at most 3", most)
+	}
+	for i, status := range statuses {
+		if status.Name != fmt.Sprint(i) || status.Status != api.StatusOK {
+			t.Errorf("status %d = %+v", i, status)
+		}
+	}
+}
+
+func TestGuardedSweep(t *testing.T) {
+	withTokens(t, map[string]tokenCaller{
+		"admin": {Name: "console", Admin: true},
+		"user":  {Name: "billing"},
+	})
+	previous := limits
+	limits = newLimiter(api.Limits{})
+	defer func() { limits = previous }()
+	handler := guardedSweep(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		target        string
+		authorization string
+		status        int
+	}{
+		{"dependencies", "/status", "", http.StatusOK},
+		{"not swept", "/status?instances=false", "", http.StatusOK},
+		{"swept by an admin", "/status?instances=true", "Bearer admin", http.StatusOK},
+		{"swept by a user", "/status?instances=true", "Bearer user", http.StatusForbidden},
+		{"swept anonymously", "/status?instances=1", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.target, nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != test.status {
+				t.Errorf("status = %d, want %d", w.Code, test.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	nebula "github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sightings remembers when this replica last saw metad list each host
+// online. metad tells neither when a host went offline nor the time of its
+// heartbeats, so this is the closest the wrapper knows; it is lost on restart
+// and each replica keeps its own.
+var sightings = &sightingLog{seen: map[string]time.Time{}}
+
+type sightingLog struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// observe records the status of host of instance and returns when it was
+// last seen online, zero if never.
+func (l *sightingLog) observe(instance, host string, online bool, now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := instance + "/" + host
+	if online {
+		l.seen[key] = now
+	}
+	return l.seen[key]
+}
+
+// hostAddress formats a metad host address as ip:port.
+func hostAddress(addr *nebula.HostAddr) string {
+	ip := uint32(addr.GetIp())
+	ipString := net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+	return net.JoinHostPort(ipString, strconv.Itoa(int(addr.GetPort())))
+}
+
+func StorageHostsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	storageHostsRequest := api.StorageHostsRequest{}
+	storageHostsResponse := api.StorageHostsResponse{}
+
+	fail := func(code int) {
+		storageHostsResponse.Code = code
+		body, _ := json.Marshal(storageHostsResponse)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+	}
+
+	bodyData, err := requestBody(r)
+	if err != nil {
+		logger.Error(err, "Read request body failed")
+		fail(api.ErrInvalidRequestBody)
+		return
+	}
+	if err := decodeRequest(bodyData, &storageHostsRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	instance := storageHostsRequest.InstanceID
+
+	metadClient, err := makeMetadClient(r.Context(), instance)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	defer metadClient.Close()
+
+	listHostsResp, err := metadClient.ListHosts(nebula_metad.NewListHostsReq())
+	if err != nil {
+		logger.Error(err, "List hosts failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	if listHostsResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List hosts failed", "metadCode", listHostsResp.Code.String())
+		fail(api.ErrInternalError)
+		return
+	}
+
+	// distribution[host][space] counts the partitions of space on host.
+	distribution := map[string]map[string]*api.SpacePartitions{}
+	spacePartitions := func(host, space string) *api.SpacePartitions {
+		if distribution[host] == nil {
+			distribution[host] = map[string]*api.SpacePartitions{}
+		}
+		if distribution[host][space] == nil {
+			distribution[host][space] = &api.SpacePartitions{Space: space}
+		}
+		return distribution[host][space]
+	}
+
+	allOnline := true
+	for _, item := range listHostsResp.Hosts {
+		address := hostAddress(item.GetHostAddr())
+		for space, parts := range item.AllParts {
+			spacePartitions(address, space).Partitions += len(parts)
+		}
+		for space, parts := range item.LeaderParts {
+			spacePartitions(address, space).Leaders += len(parts)
+		}
+		allOnline = allOnline && item.Status == nebula_metad.HostStatus_ONLINE
+	}
+
+	// Only the replicas on hosts not online are lost, which takes the
+	// partitions of every space to tell.
+	if !allOnline {
+		if !countLost(r, metadClient, spacePartitions, fail) {
+			return
+		}
+	}
+
+	pods, err := client.CoreV1().Pods(instance).List(r.Context(), metav1.ListOptions{})
+	if err != nil {
+		logger.Error(err, "List pods failed")
+		fail(api.ErrInternalError)
+		return
+	}
+	podsByIP := map[string]int{}
+	for i, pod := range pods.Items {
+		if pod.Status.PodIP != "" {
+			podsByIP[pod.Status.PodIP] = i
+		}
+	}
+
+	now := time.Now()
+	storageHostsResponse.Hosts = []api.StorageHost{}
+	for _, item := range listHostsResp.Hosts {
+		address := hostAddress(item.GetHostAddr())
+		host := api.StorageHost{
+			Host:   address,
+			Status: item.Status.String(),
+			Spaces: []api.SpacePartitions{},
+		}
+		if seen := sightings.observe(instance, address, item.Status == nebula_metad.HostStatus_ONLINE, now); !seen.IsZero() {
+			host.LastSeenOnline = seen.Format(time.RFC3339)
+		}
+
+		if ip, _, err := net.SplitHostPort(address); err == nil {
+			if i, ok := podsByIP[ip]; ok {
+				host.Pod = pods.Items[i].Name
+				host.Node = pods.Items[i].Spec.NodeName
+			}
+		}
+
+		for _, spaceParts := range distribution[address] {
+			host.Spaces = append(host.Spaces, *spaceParts)
+			host.Partitions += spaceParts.Partitions
+			host.Leaders += spaceParts.Leaders
+		}
+		sort.Slice(host.Spaces, func(i, j int) bool { return host.Spaces[i].Space < host.Spaces[j].Space })
+
+		storageHostsResponse.Hosts = append(storageHostsResponse.Hosts, host)
+	}
+	sort.Slice(storageHostsResponse.Hosts, func(i, j int) bool {
+		return storageHostsResponse.Hosts[i].Host < storageHostsResponse.Hosts[j].Host
+	})
+
+	respBody, _ := json.Marshal(storageHostsResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
+}
+
+// countLost counts the replicas metad counts lost in the partitions of every
+// space, writing the error response with fail if it can't.
+func countLost(r *http.Request, metadClient *utils.Client, spacePartitions func(host, space string) *api.SpacePartitions, fail func(code int)) bool {
+	logger := logging.FromContext(r.Context())
+
+	listSpacesResp, err := metadClient.ListSpaces(nebula_metad.NewListSpacesReq())
+	if err != nil {
+		logger.Error(err, "List spaces failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return false
+	}
+	if listSpacesResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List spaces failed", "metadCode", listSpacesResp.Code.String())
+		fail(api.ErrInternalError)
+		return false
+	}
+
+	for _, space := range listSpacesResp.Spaces {
+		listPartsReq := nebula_metad.NewListPartsReq()
+		listPartsReq.SpaceID = space.Id.GetSpaceID()
+		listPartsResp, err := metadClient.ListParts(listPartsReq)
+		if err != nil {
+			logger.Error(err, "List parts failed", "space", space.Name)
+			fail(metadErrorCode(err, api.ErrInternalError))
+			return false
+		}
+		if listPartsResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+			logger.Info("List parts failed", "space", space.Name, "metadCode", listPartsResp.Code.String())
+			fail(api.ErrInternalError)
+			return false
+		}
+
+		for _, part := range listPartsResp.Parts {
+			for _, lost := range part.Losts {
+				spacePartitions(hostAddress(lost), space.Name).Lost++
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// An instance is a namespace matching the namespace selector that runs pods
+// matching the workload selector, both label selectors set from the
+// command line. The pods and PVCs of an instance tell their component by the
+// component label, as the operator sets it.
+
+// instanceDiscovery finds the instances and keeps the list for ttl, so
+// requests naming an instance don't each list the cluster. A name missing
+// from the list has it read again, at most every relistInterval, so a new
+// instance is known right away.
+type instanceDiscovery struct {
+	namespaceSelector string
+	workloadSelector  string
+	componentLabel    string
+	ttl               time.Duration
+
+	mu     sync.Mutex
+	names  []string
+	listed time.Time
+}
+
+const relistInterval = 5 * time.Second
+
+var instances = &instanceDiscovery{
+	workloadSelector: "app.kubernetes.io/managed-by=nebula-operator",
+	componentLabel:   "app.kubernetes.io/component",
+	ttl:              30 * time.Second,
+}
+
+// validateSelectors checks the selectors parse, so a typo fails at start
+// rather than on every request.
+func (d *instanceDiscovery) validateSelectors() error {
+	for _, selector := range []string{d.namespaceSelector, d.workloadSelector} {
+		if _, err := labels.Parse(selector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the names of the instances, sorted, reading them again if the
+// list is older than maxAge. If they can't be listed the last list read is
+// returned, and the error only if there is none.
+func (d *instanceDiscovery) list(ctx context.Context, maxAge time.Duration) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.names != nil && time.Since(d.listed) < maxAge {
+		return d.names, nil
+	}
+
+	names, err := d.discover(ctx)
+	if err != nil {
+		if d.names == nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Error(err, "List instances failed, using the last list", "listed", d.listed.Format(time.RFC3339))
+		return d.names, nil
+	}
+	d.names = names
+	d.listed = time.Now()
+	return names, nil
+}
+
+func (d *instanceDiscovery) discover(ctx context.Context) ([]string, error) {
+	nss, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: d.namespaceSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	var running map[string]bool
+	if d.workloadSelector != "" {
+		pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: d.workloadSelector})
+		if err != nil {
+			return nil, err
+		}
+		running = map[string]bool{}
+		for _, pod := range pods.Items {
+			running[pod.Namespace] = true
+		}
+	}
+
+	names := []string{}
+	for _, ns := range nss.Items {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		if running != nil && !running[ns.Name] {
+			continue
+		}
+		names = append(names, ns.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// known reports whether name is an instance.
+func (d *instanceDiscovery) known(ctx context.Context, name string) (bool, error) {
+	found := func(names []string) bool {
+		i := sort.SearchStrings(names, name)
+		return i < len(names) && names[i] == name
+	}
+	names, err := d.list(ctx, d.ttl)
+	if err != nil || found(names) {
+		return err == nil, err
+	}
+	names, err = d.list(ctx, relistInterval)
+	return err == nil && found(names), err
+}
+
+// component returns the Nebula component an object with the labels belongs
+// to, or "" if it belongs to none, such as the wrapper's own pods.
+func (d *instanceDiscovery) component(objectLabels map[string]string) string {
+	value := objectLabels[d.componentLabel]
+	for _, component := range nebulaComponents {
+		if value == component {
+			return component
+		}
+	}
+	return ""
+}
+
+// objectComponent returns the Nebula component of a pod or PVC, by its
+// labels.
+func objectComponent(object metav1.Object) string {
+	return instances.component(object.GetLabels())
+}
+
+// discovered turns away with 404 and ErrNoInstance the requests naming an
+// InstanceID that isn't an instance. Requests naming none, or a malformed one, are left
+// to their handler to validate. If the instances can't be listed at all the
+// request is let through: metad will tell whether the instance is there.
+func discovered(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := struct{ InstanceID string }{}
+		if bodyData, err := requestBody(r); err == nil {
+			json.Unmarshal(bodyData, &req)
+		}
+		if req.InstanceID == "" || len(validation.IsDNS1123Label(req.InstanceID)) != 0 {
+			handler(w, r)
+			return
+		}
+
+		logger := logging.FromContext(r.Context())
+		known, err := instances.known(r.Context(), req.InstanceID)
+		if err != nil {
+			logger.Error(err, "List instances failed")
+		} else if !known {
+			logger.Info("Instance not found", "instanceID", req.InstanceID)
+			errorResponse := api.ErrorResponse{
+				Code:   api.ErrNoInstance,
+				Errors: []api.FieldError{{Field: "InstanceID", Rule: "instance", Message: "is not a known instance"}},
+			}
+			body, _ := json.Marshal(errorResponse)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(body)
+			return
+		}
+		handler(w, r)
+	}
+}
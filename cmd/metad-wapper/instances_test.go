@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeKube lists the namespaces and pods it holds, filtered by label
+// selector, as the Kubernetes API would. Anything else it is asked panics.
+type fakeKube struct {
+	kubernetes.Interface
+	typedcorev1.CoreV1Interface
+
+	mu         sync.Mutex
+	namespaces []corev1.Namespace
+	pods       []corev1.Pod
+	// err fails every list.
+	err error
+	// lists counts the namespace lists.
+	lists int
+}
+
+func (k *fakeKube) CoreV1() typedcorev1.CoreV1Interface { return k }
+
+func (k *fakeKube) Namespaces() typedcorev1.NamespaceInterface { return fakeNamespaces{kube: k} }
+
+func (k *fakeKube) Pods(namespace string) typedcorev1.PodInterface {
+	return fakePods{kube: k, namespace: namespace}
+}
+
+func (k *fakeKube) set(namespaces []corev1.Namespace, pods []corev1.Pod, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.namespaces, k.pods, k.err = namespaces, pods, err
+}
+
+func (k *fakeKube) listed() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.lists
+}
+
+type fakeNamespaces struct {
+	typedcorev1.NamespaceInterface
+	kube *fakeKube
+}
+
+func (n fakeNamespaces) List(ctx context.Context, opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+	n.kube.mu.Lock()
+	defer n.kube.mu.Unlock()
+	n.kube.lists++
+	if n.kube.err != nil {
+		return nil, n.kube.err
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.NamespaceList{}
+	for _, ns := range n.kube.namespaces {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			list.Items = append(list.Items, ns)
+		}
+	}
+	return list, nil
+}
+
+type fakePods struct {
+	typedcorev1.PodInterface
+	kube      *fakeKube
+	namespace string
+}
+
+func (p fakePods) List(ctx context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+	p.kube.mu.Lock()
+	defer p.kube.mu.Unlock()
+	if p.kube.err != nil {
+		return nil, p.kube.err
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	list := &corev1.PodList{}
+	for _, pod := range p.kube.pods {
+		if (p.namespace == metav1.NamespaceAll || pod.Namespace == p.namespace) && selector.Matches(labels.Set(pod.Labels)) {
+			list.Items = append(list.Items, pod)
+		}
+	}
+	return list, nil
+}
+
+// withKube has the Kubernetes API be kube until the test is over.
+func withKube(t *testing.T, kube kubernetes.Interface) {
+	previous := client
+	client = kube
+	t.Cleanup(func() { client = previous })
+}
+
+func namespace(name string, nsLabels map[string]string) corev1.Namespace {
+	return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+}
+
+func operatorPod(namespace string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nebula-metad-0", Namespace: namespace,
+		Labels: map[string]string{"app.kubernetes.io/managed-by": "nebula-operator"}}}
+}
+
+func TestInstanceDiscovery(t *testing.T) {
+	kube := &fakeKube{}
+	withKube(t, kube)
+	deleting := namespace("deleting", nil)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	kube.set([]corev1.Namespace{
+		namespace("nebula", map[string]string{"tenant": "a"}),
+		namespace("other", map[string]string{"tenant": "b"}),
+		// Running no pod of the operator.
+		namespace("kube-system", map[string]string{"tenant": "a"}),
+		deleting,
+	}, []corev1.Pod{operatorPod("nebula"), operatorPod("other"), operatorPod("deleting"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}}}, nil)
+
+	tests := []struct {
+		name              string
+		namespaceSelector string
+		workloadSelector  string
+		want              []string
+	}{
+		{"workloads", "", instances.workloadSelector, []string{"nebula", "other"}},
+		{"namespace selector", "tenant=a", instances.workloadSelector, []string{"nebula"}},
+		{"any namespace", "tenant", "", []string{"kube-system", "nebula", "other"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &instanceDiscovery{namespaceSelector: test.namespaceSelector, workloadSelector: test.workloadSelector, ttl: time.Minute}
+			got, err := d.list(context.Background(), d.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("list = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInstanceKnown(t *testing.T) {
+	kube := &fakeKube{}
+	withKube(t, kube)
+	kube.set([]corev1.Namespace{namespace("nebula", nil)}, nil, nil)
+	ctx := context.Background()
+	d := &instanceDiscovery{ttl: time.Minute}
+
+	if known, err := d.known(ctx, "nebula"); err != nil || !known {
+		t.Fatalf("known(nebula) = %v, %v, want true", known, err)
+	}
+
+	// Made since the list was read, and within relistInterval of it: not
+	// known yet, rather than listing the cluster on every miss.
+	kube.set([]corev1.Namespace{namespace("nebula", nil), namespace("new", nil)}, nil, nil)
+	if known, err := d.known(ctx, "new"); err != nil || known {
+		t.Errorf("known(new) within relistInterval = %v, %v, want false", known, err)
+	}
+	if got := kube.listed(); got != 1 {
+		t.Errorf("instances listed %d times, want 1", got)
+	}
+
+	// Past relistInterval a miss reads the list again.
+	d.listed = d.listed.Add(-relistInterval)
+	if known, err := d.known(ctx, "new"); err != nil || !known {
+		t.Errorf("known(new) = %v, %v, want true", known, err)
+	}
+	if known, err := d.known(ctx, "nebula"); err != nil || !known {
+		t.Errorf("known(nebula) = %v, %v, want true", known, err)
+	}
+	if got := kube.listed(); got != 2 {
+		t.Errorf("instances listed %d times, want 2", got)
+	}
+
+	// The cluster unreachable, the last list is used.
+	kube.set(nil, nil, errors.New("connection refused"))
+	d.listed = d.listed.Add(-time.Hour)
+	if known, err := d.known(ctx, "new"); err != nil || !known {
+		t.Errorf("known(new) from the last list = %v, %v, want true", known, err)
+	}
+	if known, err := d.known(ctx, "gone"); err != nil || known {
+		t.Errorf("known(gone) from the last list = %v, %v, want false", known, err)
+	}
+}
+
+func TestDiscovered(t *testing.T) {
+	kube := &fakeKube{}
+	withKube(t, kube)
+	previous := instances
+	instances = &instanceDiscovery{ttl: time.Minute}
+	defer func() { instances = previous }()
+
+	called := false
+	handler := discovered(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(body string) *httptest.ResponseRecorder {
+		called = false
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	// Never listed: the request goes through, for metad to tell.
+	kube.set(nil, nil, errors.New("connection refused"))
+	if w := serve(`{"InstanceID": "nebula"}`); w.Code != http.StatusOK || !called {
+		t.Errorf("status with the instances unlisted = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	kube.set([]corev1.Namespace{namespace("nebula", nil)}, nil, nil)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"known", `{"InstanceID": "nebula"}`, http.StatusOK},
+		{"unknown", `{"InstanceID": "other"}`, http.StatusNotFound},
+		// Left to the handler to validate.
+		{"none", `{}`, http.StatusOK},
+		{"malformed", `{"InstanceID": "Not_A_Namespace"}`, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(test.body)
+			if w.Code != test.status {
+				t.Fatalf("status = %d, want %d", w.Code, test.status)
+			}
+			if called != (test.status == http.StatusOK) {
+				t.Errorf("handler called = %v", called)
+			}
+			if test.status == http.StatusNotFound {
+				if code := responseCode(w.Body.Bytes()); code != api.ErrNoInstance {
+					t.Errorf("code = %d, want %d", code, api.ErrNoInstance)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// jobTime formats the time metad records for a job or task, in seconds
+// since the epoch, leaving it empty if the job hasn't got there yet.
+func jobTime(seconds int64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
+
+// apiJob converts a metad job. metad keeps the space of compact and flush
+// jobs as their only parameter.
+func apiJob(desc *nebula_metad.JobDesc) api.Job {
+	job := api.Job{
+		ID:        desc.Id,
+		Command:   desc.Cmd,
+		Status:    desc.Status.String(),
+		StartTime: jobTime(desc.StartTime),
+		StopTime:  jobTime(desc.StopTime),
+	}
+	if len(desc.Paras) > 0 {
+		job.Space = desc.Paras[0]
+	}
+	return job
+}
+
+// runAdminJob runs op on metad, returning the code to fail with if it failed.
+func runAdminJob(metadClient *utils.Client, logger logr.Logger, op nebula_metad.AdminJobOp, paras ...string) (*nebula_metad.AdminJobResult_, int) {
+	adminJobReq := nebula_metad.NewAdminJobReq()
+	adminJobReq.Op = op
+	adminJobReq.Paras = paras
+	adminJobResp, err := metadClient.RunAdminJob(adminJobReq)
+	if err != nil {
+		logger.Error(err, "Run admin job failed", "op", op.String())
+		return nil, metadErrorCode(err, api.ErrInternalError)
+	}
+	switch adminJobResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+	case nebula_metad.ErrorCode_E_NOT_FOUND:
+		logger.Info("Admin job not found", "op", op.String(), "paras", paras)
+		return nil, api.ErrJobNotFound
+	default:
+		logger.Info("Run admin job failed", "op", op.String(), "paras", paras, "metadCode", adminJobResp.Code.String())
+		return nil, api.ErrInternalError
+	}
+	if adminJobResp.Result_ == nil {
+		return nebula_metad.NewAdminJobResult_(), 0
+	}
+	return adminJobResp.Result_, 0
+}
+
+func SubmitJobHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	submitJobRequest := api.SubmitJobRequest{}
+	submitJobResponse := api.SubmitJobResponse{}
+	if !readRequest(w, r, &submitJobRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), submitJobRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		submitJobResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, submitJobResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	// metad takes jobs on spaces it doesn't know and fails them later, so
+	// the space is checked first.
+	getSpaceReq := nebula_metad.NewGetSpaceReq()
+	getSpaceReq.SpaceName = submitJobRequest.SpaceName
+	getSpaceResp, err := metadClient.GetSpace(getSpaceReq)
+	if err != nil {
+		logger.Error(err, "Get space failed")
+		submitJobResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, submitJobResponse, true)
+		return
+	}
+	if getSpaceResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		submitJobResponse.Code = api.ErrInternalError
+		if getSpaceResp.Code == nebula_metad.ErrorCode_E_NOT_FOUND {
+			submitJobResponse.Code = api.ErrSpaceNotFound
+		}
+		logger.Info("Get space failed", "space", submitJobRequest.SpaceName, "metadCode", getSpaceResp.Code.String())
+		writeResponse(w, submitJobResponse, true)
+		return
+	}
+
+	result, code := runAdminJob(metadClient, logger, nebula_metad.AdminJobOp_ADD, submitJobRequest.Command, submitJobRequest.SpaceName)
+	if code != 0 {
+		submitJobResponse.Code = code
+		writeResponse(w, submitJobResponse, true)
+		return
+	}
+	submitJobResponse.JobID = result.GetJobID()
+	logger.Info("Admin job submitted", "jobID", submitJobResponse.JobID, "command", submitJobRequest.Command, "space", submitJobRequest.SpaceName)
+	writeResponse(w, submitJobResponse, false)
+}
+
+func ListJobsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	listJobsRequest := api.ListJobsRequest{}
+	listJobsResponse := api.ListJobsResponse{}
+	if !readRequest(w, r, &listJobsRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), listJobsRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		listJobsResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listJobsResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	result, code := runAdminJob(metadClient, logger, nebula_metad.AdminJobOp_SHOW_All)
+	if code != 0 {
+		listJobsResponse.Code = code
+		writeResponse(w, listJobsResponse, true)
+		return
+	}
+
+	listJobsResponse.Jobs = []api.Job{}
+	for _, desc := range result.JobDesc {
+		job := apiJob(desc)
+		if listJobsRequest.SpaceName != "" && job.Space != listJobsRequest.SpaceName {
+			continue
+		}
+		listJobsResponse.Jobs = append(listJobsResponse.Jobs, job)
+	}
+	// Newest first, as the jobs worth looking at are the recent ones.
+	sort.Slice(listJobsResponse.Jobs, func(i, j int) bool {
+		return listJobsResponse.Jobs[i].ID > listJobsResponse.Jobs[j].ID
+	})
+	writeResponse(w, listJobsResponse, false)
+}
+
+func ShowJobHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	jobRequest := api.JobRequest{}
+	jobResponse := api.JobResponse{}
+	if !readRequest(w, r, &jobRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), jobRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		jobResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, jobResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	result, code := runAdminJob(metadClient, logger, nebula_metad.AdminJobOp_SHOW, strconv.Itoa(int(jobRequest.JobID)))
+	if code == 0 && len(result.JobDesc) == 0 {
+		code = api.ErrJobNotFound
+	}
+	if code != 0 {
+		jobResponse.Code = code
+		writeResponse(w, jobResponse, true)
+		return
+	}
+
+	jobResponse.Job = apiJob(result.JobDesc[0])
+	jobResponse.Tasks = []api.JobTask{}
+	for _, desc := range result.TaskDesc {
+		task := api.JobTask{
+			ID:        desc.TaskID,
+			Status:    desc.Status.String(),
+			StartTime: jobTime(desc.StartTime),
+			StopTime:  jobTime(desc.StopTime),
+		}
+		if desc.Host != nil {
+			task.Host = hostAddress(desc.Host)
+		}
+		jobResponse.Tasks = append(jobResponse.Tasks, task)
+	}
+	sort.Slice(jobResponse.Tasks, func(i, j int) bool { return jobResponse.Tasks[i].ID < jobResponse.Tasks[j].ID })
+	writeResponse(w, jobResponse, false)
+}
+
+func StopJobHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	jobRequest := api.JobRequest{}
+	stopJobResponse := api.StopJobResponse{}
+	if !readRequest(w, r, &jobRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), jobRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		stopJobResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, stopJobResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	if _, code := runAdminJob(metadClient, logger, nebula_metad.AdminJobOp_STOP, strconv.Itoa(int(jobRequest.JobID))); code != 0 {
+		stopJobResponse.Code = code
+		writeResponse(w, stopJobResponse, true)
+		return
+	}
+	logger.Info("Admin job stopped", "jobID", jobRequest.JobID)
+	writeResponse(w, stopJobResponse, false)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiterIdle is how long the bucket of a caller or instance that sends
+	// nothing is kept; a new one starts full anyway.
+	limiterIdle = 10 * time.Minute
+	// busyRetryAfter is suggested to callers turned away because an instance
+	// is at its concurrency cap, as there is no telling when a slot frees.
+	busyRetryAfter = time.Second
+	// maxBuckets bounds the buckets kept of callers or instances; past it
+	// the idle ones are forgotten at once rather than every limiterIdle.
+	maxBuckets = 10000
+	// limitsKey is the shared state key of the limits set through
+	// PathSetLimits, which every replica applies.
+	limitsKey = "limits"
+	// limitsSyncInterval is how often the limits set through another replica
+	// are read and applied.
+	limitsSyncInterval = 10 * time.Second
+)
+
+// limits is the limiter shared by every limited handler. It is set up in
+// main from the flags, and changed by the limits set through PathSetLimits.
+var limits *limiter
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// limiter enforces api.Limits: a token bucket per caller and per instance,
+// and a count of the requests in flight per instance.
+type limiter struct {
+	mu        sync.Mutex
+	limits    api.Limits
+	callers   map[string]*bucket
+	instances map[string]*bucket
+	inFlight  map[string]int
+	lastPrune time.Time
+}
+
+func newLimiter(l api.Limits) *limiter {
+	return &limiter{
+		limits:    l,
+		callers:   map[string]*bucket{},
+		instances: map[string]*bucket{},
+		inFlight:  map[string]int{},
+		lastPrune: time.Now(),
+	}
+}
+
+func (l *limiter) get() api.Limits {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limits
+}
+
+// set changes the limits. The buckets are started afresh, full, with the
+// new rates and bursts; requests in flight still count against the new
+// concurrency cap.
+func (l *limiter) set(limits api.Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limits == l.limits {
+		return
+	}
+	l.limits = limits
+	l.callers = map[string]*bucket{}
+	l.instances = map[string]*bucket{}
+}
+
+func rateLimit(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(perSecond)
+}
+
+func (l *limiter) bucket(buckets map[string]*bucket, key string, perSecond float64, burst int, now time.Time) *bucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rateLimit(perSecond), burst)}
+		buckets[key] = b
+	}
+	b.lastUsed = now
+	return b
+}
+
+// prune forgets the buckets of callers and instances gone quiet, so the
+// maps don't grow with every address that ever sent a request.
+func (l *limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < limiterIdle && len(l.callers) < maxBuckets && len(l.instances) < maxBuckets {
+		return
+	}
+	l.lastPrune = now
+	for _, buckets := range []map[string]*bucket{l.callers, l.instances} {
+		for key, b := range buckets {
+			if now.Sub(b.lastUsed) > limiterIdle {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+// acquire admits a request from caller to instance ("" if it names none).
+// It returns a release function to call once the request is done, or how
+// long the caller should wait before retrying and why.
+func (l *limiter) acquire(caller, instance string) (func(), time.Duration, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	callerReservation := l.bucket(l.callers, caller, l.limits.CallerRate, l.limits.CallerBurst, now).limiter.ReserveN(now, 1)
+	if delay := reservationDelay(callerReservation, now); delay > 0 {
+		return nil, delay, "caller rate limit exceeded"
+	}
+	if instance == "" {
+		return func() {}, 0, ""
+	}
+
+	instanceReservation := l.bucket(l.instances, instance, l.limits.InstanceRate, l.limits.InstanceBurst, now).limiter.ReserveN(now, 1)
+	if delay := reservationDelay(instanceReservation, now); delay > 0 {
+		callerReservation.CancelAt(now)
+		return nil, delay, "instance rate limit exceeded"
+	}
+
+	if l.limits.InstanceConcurrency > 0 && l.inFlight[instance] >= l.limits.InstanceConcurrency {
+		callerReservation.CancelAt(now)
+		instanceReservation.CancelAt(now)
+		return nil, busyRetryAfter, "too many operations in flight on the instance"
+	}
+
+	l.inFlight[instance]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight[instance]--
+		if l.inFlight[instance] == 0 {
+			delete(l.inFlight, instance)
+		}
+	}, 0, ""
+}
+
+// reservationDelay returns how long r makes the caller wait, cancelling r if
+// it has to: requests over the limit are turned away, not queued.
+func reservationDelay(r *rate.Reservation, now time.Time) time.Duration {
+	if !r.OK() {
+		return busyRetryAfter
+	}
+	delay := r.DelayFrom(now)
+	if delay > 0 {
+		r.CancelAt(now)
+	}
+	return delay
+}
+
+// limited turns away with 429 and a Retry-After header the requests over the
+// limits of their caller or instance.
+func limited(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := struct{ InstanceID string }{}
+		if bodyData, err := requestBody(r); err == nil {
+			json.Unmarshal(bodyData, &req)
+		}
+
+		release, retryAfter, reason := limits.acquire(requestCaller(r), req.InstanceID)
+		if release == nil {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			logging.FromContext(r.Context()).Info("Request limited", "caller", requestCaller(r), "reason", reason, "retryAfter", seconds)
+
+			errorResponse := api.ErrorResponse{
+				Code:   api.ErrTooManyRequests,
+				Errors: []api.FieldError{{Message: reason}},
+			}
+			body, _ := json.Marshal(errorResponse)
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(body)
+			return
+		}
+		defer release()
+
+		handler(w, r)
+	}
+}
+
+func LimitsHandler(w http.ResponseWriter, r *http.Request) {
+	limitsResponse := api.LimitsResponse{Limits: limits.get()}
+	body, _ := json.Marshal(limitsResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func SetLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	newLimits := api.Limits{}
+
+	bodyData, err := requestBody(r)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+
+	if err := decodeRequest(bodyData, &newLimits); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+
+	if errs := validateLimits(newLimits); len(errs) > 0 {
+		writeRequestError(w, r, errs)
+		return
+	}
+
+	logger := logging.FromContext(r.Context())
+	if err := sharedState.set(r.Context(), limitsKey, newLimits); err != nil {
+		logger.Error(err, "Save limits failed")
+		body, _ := json.Marshal(api.LimitsResponse{Code: api.ErrInternalError})
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(body)
+		return
+	}
+	limits.set(newLimits)
+	logger.Info("Limits changed", "limits", fmt.Sprintf("%+v", newLimits))
+
+	limitsResponse := api.LimitsResponse{Limits: newLimits}
+	body, _ := json.Marshal(limitsResponse)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// syncLimits applies the limits set through any replica, read from the
+// shared state every interval until stop is closed. Until limits are set
+// the flags are kept.
+func syncLimits(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		shared := api.Limits{}
+		ok, err := sharedState.get(ctx, limitsKey, &shared)
+		cancel()
+		switch {
+		case err != nil:
+			logging.Log.Error(err, "Read limits failed")
+		case ok && shared != limits.get():
+			limits.set(shared)
+			logging.Log.Info("Limits changed by another replica", "limits", fmt.Sprintf("%+v", shared))
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func validateLimits(l api.Limits) ValidationErrors {
+	errs := ValidationErrors{}
+	check := func(field string, negative bool) {
+		if negative {
+			errs = append(errs, api.FieldError{Field: field, Rule: "min=0", Message: "must not be negative"})
+		}
+	}
+	check("callerRate", l.CallerRate < 0)
+	check("callerBurst", l.CallerBurst < 0)
+	check("instanceRate", l.InstanceRate < 0)
+	check("instanceBurst", l.InstanceBurst < 0)
+	check("instanceConcurrency", l.InstanceConcurrency < 0)
+	if l.CallerRate > 0 && l.CallerBurst == 0 {
+		errs = append(errs, api.FieldError{Field: "callerBurst", Rule: "min=1", Message: "must be at least 1 when callerRate is set"})
+	}
+	if l.InstanceRate > 0 && l.InstanceBurst == 0 {
+		errs = append(errs, api.FieldError{Field: "instanceBurst", Rule: "min=1", Message: "must be at least 1 when instanceRate is set"})
+	}
+	return errs
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestLimitsSharedByReplicas(t *testing.T) {
+	flags := api.Limits{CallerRate: 10, CallerBurst: 20}
+	one, other := newLimiter(flags), newLimiter(flags)
+	previous := limits
+	defer func() { limits = previous }()
+	defer sharedState.set(context.Background(), limitsKey, nil)
+
+	// Set through one replica.
+	limits = one
+	w := httptest.NewRecorder()
+	SetLimitsHandler(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"callerRate": 1, "callerBurst": 2}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	want := api.Limits{CallerRate: 1, CallerBurst: 2}
+	if got := one.get(); got != want {
+		t.Errorf("limits of the replica set through = %+v, want %+v", got, want)
+	}
+
+	// Applied by the other on its next sync.
+	limits = other
+	stop := make(chan struct{})
+	close(stop)
+	syncLimits(time.Hour, stop)
+	if got := other.get(); got != want {
+		t.Errorf("limits of the other replica = %+v, want %+v", got, want)
+	}
+}
+
+func TestLimitedByCaller(t *testing.T) {
+	withTokens(t, map[string]tokenCaller{"s3cr3t": {Name: "console"}})
+	previous := limits
+	limits = newLimiter(api.Limits{CallerRate: 0.001, CallerBurst: 1})
+	defer func() { limits = previous }()
+
+	handler := limited(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	send := func(remoteAddr, authorization string) int {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		r.RemoteAddr = remoteAddr
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		authorization string
+		status        int
+	}{
+		{"first from an address", "192.0.2.1:1000", "", http.StatusOK},
+		// Another port of the same host is the same caller.
+		{"again from the address", "192.0.2.1:2000", "", http.StatusTooManyRequests},
+		{"another address", "192.0.2.2:1000", "", http.StatusOK},
+		// The token names the caller wherever it connects from.
+		{"first with a token", "192.0.2.1:1000", "Bearer s3cr3t", http.StatusOK},
+		{"again with the token", "192.0.2.3:1000", "Bearer s3cr3t", http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		if status := send(test.remoteAddr, test.authorization); status != test.status {
+			t.Errorf("%s: status = %d, want %d", test.name, status, test.status)
+		}
+	}
+}
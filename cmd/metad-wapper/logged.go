@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/metrics"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// requestIDHeader carries the ID a request is logged under. It is taken from
+// the caller when set, so a request can be followed from the console through
+// the wrapper, and echoed back in the response.
+const requestIDHeader = "X-Request-ID"
+
+// responseRecorder captures the status and body a handler answered with.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// maxRequestBody is the size in bytes a request body may reach at most.
+const maxRequestBody = 1 << 20
+
+type bodyKey struct{}
+
+// readBody reads the body of r, once, for requestBody to share with the
+// middlewares and the handler.
+func readBody(r *http.Request) (*http.Request, error) {
+	bodyData := []byte{}
+	if r.Body != nil {
+		var err error
+		if bodyData, err = ioutil.ReadAll(r.Body); err != nil {
+			return r, err
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), bodyKey{}, bodyData)), nil
+}
+
+// requestBody returns the body of r, as read by logged, or reads it if r
+// didn't go through logged.
+func requestBody(r *http.Request) ([]byte, error) {
+	if bodyData, ok := r.Context().Value(bodyKey{}).([]byte); ok {
+		return bodyData, nil
+	}
+	if r.Body == nil {
+		return nil, nil
+	}
+	return ioutil.ReadAll(r.Body)
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// logged wraps a handler with a request logger, reachable by the handler
+// through logging.FromContext, and logs one line per request once it is
+// answered. The request body is read once, up front, and shared through
+// requestBody; one that can't be read is answered 400 Bad Request. The request is also counted in the handler metrics and traced as
+// a span continuing the trace context the caller sent, if any.
+func logged(endpoint string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		r, bodyErr := readBody(r)
+		req := struct{ InstanceID string }{}
+		if bodyData, err := requestBody(r); bodyErr == nil && err == nil {
+			json.Unmarshal(bodyData, &req)
+		}
+
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), endpoint,
+			attribute.String("http.method", r.Method),
+			attribute.String("http.target", r.URL.Path),
+			attribute.String("request.id", requestID),
+			attribute.String("instance", req.InstanceID))
+
+		logger := logging.Log.WithValues("requestID", requestID, "endpoint", endpoint)
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			logger = logger.WithValues("traceID", traceID)
+		}
+		if req.InstanceID != "" {
+			logger = logger.WithValues("instanceID", req.InstanceID)
+		}
+		r = r.WithContext(logging.NewContext(ctx, logger))
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		if bodyErr != nil {
+			writeRequestError(recorder, r, bodyErr)
+		} else {
+			handler(recorder, r)
+		}
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		code := responseCode(recorder.body.Bytes())
+		latency := time.Since(start)
+		metrics.ObserveRequest(endpoint, status, code, latency)
+
+		span.SetAttributes(attribute.Int("http.status_code", status), attribute.Int("code", code))
+		var err error
+		if code != 0 || status >= http.StatusBadRequest {
+			err = fmt.Errorf("http %d, code %d", status, code)
+		}
+		tracing.End(span, err)
+		logger.Info("Request completed",
+			"method", r.Method,
+			"status", status,
+			"code", code,
+			"latency", latency.Seconds(),
+			"remoteAddr", r.RemoteAddr)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestRequestBody(t *testing.T) {
+	var got []byte
+	handler := served("/test/body", func(w http.ResponseWriter, r *http.Request) {
+		first, _ := requestBody(r)
+		second, _ := requestBody(r)
+		if !bytes.Equal(first, second) {
+			t.Errorf("requestBody = %q, then %q", first, second)
+		}
+		got = first
+		writeResponse(w, api.ErrorResponse{}, false)
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"empty", "", http.StatusOK},
+		{"body", `{"instanceID": "nebula"}`, http.StatusOK},
+		{"at the limit", strings.Repeat("x", maxRequestBody), http.StatusOK},
+		{"too large", strings.Repeat("x", maxRequestBody+1), http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got = nil
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodPost, "/test/body", strings.NewReader(test.body)))
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, test.wantStatus)
+			}
+			if test.wantStatus == http.StatusOK && string(got) != test.body {
+				t.Errorf("handler read %d bytes, want %d", len(got), len(test.body))
+			}
+			if test.wantStatus != http.StatusOK && got != nil {
+				t.Error("handler called on a body too large")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package logging builds the wrapper's structured logger: JSON lines with a
+// level, a timestamp and key/value context, with secret values redacted.
+package logging
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the value of any key that looks like a secret.
+const Redacted = "[REDACTED]"
+
+var secretKeys = []string{"password", "passwd", "secret", "token", "credential"}
+
+// Log is the process-wide logger used where no request logger is at hand.
+var Log logr.Logger = New(0)
+
+// New returns a JSON logger writing to stderr. Messages logged with V(n) are
+// written only if n <= verbosity.
+func New(verbosity int) logr.Logger {
+	config := zap.NewProductionConfig()
+	config.Level = zap.NewAtomicLevelAt(zapcore.Level(-verbosity))
+	config.Sampling = nil
+	config.EncoderConfig.TimeKey = "time"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.DisableStacktrace = true
+	// zapr wraps zap, so the caller would always point into zapr.
+	config.DisableCaller = true
+
+	zapLog, err := config.Build()
+	if err != nil {
+		panic(err)
+	}
+	return &redactingLogger{Logger: zapr.NewLogger(zapLog)}
+}
+
+// SetVerbosity replaces Log with a logger of the given verbosity.
+func SetVerbosity(verbosity int) {
+	Log = New(verbosity)
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger logr.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or Log.
+func FromContext(ctx context.Context) logr.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(logr.Logger); ok {
+		return logger
+	}
+	return Log
+}
+
+func isSecret(key interface{}) bool {
+	k, ok := key.(string)
+	if !ok {
+		return false
+	}
+	k = strings.ToLower(k)
+	for _, secret := range secretKeys {
+		if strings.Contains(k, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(keysAndValues []interface{}) []interface{} {
+	var res []interface{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if isSecret(keysAndValues[i]) {
+			if res == nil {
+				res = append([]interface{}{}, keysAndValues...)
+			}
+			res[i+1] = Redacted
+		}
+	}
+	if res == nil {
+		return keysAndValues
+	}
+	return res
+}
+
+type redactingLogger struct {
+	logr.Logger
+}
+
+func (l *redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.Logger.Info(msg, redact(keysAndValues)...)
+}
+
+func (l *redactingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.Logger.Error(err, msg, redact(keysAndValues)...)
+}
+
+func (l *redactingLogger) V(level int) logr.InfoLogger {
+	return &redactingInfoLogger{l.Logger.V(level)}
+}
+
+func (l *redactingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &redactingLogger{l.Logger.WithValues(redact(keysAndValues)...)}
+}
+
+func (l *redactingLogger) WithName(name string) logr.Logger {
+	return &redactingLogger{l.Logger.WithName(name)}
+}
+
+type redactingInfoLogger struct {
+	logr.InfoLogger
+}
+
+func (l *redactingInfoLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.InfoLogger.Info(msg, redact(keysAndValues)...)
+}
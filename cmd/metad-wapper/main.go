@@ -3,45 +3,63 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/metrics"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/tracing"
 	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
 	"io/ioutil"
 	corev1 "k8s.io/api/core/v1"
-	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 	nebula "github.com/vesoft-inc/nebula-go/nebula"
 	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	metricsv1beta1api "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
-var client *kubernetes.Clientset
-var metricsClient *metricsclientset.Clientset
+var client kubernetes.Interface
+var metricsClient metricsclientset.Interface
 
-func init() {
-	client = makeKubeClient()
-	metricsClient = makeMetircClient()
-	utils.SetK8sClient(client)
+const prometheusURL = "http://prometheus.kube-system:9090"
+
+var prometheusClient = &http.Client{
+	Timeout: time.Second * 5,
+	Transport: metrics.InstrumentRoundTripper("prometheus", tracing.Transport(nil)),
+}
+
+// fatal logs err and exits non-zero; it is for errors the wrapper can't
+// start or keep running with.
+func fatal(err error, msg string, keysAndValues ...interface{}) {
+	logging.Log.Error(err, msg, keysAndValues...)
+	os.Exit(1)
 }
 
 func makeMetircClient() *metricsclientset.Clientset {
 	config, err := rest.InClusterConfig()
 	if err != nil {
-		log.Fatalf("Can't Create K8s Client: %v", err)
+		fatal(err, "Create k8s client failed")
 	}
 
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return metrics.InstrumentRoundTripper("metrics", tracing.Transport(rt))
+	}
 	metricsClient, err := metricsclientset.NewForConfig(config)
 
 	if err != nil {
-		log.Fatalf("Can't Create K8s Client: %v", err)
+		fatal(err, "Create k8s client failed")
 		return nil
 	}
 	return metricsClient
@@ -50,217 +68,290 @@ func makeMetircClient() *metricsclientset.Clientset {
 func makeKubeClient() *kubernetes.Clientset {
 	config, err := rest.InClusterConfig()
 	if err != nil {
-		log.Fatalf("Can't Create K8s Client: %v", err)
+		fatal(err, "Create k8s client failed")
 	}
 
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return metrics.InstrumentRoundTripper("kubernetes", tracing.Transport(rt))
+	}
 	restClient, err := kubernetes.NewForConfig(config)
 
 	if err != nil {
-		log.Fatalf("Can't Create K8s Client: %v", err)
+		fatal(err, "Create k8s client failed")
 		return nil
 	}
 	return restClient
 }
 
-func makeMetadClient(ns string) (*nebula_metad.MetaServiceClient, error) {
-	metadSvc, err := client.CoreV1().Services(ns).Get(context.Background(),"nebula-metad", metav1.GetOptions{})
+func makeMetadClient(ctx context.Context, ns string) (*utils.Client, error) {
+	return utils.Dial(ctx, ns)
+}
 
-	if err != nil {
-		return nil, err
+// metadErrorCode is the code reporting err, a failure to reach metad:
+// ErrInstanceUnavailable while the instance's circuit breaker is open, code
+// otherwise.
+func metadErrorCode(err error, code int) int {
+	if errors.Is(err, utils.ErrInstanceUnavailable) {
+		return api.ErrInstanceUnavailable
 	}
+	return code
+}
 
-	metadSvcIp := metadSvc.Spec.ClusterIP
-
-	timeoutOption := thrift.SocketTimeout(time.Second * 5)
-	addressOption := thrift.SocketAddr(metadSvcIp + ":44500")
+func main() {
+	verbosity := flag.Int("v", 0, "log verbosity, messages logged at a higher V level are dropped")
+	auditLogPath := flag.String("audit-log", "", "file mutating operations are recorded in, e.g. /var/log/metad-wapper/audit.log; empty to disable")
+	auditLogMaxSize := flag.Int64("audit-log-max-size", 100, "size in MiB at which the audit log is rotated")
+	auditLogMaxBackups := flag.Int("audit-log-max-backups", 10, "number of rotated audit logs to keep")
+	auditLogShared := flag.Bool("audit-log-shared", false, "the directory of -audit-log is shared by every replica, each logging to a file of its own with the same extension; /audit then returns the events of all of them")
+	traceExporter := flag.String("trace-exporter", tracing.ExporterNone, "where spans are sent: none, stdout or otlp")
+	traceEndpoint := flag.String("trace-endpoint", "localhost:4317", "address of the OTLP collector, for -trace-exporter=otlp")
+	listenAddress := flag.String("listen-address", "0.0.0.0:8880", "address the API is served on")
+	readTimeout := flag.Duration("read-timeout", 30*time.Second, "maximum time to read a request")
+	writeTimeout := flag.Duration("write-timeout", 2*time.Minute, "maximum time to handle a request and write its response")
+	idleTimeout := flag.Duration("idle-timeout", 2*time.Minute, "how long an idle keep-alive connection is kept open")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 70*time.Second, "how long requests in flight are given to finish on SIGTERM, at least -request-timeout and below the pod's terminationGracePeriodSeconds")
+	flag.DurationVar(&requestTimeout, "request-timeout", time.Minute, "how long a request may work on metad and Kubernetes, below -write-timeout")
+	callerRate := flag.Float64("caller-rate", 10, "requests per second allowed to each caller, 0 for no limit")
+	callerBurst := flag.Int("caller-burst", 20, "requests a caller may send at once")
+	instanceRate := flag.Float64("instance-rate", 20, "requests per second allowed to each instance, 0 for no limit")
+	instanceBurst := flag.Int("instance-burst", 40, "requests an instance may receive at once")
+	instanceConcurrency := flag.Int("instance-concurrency", 8, "requests working on one instance's metad at the same time, 0 for no limit")
+	metadAttempts := flag.Int("metad-attempts", 3, "tries at a metad RPC failing transiently, 1 for no retries")
+	metadBackoff := flag.Duration("metad-backoff", 100*time.Millisecond, "wait before the first retry of a metad RPC, doubled for each further one")
+	metadMaxBackoff := flag.Duration("metad-max-backoff", 2*time.Second, "longest wait between retries of a metad RPC")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "transient metad failures in a row after which an instance is failed fast, 0 to never")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "how long an instance is failed fast before metad is tried again")
+	cacheSpacesTTL := flag.Duration("cache-spaces-ttl", 30*time.Second, "how long the spaces of an instance are cached, 0 to disable")
+	cacheUsersTTL := flag.Duration("cache-users-ttl", 10*time.Second, "how long the users of an instance are cached, 0 to disable")
+	cacheRolesTTL := flag.Duration("cache-roles-ttl", 10*time.Second, "how long the roles of a user are cached, 0 to disable")
+	stateConfigMap := flag.String("state-configmap", "metad-wapper-state", "ConfigMap the replicas keep their shared state in, empty to keep it in memory")
+	stateNamespace := flag.String("state-namespace", os.Getenv("POD_NAMESPACE"), "namespace of -state-configmap, by default the one the wrapper runs in")
+	snapshotRetentionInterval := flag.Duration("snapshot-retention-interval", 10*time.Minute, "how often expired snapshots are dropped, 0 to never")
+	snapshotRetentionLease := flag.String("snapshot-retention-lease", "metad-wapper-snapshot-retention", "Lease, in -state-namespace, electing the one replica dropping expired snapshots; empty to have every replica drop them")
+	flag.StringVar(&instances.namespaceSelector, "instance-namespace-selector", instances.namespaceSelector, "label selector of the namespaces holding instances, empty for any namespace")
+	flag.StringVar(&instances.workloadSelector, "instance-workload-selector", instances.workloadSelector, "label selector of the pods an instance's namespace runs, empty to take every namespace the namespace selector matches")
+	flag.StringVar(&instances.componentLabel, "component-label", instances.componentLabel, "label telling the Nebula component of a pod or PVC")
+	flag.DurationVar(&instances.ttl, "instance-cache-ttl", instances.ttl, "how long the list of instances is kept before it is read again")
+	tokensFile := flag.String("tokens-file", "", "JSON file of the bearer tokens callers authenticate with, empty or missing to take every caller as anonymous")
+	priceSheet := flag.String("price-sheet", "", "JSON file of the prices costs are computed with, empty to leave every resource free")
+	flag.StringVar(&bandwidthAnnotation, "bandwidth-annotation", bandwidthAnnotation, "Service annotation telling the bandwidth units a load balancer is provisioned with")
+	flag.Int64Var(&defaultBandwidth, "default-bandwidth", defaultBandwidth, "bandwidth units of a load balancer without the bandwidth annotation")
+	httpPorts := map[string]*int{}
+	for _, component := range nebulaComponents {
+		httpPorts[component] = flag.Int(component+"-http-port", componentHTTPPorts[component], "port "+component+" serves its status endpoint on")
+	}
+	flag.Parse()
+
+	logging.SetVerbosity(*verbosity)
 
-	transport, err := thrift.NewSocket(timeoutOption, addressOption)
-	protocol := thrift.NewBinaryProtocolFactoryDefault()
-	if err != nil {
-		return nil, err
+	client = makeKubeClient()
+	metricsClient = makeMetircClient()
+	utils.SetK8sClient(client)
+	if *stateNamespace == "" {
+		*stateNamespace = metav1.NamespaceDefault
 	}
-
-	metadClient := nebula_metad.NewMetaServiceClientFactory(transport, protocol)
-
-	err = metadClient.Transport.Open()
-
-	if err != nil {
-		fmt.Println("MetaThrift Cant Open " + err.Error())
-		return nil, err
+	if *stateConfigMap != "" {
+		sharedState = newStateStore(client.CoreV1().ConfigMaps(*stateNamespace), *stateConfigMap)
 	}
 
-	return metadClient, nil
-}
-
-func main() {
-	http.HandleFunc("/metadwapper/list/spaces", ListSpaceHandler)
-	http.HandleFunc("/metadwapper/list/users", ListUsersHandler)
-	http.HandleFunc("/metadwapper/create/spaces", CreateSpaceHandler)
-	http.HandleFunc("/metadwapper/create/users", CreateUserHandler)
-	http.HandleFunc("/metadwapper/clusterCost", ClusterCosts)
-	http.HandleFunc("/metadwapper/changeGod", changeGod)
-	http.HandleFunc("/metadwapper/delete/users", revokeUsersHandler)
-	http.HandleFunc("/metadwapper/initialize", InitializeHandler)
-	http.HandleFunc("/metadwapper/list/spaces/users", ListSpaceUsersHandler)
-	http.HandleFunc("/metadwapper/list/rootspaces/users", ListRootSpaceUsersHandler)
-	http.HandleFunc("/metadwapper/instance/version", InstanceVersion)
-
-	err := http.ListenAndServe("0.0.0.0:8880", nil)
-
+	shutdownTracing, err := tracing.Setup(context.Background(), *traceExporter, *traceEndpoint)
 	if err != nil {
-		fmt.Println("")
+		fatal(err, "Set up tracing failed")
 	}
-}
-
-type ListSpaceRequest struct {
-	InstanceID string
-	UserName   string
-}
-
-type ListSpaceResponse struct {
-	InstanceID string
-	Spaces     []string
-	Code       int
-}
-
-type ListUsersRequest struct {
-	InstanceID string
-}
-
-type ListUsersResponse struct {
-	Users      []string
-	Code       int
-}
-
-type InstanceInfoRequest struct {
-	InstanceID string
-}
-
-type InstanceInfo struct {
-	DiskUsage 	int64   `json:"diskUsage,omitempty"`
-	TotalDiskSpace int64	`json:"totalDiskSpace,omitempty"`
-	Component string	`json:"component"`
-	Version   string	`json:"version"`
-	CommitID  string	`json:"commitID"`
-	BuildTime string	`json:"buildTime"`
-}
-
-type InstanceInfoResponse struct {
-	Code       int
-	Infos []InstanceInfo `json:"data"`
-}
 
-type CreateSpaceRequest struct {
-	InstanceID string
-	SpaceName  string
-}
-
-type CreateUserRequest struct {
-	InstanceID string
-	UserName   string
-	Role       string
-	SpaceName  string
-	Account    string
-}
-type CreateUserResponse struct {
-	Code int
-}
-
-type TransferGodUserRequest struct {
-	InstanceID string
-	UserName   string
-	OldName    string
-}
-
-type TransferGodUserResponse struct {
-	Code int
-}
-
-type ListUserRequest struct {
-	InstanceID string
-	SpaceName  string
-	Operator   string
-}
+	limits = newLimiter(api.Limits{
+		CallerRate:          *callerRate,
+		CallerBurst:         *callerBurst,
+		InstanceRate:        *instanceRate,
+		InstanceBurst:       *instanceBurst,
+		InstanceConcurrency: *instanceConcurrency,
+	})
+	if errs := validateLimits(limits.get()); len(errs) > 0 {
+		fatal(errs, "Invalid limits")
+	}
+	if requestTimeout <= 0 || requestTimeout >= *writeTimeout {
+		fatal(fmt.Errorf("-request-timeout must be positive and below -write-timeout"), "Invalid request timeout",
+			"requestTimeout", requestTimeout.String(), "writeTimeout", writeTimeout.String())
+	}
+	if *shutdownTimeout < requestTimeout {
+		fatal(fmt.Errorf("-shutdown-timeout must be at least -request-timeout"), "Invalid shutdown timeout",
+			"shutdownTimeout", shutdownTimeout.String(), "requestTimeout", requestTimeout.String())
+	}
 
-type ListUserResponse struct {
-	UserRoles map[string]string
-	Code      int
-}
+	if err := instances.validateSelectors(); err != nil {
+		fatal(err, "Invalid instance selector")
+	}
 
-type RevokeUserRequest struct {
-	InstanceID string
-	UserName   string
-	Space      string
-	Role       string
-	Account    string
-}
+	utils.SetLogger(logging.Log)
+	utils.SetObserver(metrics.Metad)
+	utils.SetTracer(tracing.Tracer())
+	utils.SetRetryPolicy(utils.RetryPolicy{Attempts: *metadAttempts, BaseDelay: *metadBackoff, MaxDelay: *metadMaxBackoff})
+	utils.SetBreakerPolicy(utils.BreakerPolicy{Threshold: *breakerThreshold, Cooldown: *breakerCooldown})
+	utils.SetCacheTTL(utils.CacheTTL{Spaces: *cacheSpacesTTL, Users: *cacheUsersTTL, Roles: *cacheRolesTTL})
+	for component, port := range httpPorts {
+		componentHTTPPorts[component] = *port
+	}
+	if *priceSheet != "" {
+		sheet, err := loadPriceSheet(*priceSheet)
+		if err != nil {
+			fatal(err, "Load price sheet failed", "path", *priceSheet)
+		}
+		prices = sheet
+	}
 
-type RevokeUserResponse struct {
-	Code int
-}
+	if *tokensFile != "" {
+		loaded, err := loadTokens(*tokensFile)
+		if err != nil {
+			fatal(err, "Load tokens failed", "path", *tokensFile)
+		}
+		if len(loaded) == 0 {
+			logging.Log.Info("No tokens loaded, every caller is anonymous", "path", *tokensFile)
+		}
+		tokens = loaded
+	}
 
-type Machine struct {
-	Duration string `json:"duration,omitempty"`
-	Cpu int64 	`json:"cpu,omitempty"`
-	Memory int64 `json:"memory,omitempty"`
-}
+	if *auditLogPath != "" {
+		l, err := NewAuditLog(*auditLogPath, *auditLogMaxSize*1024*1024, *auditLogMaxBackups, *auditLogShared)
+		if err != nil {
+			fatal(err, "Open audit log failed")
+		}
+		auditLog = l
+	}
+
+	handle(api.PathListSpaces, discovered(limited(ListSpaceHandler)))
+	handle(api.PathListUsers, discovered(limited(ListUsersHandler)))
+	handle(api.PathCreateSpaces, discovered(limited(committed(audited("CreateSpace", CreateSpaceHandler)))))
+	handle(api.PathCreateUsers, discovered(limited(committed(audited("CreateUser", CreateUserHandler)))))
+	handle(api.PathClusterCost, limited(ClusterCosts))
+	handle(api.PathChangeGod, discovered(limited(committed(audited("ChangeGod", changeGod)))))
+	handle(api.PathDeleteUsers, discovered(limited(committed(audited("RevokeUser", revokeUsersHandler)))))
+	handle(api.PathInitialize, discovered(limited(committed(audited("Initialize", InitializeHandler)))))
+	handle(api.PathListSpaceUsers, discovered(limited(ListSpaceUsersHandler)))
+	handle(api.PathListRootSpaceUsers, discovered(limited(ListRootSpaceUsersHandler)))
+	handle(api.PathInstanceVersion, discovered(limited(InstanceVersion)))
+	handle(api.PathStorageHosts, discovered(limited(StorageHostsHandler)))
+	handle(api.PathBalanceData, discovered(limited(committed(audited("BalanceData", BalanceDataHandler)))))
+	handle(api.PathBalanceLeader, discovered(limited(committed(audited("BalanceLeader", BalanceLeaderHandler)))))
+	handle(api.PathBalanceStop, discovered(limited(committed(audited("StopBalance", BalanceStopHandler)))))
+	handle(api.PathBalanceStatus, discovered(limited(BalanceStatusHandler)))
+	handle(api.PathSubmitJob, discovered(limited(committed(audited("SubmitJob", SubmitJobHandler)))))
+	handle(api.PathListJobs, discovered(limited(ListJobsHandler)))
+	handle(api.PathShowJob, discovered(limited(ShowJobHandler)))
+	handle(api.PathStopJob, discovered(limited(committed(audited("StopJob", StopJobHandler)))))
+	handle(api.PathCreateSnapshot, discovered(limited(committed(audited("CreateSnapshot", CreateSnapshotHandler)))))
+	handle(api.PathListSnapshots, discovered(limited(ListSnapshotsHandler)))
+	handle(api.PathDropSnapshot, discovered(limited(committed(audited("DropSnapshot", DropSnapshotHandler)))))
+	handle(api.PathSnapshotPolicy, discovered(limited(audited("SetSnapshotPolicy", SnapshotPolicyHandler))))
+	handle(api.PathListConfigs, discovered(limited(ListConfigsHandler)))
+	handle(api.PathGetConfig, discovered(limited(GetConfigHandler)))
+	handle(api.PathSetConfig, discovered(limited(committed(audited("SetConfig", SetConfigHandler)))))
+	handle(api.PathAudit, adminOnly(AuditHandler))
+	handle(api.PathOpenAPI, OpenAPIHandler)
+	handle(api.PathStatus, guardedSweep(StatusHandler))
+	handle(api.PathLimits, LimitsHandler)
+	handle(api.PathSetLimits, adminOnly(audited("SetLimits", SetLimitsHandler)))
+	// Not logged: scrapes and probes would drown out the requests worth reading.
+	http.Handle(api.PathMetrics, metrics.Handler())
+	http.HandleFunc(api.PathHealthz, HealthzHandler)
+	http.HandleFunc(api.PathReadyz, ReadyzHandler)
+
+	server := &http.Server{
+		Addr:              *listenAddress,
+		ReadHeaderTimeout: *readTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+	}
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	switch {
+	case *snapshotRetentionInterval <= 0:
+	case *snapshotRetentionLease != "":
+		identity, err := os.Hostname()
+		if err != nil {
+			fatal(err, "Get hostname failed")
+		}
+		lease := &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: *snapshotRetentionLease, Namespace: *stateNamespace},
+			Client:     client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		}
+		go runElectedRetention(retentionCtx, lease, *snapshotRetentionInterval)
+	default:
+		go runRetention(*snapshotRetentionInterval, retentionCtx.Done())
+	}
+	stopLimitsSync := make(chan struct{})
+	go syncLimits(limitsSyncInterval, stopLimitsSync)
+	err = serve(server, *shutdownTimeout)
+	stopRetention()
+	close(stopLimitsSync)
 
-type Disk struct {
-	Duration 	string `json:"duration,omitempty"`
-	Size 		int64 	`json:"size,omitempty"`
-	Usage       int64   `json:"usage,omitempty"`
-	DiskName    string   `json:"diskName,omitempty"`
-}
+	utils.CloseIdleConnections()
+	if auditLog != nil {
+		auditLog.Close()
+	}
+	shutdownTracing(context.Background())
 
-type LoadBalacer struct {
-	Duration 	string `json:"duration,omitempty"`
-	Band 		int64 	`json:"band,omitempty"`
+	if err != nil {
+		fatal(err, "Serve failed", "address", *listenAddress)
+	}
 }
 
-type Instance struct {
-	InstanceName string `json:"instanceName,omitempty"`
-	Cpu 		 int64 `json:"cpu,omitempty"`
-	CpuUsage     int64 `json:"cpuUsage,omitempty"`
-	MemoryUsage     int64 `json:"memoryUsage,omitempty"`
-	Memory 		 int64 `json:"memory,omitempty"`
-	Disks  		 []Disk `json:"disks,omitempty"`
+// handle registers handler on path, as served.
+func handle(path string, handler http.HandlerFunc) {
+	http.HandleFunc(path, served(path, handler))
 }
 
-type ClusterCost struct {
-	ClusterName 	string  `json:"clusterName,omitempty"`
-	Machines 		[]Machine `json:"machines,omitempty"`
-	Disks    		[]Disk		`json:"disks,omitempty"`
-	LoadBalacer 	[]LoadBalacer `json:"loadBalacer,omitempty"`
-	Instances  		[]Instance  `json:"instances,omitempty"`
+// served wraps the handler of path, logged under the path and bounded by
+// requestTimeout, with a body of at most maxRequestBody.
+func served(path string, handler http.HandlerFunc) http.HandlerFunc {
+	handler = logged(path, bounded(uncached(handler)))
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		handler(w, r)
+	}
 }
 
-type ClusterCostResponse struct {
-	Code 		int 		`json:"code,omitempty"`
-	ClusterCost ClusterCost `json:"clusterCost,omitempty"`
+// uncached makes the requests sent with Cache-Control: no-cache, or Pragma:
+// no-cache, read metad rather than the metadata cache.
+func uncached(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		noCache := r.Header.Get("Pragma") == "no-cache"
+		for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+			if directive = strings.ToLower(strings.TrimSpace(directive)); directive == "no-cache" || directive == "no-store" || directive == "max-age=0" {
+				noCache = true
+			}
+		}
+		if noCache {
+			r = r.WithContext(utils.WithoutCache(r.Context()))
+		}
+		handler(w, r)
+	}
 }
 
-func GetPodMertris(instance string) (*metricsv1beta1api.PodMetricsList, error) {
-	m, err := metricsClient.MetricsV1beta1().PodMetricses(instance).List(context.TODO(), metav1.ListOptions{})
+func GetPodMertris(ctx context.Context, instance string) (*metricsv1beta1api.PodMetricsList, error) {
+	m, err := metricsClient.MetricsV1beta1().PodMetricses(instance).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func GetPVCUsage(instance string) (map[string]int64, error){
+func GetPVCUsage(ctx context.Context, instance string) (map[string]int64, error){
 
 	res := map[string]int64{}
-	httpPath := "http://prometheus.kube-system:9090/api/v1/query?query=sum(kubelet_volume_stats_capacity_bytes{namespace=\"" + instance + "\"}-kubelet_volume_stats_available_bytes{namespace=\"" + instance + "\"})by(persistentvolumeclaim)"
+	httpPath := prometheusURL + "/api/v1/query?query=sum(kubelet_volume_stats_capacity_bytes{namespace=\"" + instance + "\"}-kubelet_volume_stats_available_bytes{namespace=\"" + instance + "\"})by(persistentvolumeclaim)"
 
-	httpClient := http.Client{
-		Timeout: time.Second * 5,
+	req, err := http.NewRequest("GET", httpPath, nil)
+	if err != nil {
+		return res, err
 	}
 
-	resp, err := httpClient.Get(httpPath)
+	resp, err := prometheusClient.Do(req.WithContext(ctx))
 
 	if err != nil {
 		return res, err
 	}
+	defer resp.Body.Close()
 
 	type PrometheusResult struct {
 		Metric map[string]string `json:"metric"`
@@ -306,217 +397,236 @@ func GetPVCUsage(instance string) (map[string]int64, error){
 }
 
 func InstanceVersion(w http.ResponseWriter, r *http.Request) {
-	instanceInfoRequest := InstanceInfoRequest{}
-	instanceInfoResponse := InstanceInfoResponse{}
-	bodyData, err := ioutil.ReadAll(r.Body)
+	logger := logging.FromContext(r.Context())
+	instanceInfoRequest := api.InstanceInfoRequest{}
+	instanceInfoResponse := api.InstanceInfoResponse{}
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		instanceInfoResponse.Code = ErrInvalidRequestBody
+		instanceInfoResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(instanceInfoResponse)
+		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 
-		fmt.Println("Invalid InstanceInfoRequest Body")
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "Read request body failed")
 		return
 	}
 
-	json.Unmarshal(bodyData, &instanceInfoRequest)
-
-	fmt.Printf("Get Instance %v Version", instanceInfoRequest.InstanceID)
+	if err := decodeRequest(bodyData, &instanceInfoRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	//pods, err := client.CoreV1().Pods(instanceInfoRequest.InstanceID).List(metav1.ListOptions{})
+	logger.Info("Get instance version")
 
+	pods, err := client.CoreV1().Pods(instanceInfoRequest.InstanceID).List(r.Context(), metav1.ListOptions{})
 	if err != nil {
-		instanceInfoResponse.Code = ErrInternalError
+		instanceInfoResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(instanceInfoResponse)
+		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 
-		log.Printf("List Pods Error: %s", err.Error())
-
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "List pods failed")
 		return
 	}
 
-	type NebulaVersionResponse struct {
-		Status string `json:"status"`
-		BuildTime string `json:"build_time"`
-		GitCommitID string `json:"git_info_sha"`
-		Version     string `json:"version"`
+	instanceInfoResponse.Infos = podVersions(r.Context(), pods.Items)
+	instanceInfoResponse.VersionSkew = markVersionSkew(instanceInfoResponse.Infos)
+	for _, info := range instanceInfoResponse.Infos {
+		if info.Error != "" {
+			logger.Info("Read component status failed", "pod", info.Pod, "error", info.Error)
+		}
+		if info.VersionSkew {
+			logger.Info("Component version skew", "pod", info.Pod, "version", info.Version, "commitID", info.CommitID)
+		}
 	}
 
-	diskUsage, err := GetPVCUsage(instanceInfoRequest.InstanceID)
-
+	// Disk usage is reported along, per replica, when Prometheus can tell it.
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(instanceInfoRequest.InstanceID).List(r.Context(), metav1.ListOptions{})
 	if err != nil {
-		instanceInfoResponse.Code = ErrInternalError
-		body, _ := json.Marshal(instanceInfoResponse)
-		w.Write(body)
-
-		log.Println("List PVC Error: %v", err.Error())
-		w.WriteHeader(http.StatusForbidden)
-		return
+		logger.Error(err, "List PVCs failed")
 	}
-
-	for pvc,usage := range diskUsage {
-		if pvc == "data-storaged-0" {
-			instanceInfoResponse.Infos = append(instanceInfoResponse.Infos, InstanceInfo{
-				Component: "storaged",
-				DiskUsage: usage,
-				Version: "v1.0.0",
-			})
+	diskUsage, err := GetPVCUsage(r.Context(), instanceInfoRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Get PVC usage failed")
+	}
+	capacity := map[string]int64{}
+	if pvcs != nil {
+		for _, pvc := range pvcs.Items {
+			capacity[pvc.Name] = pvc.Status.Capacity.Storage().Value()
 		}
-
-		if pvc == "data-metad-0" {
-			instanceInfoResponse.Infos = append(instanceInfoResponse.Infos, InstanceInfo{
-				Component: "metad",
-				DiskUsage: usage,
-				Version: "v1.0.0",
-			})
+	}
+	podClaims := map[string][]string{}
+	for _, pod := range pods.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				podClaims[pod.Name] = append(podClaims[pod.Name], volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+	for i := range instanceInfoResponse.Infos {
+		info := &instanceInfoResponse.Infos[i]
+		for _, claim := range podClaims[info.Pod] {
+			info.DiskUsage += diskUsage[claim]
+			info.TotalDiskSpace += capacity[claim]
 		}
 	}
-	respBody, _ := json.Marshal(instanceInfoResponse)
-
-	w.Write(respBody)
 
+	respBody, _ := json.Marshal(instanceInfoResponse)
 	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
 func ClusterCosts(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Get Cluster Costs")
+	logger := logging.FromContext(r.Context())
+	logger.Info("Get cluster costs")
 
-	clusterCostResponse := ClusterCostResponse{}
+	clusterCostResponse := api.ClusterCostResponse{}
 
-	nss, err := client.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	now := time.Now()
+	window, err := api.ParseBillingWindow(r.URL.Query(), now)
 	if err != nil {
-		fmt.Println("Inner Error: %v", err)
-		clusterCostResponse.Code = ErrInternalError
+		writeRequestError(w, r, err)
+		return
+	}
+	clusterCostResponse.ClusterCost.Currency = prices.Currency
+	clusterCostResponse.ClusterCost.Start = window.Start
+	clusterCostResponse.ClusterCost.End = window.End
+	clusterCostResponse.ClusterCost.WindowSeconds = int64(window.End.Sub(window.Start).Seconds())
+
+	names, err := instances.list(r.Context(), instances.ttl)
+	if err != nil {
+		logger.Error(err, "List instances failed")
+		clusterCostResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(clusterCostResponse)
+		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
+		return
+	}
+
+	// Load balancers are listed for the cluster, whichever namespace they
+	// are in, and billed to the instance whose namespace they are in.
+	services, err := client.CoreV1().Services(metav1.NamespaceAll).List(r.Context(), metav1.ListOptions{})
+	if err != nil {
+		logger.Error(err, "List load balancers failed")
+		clusterCostResponse.Code = api.ErrInternalError
+		body, _ := json.Marshal(clusterCostResponse)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
+	namespaceServices := map[string][]corev1.Service{}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		namespaceServices[service.Namespace] = append(namespaceServices[service.Namespace], *service)
+		clusterCostResponse.ClusterCost.LoadBalacer = append(clusterCostResponse.ClusterCost.LoadBalacer, apiLoadBalancer(service, now))
+	}
 
-	for _, ns := range nss.Items {
-		pvcs, err := client.CoreV1().PersistentVolumeClaims(ns.Name).List(context.Background(), metav1.ListOptions{})
+	for _, name := range names {
+		pvcs, err := client.CoreV1().PersistentVolumeClaims(name).List(r.Context(), metav1.ListOptions{})
 		if err != nil {
-			fmt.Println("Inner Error: %v", err)
-			clusterCostResponse.Code = ErrInternalError
+			logger.Error(err, "List PVCs failed", "namespace", name)
+			clusterCostResponse.Code = api.ErrInternalError
 			body, _ := json.Marshal(clusterCostResponse)
-			w.Write(body)
 			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
 			return
 		}
 
-		pvcUsage, err := GetPVCUsage(ns.Name)
-
-		if strings.Contains(ns.Name, "nebula") {
-			if ns.DeletionTimestamp != nil {
-				continue
-			}
-
-			if err != nil {
-				fmt.Println("Inner Error: %v", err)
-				clusterCostResponse.Code = ErrInternalError
-				body, _ := json.Marshal(clusterCostResponse)
-				w.Write(body)
-				w.WriteHeader(http.StatusForbidden)
-				return
-			}
+		pvcUsage, err := GetPVCUsage(r.Context(), name)
+		if err != nil {
+			logger.Error(err, "Get PVC usage failed", "namespace", name)
+			clusterCostResponse.Code = api.ErrInternalError
+			body, _ := json.Marshal(clusterCostResponse)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			return
+		}
 
-			podMetrics, err := GetPodMertris(ns.Name)
-			if err != nil {
-				fmt.Println("Inner Error: %v", err)
-				clusterCostResponse.Code = ErrInternalError
-				body, _ := json.Marshal(clusterCostResponse)
-				w.Write(body)
-				w.WriteHeader(http.StatusForbidden)
-				return
-			}
+		podMetrics, err := GetPodMertris(r.Context(), name)
+		if err != nil {
+			logger.Error(err, "Get pod metrics failed", "namespace", name)
+			clusterCostResponse.Code = api.ErrInternalError
+			body, _ := json.Marshal(clusterCostResponse)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			return
+		}
 
-			cpuUsage := int64(0)
-			memUsage := int64(0)
+		pods, err := client.CoreV1().Pods(name).List(r.Context(), metav1.ListOptions{})
+		if err != nil {
+			logger.Error(err, "List pods failed", "namespace", name)
+			clusterCostResponse.Code = api.ErrInternalError
+			body, _ := json.Marshal(clusterCostResponse)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			return
+		}
 
-			for _, metric := range podMetrics.Items {
-				for _, container := range metric.Containers {
-					cpuUsage = cpuUsage + int64(container.Usage.Cpu().Size() )
-					//fmt.Printf("Memory Usage %v: %v",container.Name, container.Usage.Memory().Value()/(1024*1024))
-					memUsage = memUsage + int64(container.Usage.Memory().Value()/(1024*1024))
-				}
-			}
+		statefulSets, err := client.AppsV1().StatefulSets(name).List(r.Context(), metav1.ListOptions{})
+		if err != nil {
+			logger.Error(err, "List StatefulSets failed", "namespace", name)
+			clusterCostResponse.Code = api.ErrInternalError
+			body, _ := json.Marshal(clusterCostResponse)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			return
+		}
 
-			instance := Instance{
-				InstanceName: ns.Name,
-				Cpu: 1000,
-				Memory: 1024,
-				CpuUsage: cpuUsage,
-				MemoryUsage: memUsage,
+		instance := api.Instance{
+			InstanceName: name,
+		}
+		countResources(&instance, pods.Items, podMetrics.Items)
+
+		for i := range pvcs.Items {
+			pvc := &pvcs.Items[i]
+			size := pvc.Status.Capacity.Storage().Value()
+			age := now.Sub(pvc.CreationTimestamp.Time)
+			disk := api.Disk{
+				Duration: age.String(),
+				DurationSeconds: int64(age.Seconds()),
+				Size: size,
+				Usage: pvcUsage[pvc.Name],
+				DiskName: objectComponent(pvc),
 			}
-
-			for _, pvc := range pvcs.Items {
-				size := pvc.Status.Capacity.Storage().Value()
-				duration := time.Now().Sub(pvc.CreationTimestamp.Time).String()
-				name := ""
-				if strings.Contains(pvc.Name, "metad") {
-					name = "metad"
-				} else {
-					name = "storaged"
-				}
-				instance.Disks = append(instance.Disks, Disk{
-					Duration: duration,
-					Size: size,
-					Usage: pvcUsage[pvc.Name],
-					DiskName: name,
-				})
-
+			if pvc.Spec.StorageClassName != nil {
+				disk.StorageClass = *pvc.Spec.StorageClassName
 			}
+			instance.Disks = append(instance.Disks, disk)
 
-			clusterCostResponse.ClusterCost.Instances = append(clusterCostResponse.ClusterCost.Instances, instance)
-		}
-		//else {
-		//	for _, pvc := range pvcs.Items {
-		//		size := pvc.Status.Capacity.Storage().Value()
-		//		duration := time.Now().Sub(pvc.CreationTimestamp.Time).String()
-		//
-		//		clusterCostResponse.ClusterCost.Disks = append(clusterCostResponse.ClusterCost.Disks, Disk{
-		//			Duration: duration,
-		//			Size: size,
-		//			Usage: pvcUsage[pvc.Name],
-		//		})
-		//	}
-		//}
-
-		loadBalancers, err := client.CoreV1().Services(ns.Name).List(context.Background(), metav1.ListOptions{})
-		if err != nil {
-			fmt.Println("List LoadBalancer In %s Failed: %v", ns.Name, err )
-			continue
 		}
-		for _, loadBalancer := range loadBalancers.Items {
-			duration := time.Now().Sub(loadBalancer.CreationTimestamp.Time).String()
-			if loadBalancer.Spec.Type == corev1.ServiceTypeLoadBalancer {
-				clusterCostResponse.ClusterCost.LoadBalacer = append(clusterCostResponse.ClusterCost.LoadBalacer, LoadBalacer{
-					Duration: duration,
-					Band: 10,
-				})
-			}
+		for i := range namespaceServices[name] {
+			instance.LoadBalacer = append(instance.LoadBalacer, apiLoadBalancer(&namespaceServices[name][i], now))
 		}
+
+		instance.Cost = priceInstance(window, pods.Items, pvcs.Items, statefulSets.Items, namespaceServices[name])
+		clusterCostResponse.ClusterCost.Cost.Add(instance.Cost)
+
+		clusterCostResponse.ClusterCost.Instances = append(clusterCostResponse.ClusterCost.Instances, instance)
 	}
+	clusterCostResponse.ClusterCost.Cost = roundCosts(clusterCostResponse.ClusterCost.Cost)
 
-	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	nodes, err := client.CoreV1().Nodes().List(r.Context(), metav1.ListOptions{})
 
 	if err != nil {
-		fmt.Println("Inner Error: %v", err)
-		clusterCostResponse.Code = ErrInternalError
+		logger.Error(err, "List nodes failed")
+		clusterCostResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(clusterCostResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	for _, node := range nodes.Items {
 		cpu := node.Status.Capacity.Cpu().Value()
 		memory := node.Status.Capacity.Memory().Value()
-		duration := time.Now().Sub(node.CreationTimestamp.Time).String()
-		clusterCostResponse.ClusterCost.Machines = append(clusterCostResponse.ClusterCost.Machines, Machine{
-			Duration: duration,
+		age := now.Sub(node.CreationTimestamp.Time)
+		clusterCostResponse.ClusterCost.Machines = append(clusterCostResponse.ClusterCost.Machines, api.Machine{
+			Duration: age.String(),
+			DurationSeconds: int64(age.Seconds()),
 			Cpu: cpu,
 			Memory: memory/(1024*1024),
 		})
@@ -524,68 +634,113 @@ func ClusterCosts(w http.ResponseWriter, r *http.Request) {
 
 	respBody, _ := json.Marshal(clusterCostResponse)
 
-	fmt.Println("Get Cluster Costs Done")
-
-	w.Write(respBody)
+	logger.V(1).Info("Get cluster costs done")
 
 	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
 
 func ListSpaceHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("List Spaces")
+	logger := logging.FromContext(r.Context())
+	logger.V(1).Info("List spaces")
 
-	listSpaceRequest := ListSpaceRequest{}
-	listSpaceResponse := ListSpaceResponse{}
+	listSpaceRequest := api.ListSpaceRequest{}
+	listSpaceResponse := api.ListSpaceResponse{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		listSpaceResponse.Code = ErrInvalidRequestBody
+		listSpaceResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(listSpaceResponse)
+		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 
-		fmt.Println("Invalid SpaceRequest Body")
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "Read request body failed")
+		return
+	}
+	if err := decodeRequest(bodyData, &listSpaceRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	after, err := decodeCursor(listSpaceRequest.Cursor)
+	if err != nil {
+		writeRequestError(w, r, err)
 		return
 	}
-	json.Unmarshal(bodyData, &listSpaceRequest)
 
-	metadClient, err := makeMetadClient(listSpaceRequest.InstanceID)
+	fail := func(code int) {
+		listSpaceResponse.Code = code
+		body, _ := json.Marshal(listSpaceResponse)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+	}
 
-	defer func() {
-		if metadClient != nil {
-			metadClient.Transport.Close()
-		}
-	}()
+	metadClient, err := makeMetadClient(r.Context(), listSpaceRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	defer metadClient.Close()
+
+	logger.V(1).Info("Filter spaces by user", "user", listSpaceRequest.UserName)
 
+	getUserRolesReq := nebula_metad.NewGetUserRolesReq()
+	getUserRolesReq.Account = listSpaceRequest.UserName
+	roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
 	if err != nil {
-		fmt.Println("Create MetadClient for error", listSpaceRequest.InstanceID, err)
-		listSpaceResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listSpaceResponse)
-		w.Write(body)
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "Get user roles failed", "user", listSpaceRequest.UserName)
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	// A user not found holds no role in any space.
+	if roleResp.Code != nebula_metad.ErrorCode_SUCCEEDED && roleResp.Code != nebula_metad.ErrorCode_E_NOT_FOUND {
+		logger.Info("Get user roles failed", "user", listSpaceRequest.UserName, "metadCode", roleResp.Code.String())
+		fail(api.ErrInternalError)
 		return
 	}
 
-	spaces, err := utils.ListSpaces(listSpaceRequest.InstanceID)
+	listSpacesResp, err := metadClient.ListSpaces(nebula_metad.NewListSpacesReq())
 	if err != nil {
-		fmt.Println("List Spaces for error: ", listSpaceRequest.InstanceID, err)
-		listSpaceResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listSpaceResponse)
-		w.Write(body)
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "List spaces failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
 		return
 	}
+	if listSpacesResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List spaces failed", "metadCode", listSpacesResp.Code.String())
+		fail(api.ErrInternalError)
+		return
+	}
+	spaceNames := map[nebula.GraphSpaceID]string{}
+	spaces := make([]string, 0, len(listSpacesResp.Spaces))
+	for _, space := range listSpacesResp.Spaces {
+		spaceNames[space.Id.GetSpaceID()] = space.Name
+		spaces = append(spaces, space.Name)
+	}
 
-	fmt.Println("Parse SpaceResponse")
+	// A GOD holds its role in every space.
+	spaceRoles := map[string]string{}
+	for _, role := range userRoles(roleResp.Roles, spaceNames) {
+		if role.Space == "" {
+			for _, space := range spaces {
+				spaceRoles[space] = role.Role
+			}
+			break
+		}
+		spaceRoles[role.Space] = role.Role
+	}
 
-	listSpaceResponse.Spaces = []string{}
-	for _, space := range spaces {
-		if utils.IsUserInSpace(space, listSpaceRequest.UserName, listSpaceRequest.InstanceID) {
-			listSpaceResponse.Spaces = append(listSpaceResponse.Spaces, space)
+	userSpaces := []string{}
+	for _, space := range withPrefix(spaces, listSpaceRequest.Prefix) {
+		role, ok := spaceRoles[space]
+		if !ok || listSpaceRequest.Role != "" && role != listSpaceRequest.Role {
+			continue
 		}
+		userSpaces = append(userSpaces, space)
 	}
+	listSpaceResponse.Total = len(userSpaces)
+	listSpaceResponse.Spaces, listSpaceResponse.NextCursor = pageOf(userSpaces, listSpaceRequest.Limit, listSpaceRequest.Order, after)
 
 	if listSpaceResponse.Spaces == nil && len(listSpaceResponse.Spaces) == 0 {
 		listSpaceResponse.Spaces = make([]string,0)
@@ -596,79 +751,173 @@ func ListSpaceHandler(w http.ResponseWriter, r *http.Request) {
 
 	respBody, _ := json.Marshal(listSpaceResponse)
 
-	w.Write(respBody)
-
 	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
 func ListUsersHandler(w http.ResponseWriter, r *http.Request) {
-	listUsersRequest := ListUsersRequest{}
-	listUsersResponse := ListUsersResponse{}
+	logger := logging.FromContext(r.Context())
+	listUsersRequest := api.ListUsersRequest{}
+	listUsersResponse := api.ListUsersResponse{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
-
-	if err != nil {
-		listUsersResponse.Code = ErrInvalidRequestBody
+	fail := func(code int) {
+		listUsersResponse.Code = code
 		body, _ := json.Marshal(listUsersResponse)
+		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
+	}
 
-		fmt.Println("Invalid SpaceRequest Body")
-		w.WriteHeader(http.StatusForbidden)
+	bodyData, err := requestBody(r)
+	if err != nil {
+		logger.Error(err, "Read request body failed")
+		fail(api.ErrInvalidRequestBody)
+		return
+	}
+	if err := decodeRequest(bodyData, &listUsersRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	after, err := decodeCursor(listUsersRequest.Cursor)
+	if err != nil {
+		writeRequestError(w, r, err)
 		return
 	}
-	json.Unmarshal(bodyData, &listUsersRequest)
 
+	metadClient, err := makeMetadClient(r.Context(), listUsersRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	defer metadClient.Close()
 
-	users, err := utils.ListUsers(listUsersRequest.InstanceID)
+	listUsersResp, err := metadClient.ListUsers(nebula_metad.NewListUsersReq())
 	if err != nil {
-		log.Println("Get User from %s Error: %v", listUsersRequest.InstanceID, err)
-		listUsersResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listUsersResponse)
-		w.Write(body)
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "List users failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
+		return
+	}
+	if listUsersResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List users failed", "metadCode", listUsersResp.Code.String())
+		fail(api.ErrInternalError)
 		return
 	}
 
-	spaces, err := utils.ListSpaces(listUsersRequest.InstanceID)
+	listSpacesResp, err := metadClient.ListSpaces(nebula_metad.NewListSpacesReq())
 	if err != nil {
-		log.Println("Get Spaces from %s Error: %v", listUsersRequest.InstanceID, err)
-		listUsersResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listUsersResponse)
-		w.Write(body)
-		w.WriteHeader(http.StatusForbidden)
+		logger.Error(err, "List spaces failed")
+		fail(metadErrorCode(err, api.ErrInternalError))
 		return
 	}
+	if listSpacesResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List spaces failed", "metadCode", listSpacesResp.Code.String())
+		fail(api.ErrInternalError)
+		return
+	}
+	spaceNames := map[nebula.GraphSpaceID]string{}
+	for _, space := range listSpacesResp.Spaces {
+		spaceNames[space.Id.GetSpaceID()] = space.Name
+	}
 
-	for _, user := range users {
-		for _, space := range spaces {
-			if utils.IsUserInSpace(space, user, listUsersRequest.InstanceID) {
-				listUsersResponse.Users = append(listUsersResponse.Users, user)
-			}
+	names := make([]string, 0, len(listUsersResp.Users))
+	for user := range listUsersResp.Users {
+		names = append(names, user)
+	}
+
+	// Users holding no role, or not the role asked for, are skipped, which
+	// takes the roles of every user to count them: one lookup each.
+	users := map[string]api.User{}
+	kept := []string{}
+	for _, name := range withPrefix(names, listUsersRequest.Prefix) {
+		getUserRolesReq := nebula_metad.NewGetUserRolesReq()
+		getUserRolesReq.Account = name
+
+		roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
+		if err != nil {
+			logger.Error(err, "Get user roles failed", "user", name)
+			fail(metadErrorCode(err, api.ErrInternalError))
+			return
+		}
+		if roleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+			logger.Info("Get user roles failed", "user", name, "metadCode", roleResp.Code.String())
+			fail(api.ErrInternalError)
+			return
 		}
+
+		user := api.User{Name: name, Roles: userRoles(roleResp.Roles, spaceNames)}
+		if len(user.Roles) == 0 || !holdsRole(user.Roles, listUsersRequest.Role) {
+			continue
+		}
+		users[name] = user
+		kept = append(kept, name)
 	}
-	respBody, _ := json.Marshal(listUsersResponse)
 
-	w.Write(respBody)
+	page, next := pageOf(kept, listUsersRequest.Limit, listUsersRequest.Order, after)
+	listUsersResponse.Users = make([]api.User, 0, len(page))
+	for _, name := range page {
+		listUsersResponse.Users = append(listUsersResponse.Users, users[name])
+	}
+	listUsersResponse.Total = len(kept)
+	listUsersResponse.NextCursor = next
 
+	respBody, _ := json.Marshal(listUsersResponse)
 	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
+}
+
+// holdsRole reports whether roles include role, or role is empty.
+func holdsRole(roles []api.SpaceRole, role string) bool {
+	if role == "" {
+		return true
+	}
+	for _, spaceRole := range roles {
+		if spaceRole.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// userRoles converts the roles of a user to the API's, sorted by space. The
+// roles in spaces missing from spaceNames, dropped since they were listed,
+// are left out.
+func userRoles(roles []*nebula.RoleItem, spaceNames map[nebula.GraphSpaceID]string) []api.SpaceRole {
+	spaceRoles := []api.SpaceRole{}
+	for _, role := range roles {
+		spaceRole := api.SpaceRole{Role: rolesToString(role.RoleType)}
+		if role.SpaceID != 0 {
+			name, ok := spaceNames[role.SpaceID]
+			if !ok {
+				continue
+			}
+			spaceRole.Space = name
+		}
+		spaceRoles = append(spaceRoles, spaceRole)
+	}
+	sort.Slice(spaceRoles, func(i, j int) bool { return spaceRoles[i].Space < spaceRoles[j].Space })
+	return spaceRoles
 }
 
 func CreateSpaceHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
 
-	fmt.Println("Begin Create Space")
+	logger.V(1).Info("Create space")
 
-	createSpaceRequest := CreateSpaceRequest{}
+	createSpaceRequest := api.CreateSpaceRequest{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
+	bodyData, err := requestBody(r)
 
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	json.Unmarshal(bodyData, &createSpaceRequest)
+	if err := decodeRequest(bodyData, &createSpaceRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(createSpaceRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), createSpaceRequest.InstanceID)
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		return
@@ -676,7 +925,7 @@ func CreateSpaceHandler(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -690,44 +939,48 @@ func CreateSpaceHandler(w http.ResponseWriter, r *http.Request) {
 	createSpaceResp, err := metadClient.CreateSpace(createSpaceReq)
 
 	if err != nil {
-		fmt.Println("create space " + createSpaceRequest.SpaceName + " error: " + err.Error())
+		logger.Error(err, "Create space failed", "space", createSpaceRequest.SpaceName)
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	if createSpaceResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("create space failed, ErrorCode is ", createSpaceResp.Code)
+		logger.Info("Create space failed", "space", createSpaceRequest.SpaceName, "metadCode", createSpaceResp.Code.String())
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
-	fmt.Println("Create Space Done")
+	logger.Info("Space created", "space", createSpaceRequest.SpaceName)
 	w.WriteHeader(http.StatusOK)
 	return
 }
 
 func changeGod(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Handle Change GOD Request")
+	logger := logging.FromContext(r.Context())
+	logger.V(1).Info("Change GOD")
 
-	transferGodUserRequest := TransferGodUserRequest{}
-	transferGodUserResponse := TransferGodUserResponse{}
-	bodyData, err := ioutil.ReadAll(r.Body)
+	transferGodUserRequest := api.TransferGodUserRequest{}
+	transferGodUserResponse := api.TransferGodUserResponse{}
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		fmt.Println("Invalid Request Body")
-		transferGodUserResponse.Code = ErrInternalError
+		logger.Error(err, "Read request body failed")
+		transferGodUserResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &transferGodUserRequest)
+	if err := decodeRequest(bodyData, &transferGodUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(transferGodUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), transferGodUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create MetadClient Error!")
-		transferGodUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		transferGodUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -736,14 +989,15 @@ func changeGod(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
-	users, err := utils.ListUsers(transferGodUserRequest.InstanceID)
+	users, err := utils.ListUsers(r.Context(), transferGodUserRequest.InstanceID)
 
 	if err != nil {
-		transferGodUserResponse.Code = ErrInternalError
+		logger.Error(err, "List users failed")
+		transferGodUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -751,21 +1005,29 @@ func changeGod(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, user := range users {
-		if user == transferGodUserRequest.UserName {
-			utils.DropUser(transferGodUserRequest.InstanceID, user)
+		if user != transferGodUserRequest.UserName {
+			continue
+		}
+		if err := utils.DropUser(r.Context(), transferGodUserRequest.InstanceID, user); err != nil {
+			logger.Error(err, "Drop user failed", "user", user)
+			transferGodUserResponse.Code = metadErrorCode(err, api.ErrDropUserFailed)
+			body, _ := json.Marshal(transferGodUserResponse)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			return
 		}
 	}
 
-	err = utils.CreateUser(transferGodUserRequest.InstanceID, transferGodUserRequest.UserName)
+	err = utils.CreateUser(r.Context(), transferGodUserRequest.InstanceID, transferGodUserRequest.UserName)
 	if err != nil {
-		transferGodUserResponse.Code = ErrInternalError
+		transferGodUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 		return
 	}
 
-	fmt.Println("Create USER " + transferGodUserRequest.UserName + " Success")
+	logger.Info("User created", "user", transferGodUserRequest.UserName)
 
 	grantRoleReq := nebula_metad.NewGrantRoleReq()
 	grantRoleReq.RoleItem = nebula.NewRoleItem()
@@ -774,11 +1036,11 @@ func changeGod(w http.ResponseWriter, r *http.Request) {
 	grantRoleReq.RoleItem.User = transferGodUserRequest.UserName
 	grantRoleReq.RoleItem.SpaceID = 0
 
-	fmt.Println("Begin Grant " + transferGodUserRequest.UserName + " to GOD")
+	logger.V(1).Info("Grant GOD", "user", transferGodUserRequest.UserName)
 	grantRoleResp, err := metadClient.GrantRole(grantRoleReq)
 	if err != nil {
-		fmt.Println("Grant Roles Failed: " + err.Error())
-		transferGodUserResponse.Code = ErrInitialUserFailed
+		logger.Error(err, "Grant GOD failed")
+		transferGodUserResponse.Code = metadErrorCode(err, api.ErrInitialUserFailed)
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -786,55 +1048,66 @@ func changeGod(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if grantRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("Grant Roles ErrorCode is : ", grantRoleResp.Code)
-		transferGodUserResponse.Code = ErrInitialUserFailed
+		logger.Info("Grant GOD failed", "metadCode", grantRoleResp.Code.String())
+		transferGodUserResponse.Code = api.ErrInitialUserFailed
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 		return
 	}
 
-
 	dropUserReq := nebula_metad.NewDropUserReq()
 	dropUserReq.Account = transferGodUserRequest.OldName
-	_, err = metadClient.DropUser(dropUserReq)
+	dropUserResp, err := metadClient.DropUser(dropUserReq)
 	if err != nil {
-		fmt.Println("MetadClient Create User Failed !", err.Error())
-		transferGodUserResponse.Code = ErrInternalError
+		logger.Error(err, "Drop old GOD failed", "oldUser", transferGodUserRequest.OldName)
+		transferGodUserResponse.Code = metadErrorCode(err, api.ErrDropUserFailed)
+		body, _ := json.Marshal(transferGodUserResponse)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+		return
+	}
+	// The old GOD gone already, as when a transfer is retried, is dropped.
+	if dropUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED && dropUserResp.Code != nebula_metad.ErrorCode_E_NOT_FOUND {
+		logger.Info("Drop old GOD failed", "oldUser", transferGodUserRequest.OldName, "metadCode", dropUserResp.Code.String())
+		transferGodUserResponse.Code = api.ErrDropUserFailed
 		body, _ := json.Marshal(transferGodUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 		return
 	}
-
 
 	transferGodUserResponse.Code = 0
 	body, _ := json.Marshal(transferGodUserResponse)
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
-	fmt.Println("Create GOD User " + transferGodUserRequest.UserName + " Success!")
+	logger.Info("GOD transferred", "user", transferGodUserRequest.UserName, "oldUser", transferGodUserRequest.OldName)
 	return
 }
 
 func InitializeHandler(w http.ResponseWriter, r *http.Request) {
-	createUserRequest := CreateUserRequest{}
-	createUserResponse := CreateUserResponse{}
-	bodyData, err := ioutil.ReadAll(r.Body)
+	logger := logging.FromContext(r.Context())
+	createUserRequest := api.InitializeRequest{}
+	createUserResponse := api.CreateUserResponse{}
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		createUserResponse.Code = ErrInternalError
+		createUserResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &createUserRequest)
+	if err := decodeRequest(bodyData, &createUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(createUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), createUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create MetadClient Error!")
-		createUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -843,7 +1116,7 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -855,8 +1128,8 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 
-		fmt.Println("MetadClient Create User Failed !", err.Error())
-		createUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create user failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -866,8 +1139,8 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 	if createUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED &&
 		createUserResp.Code != nebula_metad.ErrorCode_E_EXISTED {
 
-		fmt.Println("Create User Failed")
-		createUserResponse.Code = ErrUserExisted
+		logger.Info("Create user failed", "metadCode", createUserResp.Code.String())
+		createUserResponse.Code = api.ErrUserExisted
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -882,7 +1155,7 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Println("Create USER " + createUserRequest.UserName + " Success")
+	logger.Info("User created", "user", createUserRequest.UserName)
 
 	grantRoleReq := nebula_metad.NewGrantRoleReq()
 	grantRoleReq.RoleItem = nebula.NewRoleItem()
@@ -891,11 +1164,11 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 	grantRoleReq.RoleItem.User = createUserRequest.UserName
 	grantRoleReq.RoleItem.SpaceID = 0
 
-	fmt.Println("Begin Grant " + createUserRequest.UserName + " to GOD")
+	logger.V(1).Info("Grant GOD", "user", createUserRequest.UserName)
 	grantRoleResp, err := metadClient.GrantRole(grantRoleReq)
 	if err != nil {
-		fmt.Println("Grant Roles Failed: " + err.Error())
-		createUserResponse.Code = ErrInitialUserFailed
+		logger.Error(err, "Grant GOD failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInitialUserFailed)
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -903,8 +1176,8 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if grantRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("Grant Roles ErrorCode is : ", grantRoleResp.Code)
-		createUserResponse.Code = ErrInitialUserFailed
+		logger.Info("Grant GOD failed", "metadCode", grantRoleResp.Code.String())
+		createUserResponse.Code = api.ErrInitialUserFailed
 		body, _ := json.Marshal(createUserResponse)
 		w.WriteHeader(http.StatusForbidden)
 		w.Write(body)
@@ -915,41 +1188,45 @@ func InitializeHandler(w http.ResponseWriter, r *http.Request) {
 	body, _ := json.Marshal(createUserResponse)
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
-	fmt.Println("Create GOD User" + createUserRequest.UserName + " Success!")
+	logger.Info("GOD user created", "user", createUserRequest.UserName)
 	return
 }
 
 func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	createUserRequest := CreateUserRequest{}
-	createUserResponse := CreateUserResponse{}
-	bodyData, err := ioutil.ReadAll(r.Body)
+	logger := logging.FromContext(r.Context())
+	createUserRequest := api.CreateUserRequest{}
+	createUserResponse := api.CreateUserResponse{}
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		fmt.Println("Invalid Request Body")
-		createUserResponse.Code = ErrInvalidRequestBody
+		logger.Error(err, "Read request body failed")
+		createUserResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &createUserRequest)
+	if err := decodeRequest(bodyData, &createUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	fmt.Println("CreateUserRequest", createUserRequest)
+	logger.V(1).Info("Create user", "user", createUserRequest.UserName, "space", createUserRequest.SpaceName, "role", createUserRequest.Role, "account", createUserRequest.Account)
 
-	metadClient, err := makeMetadClient(createUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), createUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create Metad Client Failed ", err.Error())
-		createUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -967,25 +1244,23 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		roleType = nebula.RoleType_GUEST
 	}
 
-	operatorRole, err := utils.GetUserRoles(createUserRequest.Account, createUserRequest.SpaceName, createUserRequest.InstanceID)
+	operatorRole, err := utils.GetUserRoles(r.Context(), createUserRequest.Account, createUserRequest.SpaceName, createUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create User Failed ", err.Error())
-		createUserResponse.Code = ErrGrantRoleFailed
+		logger.Error(err, "Create user failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrGrantRoleFailed)
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
-	fmt.Printf("User %s Role is %d\n", createUserRequest.Account, operatorRole)
+	logger.V(1).Info("Operator role", "account", createUserRequest.Account, "role", rolesToString(operatorRole))
 
 	if operatorRole > roleType {
-		fmt.Println("Create User Failed: FatherAccount Role larger then Role")
-		createUserResponse.Code = ErrGrantRoleFailed
+		logger.Info("Create user refused: operator role is lower than the granted role", "account", createUserRequest.Account, "role", createUserRequest.Role)
+		createUserResponse.Code = api.ErrGrantRoleFailed
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
@@ -995,28 +1270,18 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	createUserReq.Account = createUserRequest.UserName
 
 	createUserResp, err := metadClient.CreateUser(createUserReq)
-
-	if err != nil && createUserResp.Code != nebula_metad.ErrorCode_E_EXISTED {
-		fmt.Println("Create User Failed ", err.Error())
-		createUserResponse.Code = ErrInternalError
-		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
-
-		w.WriteHeader(http.StatusForbidden)
+	if err != nil {
+		logger.Error(err, "Create user failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, createUserResponse, true)
 		return
 	}
-
-	if createUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		if createUserResp.Code == nebula_metad.ErrorCode_E_EXISTED {
-
-		} else {
-			createUserResponse.Code = ErrInternalError
-			body, _ := json.Marshal(createUserResponse)
-			w.Write(body)
-			fmt.Println("Create User Failed")
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
+	// The user may exist already, to be granted a role in another space.
+	if createUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED && createUserResp.Code != nebula_metad.ErrorCode_E_EXISTED {
+		logger.Info("Create user failed", "metadCode", createUserResp.Code.String())
+		createUserResponse.Code = api.ErrInternalError
+		writeResponse(w, createUserResponse, true)
+		return
 	}
 
 	grantRoleReq := nebula_metad.NewGrantRoleReq()
@@ -1024,33 +1289,33 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	getSpaceReq := nebula_metad.NewGetSpaceReq()
 	getSpaceReq.SpaceName = createUserRequest.SpaceName
-	fmt.Println("Get Space, Name is :  " + createUserRequest.SpaceName)
+	logger.V(1).Info("Get space", "space", createUserRequest.SpaceName)
 	getSpaceResp, err := metadClient.GetSpace(getSpaceReq)
 
 	if err != nil {
-		fmt.Println("Get Space Failed ", err.Error())
+		logger.Error(err, "Get space failed")
 
-		createUserResponse.Code = ErrInternalError
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	if getSpaceResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
 		if getSpaceResp.Code == nebula_metad.ErrorCode_E_NOT_FOUND {
-			createUserResponse.Code = ErrSpaceNotFound
+			createUserResponse.Code = api.ErrSpaceNotFound
 			body, _ := json.Marshal(createUserResponse)
-			w.Write(body)
-			fmt.Println("Get Space Failed ", getSpaceResp.Code)
 			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			logger.Info("Space not found", "space", createUserRequest.SpaceName)
 			return
 		} else {
-			createUserResponse.Code = ErrInternalError
+			createUserResponse.Code = api.ErrInternalError
 			body, _ := json.Marshal(createUserResponse)
-			w.Write(body)
-			fmt.Println("Get Space Error ", getSpaceResp.Code)
 			w.WriteHeader(http.StatusForbidden)
+			w.Write(body)
+			logger.Info("Get space failed", "space", createUserRequest.SpaceName, "metadCode", getSpaceResp.Code.String())
 			return
 		}
 	}
@@ -1061,65 +1326,67 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	grantRoleReq.RoleItem.User = createUserRequest.UserName
 	grantRoleReq.RoleItem.SpaceID = spaceID
 
-	fmt.Println("Grant User SpaceID", spaceID, "UserName", createUserRequest.UserName, "RoleType", roleType)
+	logger.V(1).Info("Grant role", "spaceID", spaceID, "user", createUserRequest.UserName, "role", createUserRequest.Role)
 	grantRoleResp, err := metadClient.GrantRole(grantRoleReq)
 	if err != nil {
-		fmt.Println("Grant Role Failed ", err.Error())
-		createUserResponse.Code = ErrInternalError
+		logger.Error(err, "Grant role failed")
+		createUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	if grantRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		createUserResponse.Code = ErrInternalError
+		createUserResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(createUserResponse)
-		w.Write(body)
-		fmt.Println("Grant User Error ", getSpaceResp.Code)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+		logger.Info("Grant role failed", "metadCode", grantRoleResp.Code.String())
 		return
 	}
 
 	createUserResponse.Code = 0
 	body, _ := json.Marshal(createUserResponse)
-	w.Write(body)
 	w.WriteHeader(http.StatusOK)
+	w.Write(body)
 	return
 }
 
 func revokeUsersHandler(w http.ResponseWriter, r *http.Request) {
-	deleteUserRequest := RevokeUserRequest{}
-	deleteUserResponse := RevokeUserResponse{}
+	logger := logging.FromContext(r.Context())
+	deleteUserRequest := api.RevokeUserRequest{}
+	deleteUserResponse := api.RevokeUserResponse{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		deleteUserResponse.Code = ErrInvalidRequestBody
+		deleteUserResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &deleteUserRequest)
+	if err := decodeRequest(bodyData, &deleteUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(deleteUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), deleteUserRequest.InstanceID)
 	if err != nil {
 
-		fmt.Println("Create Metad Client Error ", err.Error())
-		deleteUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		deleteUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -1137,36 +1404,34 @@ func revokeUsersHandler(w http.ResponseWriter, r *http.Request) {
 		roleType = nebula.RoleType_GUEST
 	}
 
-	revokerRole, err := utils.GetUserRoles(deleteUserRequest.Account, deleteUserRequest.Space, deleteUserRequest.InstanceID)
+	revokerRole, err := utils.GetUserRoles(r.Context(), deleteUserRequest.Account, deleteUserRequest.Space, deleteUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Delete User Failed ", err.Error())
-		deleteUserResponse.Code = ErrGrantRoleFailed
+		logger.Error(err, "Get operator role failed", "account", deleteUserRequest.Account)
+		deleteUserResponse.Code = metadErrorCode(err, api.ErrGrantRoleFailed)
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	if revokerRole > roleType {
-		fmt.Println("Delete User Failed")
-		deleteUserResponse.Code = ErrGrantRoleFailed
+		logger.Info("Revoke refused: operator role is lower than the revoked role", "account", deleteUserRequest.Account, "role", deleteUserRequest.Role)
+		deleteUserResponse.Code = api.ErrGrantRoleFailed
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 
-	spaceID, err := utils.GetSpaceID(deleteUserRequest.InstanceID, deleteUserRequest.Space)
+	spaceID, err := utils.GetSpaceID(r.Context(), deleteUserRequest.InstanceID, deleteUserRequest.Space)
 
 	if err != nil {
-		fmt.Println("Get SpaceID Failed ", err.Error())
-		deleteUserResponse.Code = ErrInternalError
+		logger.Error(err, "Get space failed", "space", deleteUserRequest.Space)
+		deleteUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
@@ -1179,31 +1444,34 @@ func revokeUsersHandler(w http.ResponseWriter, r *http.Request) {
 	revokeRoleResp, err := metadClient.RevokeRole(dropUserReq)
 
 	if err != nil {
-		fmt.Println("Revoke User Failed ", err.Error())
-		deleteUserResponse.Code = ErrInternalError
+		logger.Error(err, "Revoke role failed")
+		deleteUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	if revokeRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("Revoke User Failed, Responce Code: ", revokeRoleResp.Code)
-		deleteUserResponse.Code = ErrInternalError
+		logger.Info("Revoke role failed", "metadCode", revokeRoleResp.Code.String())
+		deleteUserResponse.Code = api.ErrInternalError
 		body, _ := json.Marshal(deleteUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	deleteUserResponse.Code = 0
 	body, _ := json.Marshal(deleteUserResponse)
+	w.WriteHeader(http.StatusOK)
 	w.Write(body)
+	return
+}
 
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	return
+	w.Write([]byte(api.OpenAPISpec))
 }
 
 func rolesToString(role nebula.RoleType) string {
@@ -1220,47 +1488,78 @@ func rolesToString(role nebula.RoleType) string {
 	}
 }
 
+// pageUserRoles keeps the users of resp.UserRoles whose name starts with
+// prefix and, if role is set, who hold it, and cuts them down to the page
+// after the user named after, set in order in resp.Users.
+func pageUserRoles(resp *api.ListUserResponse, limit int, order, after, prefix, role string) {
+	users := []string{}
+	for user, userRole := range resp.UserRoles {
+		if strings.HasPrefix(user, prefix) && (role == "" || userRole == role) {
+			users = append(users, user)
+		}
+	}
+
+	page, next := pageOf(users, limit, order, after)
+	userRoles := make(map[string]string, len(page))
+	resp.Users = make([]api.UserRole, 0, len(page))
+	for _, user := range page {
+		userRoles[user] = resp.UserRoles[user]
+		resp.Users = append(resp.Users, api.UserRole{User: user, Role: resp.UserRoles[user]})
+	}
+	resp.UserRoles = userRoles
+	resp.Total = len(users)
+	resp.NextCursor = next
+}
+
 func ListSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
-	listUserRequest := ListUserRequest{}
-	listUserResponse := ListUserResponse{}
+	logger := logging.FromContext(r.Context())
+	listUserRequest := api.ListUserRequest{}
+	listUserResponse := api.ListUserResponse{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		fmt.Println("Invalid Request Body")
-		listUserResponse.Code = ErrInvalidRequestBody
+		logger.Error(err, "Read request body failed")
+		listUserResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &listUserRequest)
+	if err := decodeRequest(bodyData, &listUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	after, err := decodeCursor(listUserRequest.Cursor)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(listUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), listUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create MetadClient Error ", err.Error())
-		listUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		listUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
-	operatorRole, err := utils.GetUserRoles(listUserRequest.Operator, listUserRequest.SpaceName, listUserRequest.InstanceID)
+	operatorRole, err := utils.GetUserRoles(r.Context(), listUserRequest.Operator, listUserRequest.SpaceName, listUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Invalid Request Body")
-		listUserResponse.Code = ErrNotFound
+		logger.Error(err, "Get operator role failed", "operator", listUserRequest.Operator)
+		listUserResponse.Code = metadErrorCode(err, api.ErrNotFound)
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
@@ -1268,56 +1567,59 @@ func ListSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
 		listUserResponse.Code = 0
 		listUserResponse.UserRoles = make(map[string]string)
 		listUserResponse.UserRoles[listUserRequest.Operator] = rolesToString(operatorRole)
+		pageUserRoles(&listUserResponse, listUserRequest.Limit, listUserRequest.Order, after, listUserRequest.Prefix, listUserRequest.Role)
 		respBody, _ := json.Marshal(listUserResponse)
-		fmt.Fprintf(w, string(respBody))
+		w.WriteHeader(http.StatusOK)
+		w.Write(respBody)
 		return
 	}
 
 	listUserReq := nebula_metad.NewListUsersReq()
 
 	listUserResp, err := metadClient.ListUsers(listUserReq)
-
+	if err != nil {
+		logger.Error(err, "List users failed")
+		listUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listUserResponse, true)
+		return
+	}
 	if listUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("List User Failed")
-		listUserResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
-
-		w.WriteHeader(http.StatusForbidden)
+		logger.Info("List users failed", "metadCode", listUserResp.Code.String())
+		listUserResponse.Code = api.ErrInternalError
+		writeResponse(w, listUserResponse, true)
 		return
 	}
 
 	getSpaceReq := nebula_metad.NewGetSpaceReq()
-	fmt.Println("SpaceName is ", listUserRequest.SpaceName)
+	logger.V(1).Info("Get space", "space", listUserRequest.SpaceName)
 	getSpaceReq.SpaceName = listUserRequest.SpaceName
 	getSpaceResp, err := metadClient.GetSpace(getSpaceReq)
 
 	if err != nil {
-		fmt.Println("Get Space Failed ", err.Error())
-		listUserResponse.Code = ErrInternalError
+		logger.Error(err, "Get space failed")
+		listUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	if getSpaceResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("Get Space Failed, ErrorCode is ", getSpaceResp.Code)
-		listUserResponse.Code = ErrNotFound
+		logger.Info("Get space failed", "space", listUserRequest.SpaceName, "metadCode", getSpaceResp.Code.String())
+		listUserResponse.Code = api.ErrNotFound
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	spaceID := getSpaceResp.Item.SpaceID
-	fmt.Println("SpaceID is ", getSpaceResp.Item.SpaceID)
+	logger.V(1).Info("Space found", "spaceID", getSpaceResp.Item.SpaceID)
 
 	listUserResponse.UserRoles = make(map[string]string)
 
 	for user, userid := range listUserResp.Users {
-		fmt.Println("Get Roles of ", userid, user)
+		logger.V(1).Info("Get user roles", "user", user, "userID", userid)
 
 		if user == "root" {
 			continue
@@ -1328,7 +1630,10 @@ func ListSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
 
 		roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
 		if err != nil {
-			fmt.Println("Get User " + user + " error " + err.Error())
+			if r.Context().Err() != nil {
+				break
+			}
+			logger.Error(err, "Get user roles failed", "user", user)
 			continue
 		}
 
@@ -1365,57 +1670,78 @@ func ListSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := r.Context().Err(); err != nil {
+		logger.Error(err, "List users aborted")
+		listUserResponse.Code = api.ErrInternalError
+		body, _ := json.Marshal(listUserResponse)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+		return
+	}
+
 	listUserResponse.Code = 0
+	pageUserRoles(&listUserResponse, listUserRequest.Limit, listUserRequest.Order, after, listUserRequest.Prefix, listUserRequest.Role)
 	respBody, _ := json.Marshal(listUserResponse)
 
-	fmt.Fprintf(w, string(respBody))
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
 func ListRootSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
-	listUserRequest := ListUserRequest{}
-	listUserResponse := ListUserResponse{}
+	logger := logging.FromContext(r.Context())
+	listUserRequest := api.ListRootUserRequest{}
+	listUserResponse := api.ListUserResponse{}
 
-	bodyData, err := ioutil.ReadAll(r.Body)
+	bodyData, err := requestBody(r)
 
 	if err != nil {
-		fmt.Println("Invalid Request Body")
-		listUserResponse.Code = ErrInvalidRequestBody
+		logger.Error(err, "Read request body failed")
+		listUserResponse.Code = api.ErrInvalidRequestBody
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
-	json.Unmarshal(bodyData, &listUserRequest)
+	if err := decodeRequest(bodyData, &listUserRequest); err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
+	after, err := decodeCursor(listUserRequest.Cursor)
+	if err != nil {
+		writeRequestError(w, r, err)
+		return
+	}
 
-	metadClient, err := makeMetadClient(listUserRequest.InstanceID)
+	metadClient, err := makeMetadClient(r.Context(), listUserRequest.InstanceID)
 	if err != nil {
-		fmt.Println("Create MetadClient Error ", err.Error())
-		listUserResponse.Code = ErrInternalError
+		logger.Error(err, "Create metad client failed")
+		listUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
 		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
-
 		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
 		return
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
 	listUserReq := nebula_metad.NewListUsersReq()
 
 	listUserResp, err := metadClient.ListUsers(listUserReq)
-
+	if err != nil {
+		logger.Error(err, "List users failed")
+		listUserResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listUserResponse, true)
+		return
+	}
 	if listUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
-		fmt.Println("List User Failed")
-		listUserResponse.Code = ErrInternalError
-		body, _ := json.Marshal(listUserResponse)
-		w.Write(body)
-
-		w.WriteHeader(http.StatusForbidden)
+		logger.Info("List users failed", "metadCode", listUserResp.Code.String())
+		listUserResponse.Code = api.ErrInternalError
+		writeResponse(w, listUserResponse, true)
 		return
 	}
 
@@ -1424,14 +1750,17 @@ func ListRootSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
 	listUserResponse.UserRoles = make(map[string]string)
 
 	for user, userid := range listUserResp.Users {
-		fmt.Println("Get Roles of ", userid, user)
+		logger.V(1).Info("Get user roles", "user", user, "userID", userid)
 
 		getUserRolesReq := nebula_metad.NewGetUserRolesReq()
 		getUserRolesReq.Account = user
 
 		roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
 		if err != nil {
-			fmt.Println("Get User " + user + " error " + err.Error())
+			if r.Context().Err() != nil {
+				break
+			}
+			logger.Error(err, "Get user roles failed", "user", user)
 			continue
 		}
 
@@ -1455,28 +1784,21 @@ func ListRootSpaceUsersHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := r.Context().Err(); err != nil {
+		logger.Error(err, "List users aborted")
+		listUserResponse.Code = api.ErrInternalError
+		body, _ := json.Marshal(listUserResponse)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(body)
+		return
+	}
+
 	listUserResponse.Code = 0
+	pageUserRoles(&listUserResponse, listUserRequest.Limit, listUserRequest.Order, after, listUserRequest.Prefix, listUserRequest.Role)
 	respBody, _ := json.Marshal(listUserResponse)
 
-	fmt.Fprintf(w, string(respBody))
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
 }
 
-const (
-	ErrNotFound                = 40001
-	ErrIllegalMemory           = 40002
-	ErrIllegalCPU              = 40003
-	ErrNoResource              = 40004
-	ErrNoMoney                 = 40005
-	ErrS3NoStorage             = 40006
-	ErrNoInstance              = 40007
-	ErrEmptyInstanceID         = 40008
-	ErrInvalidRequestBody      = 40009
-	ErrEmptySpaceName          = 40010
-	ErrCloudProviderInnerError = 40011
-	ErrUserExisted             = 40012
-	ErrGrantRoleFailed         = 40013
-	ErrInitialUserFailed       = 40014
-	ErrInternalError           = 40015
-	ErrSpaceNotFound           = 40016
-)
 
@@ -0,0 +1,216 @@
+// Package metrics defines the Prometheus metrics of the metad wrapper and the
+// helpers that record them for its handlers, metad connections and the HTTP
+// clients it uses to reach Kubernetes and Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "metad_wapper"
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by handler, HTTP status and API result code.",
+	}, []string{"handler", "status", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by handler and API result code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "code"})
+
+	metadRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_requests_total",
+		Help:      "RPCs sent to metad, by instance and method.",
+	}, []string{"instance", "method"})
+
+	metadErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_request_errors_total",
+		Help:      "RPCs to metad that failed to be sent or answered, by instance and method.",
+	}, []string{"instance", "method"})
+
+	metadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "metad_request_duration_seconds",
+		Help:      "Time taken by RPCs to metad, by instance and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"instance", "method"})
+
+	metadConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "metad_connections",
+		Help:      "Connections to metad currently open, by instance.",
+	}, []string{"instance"})
+
+	metadIdleConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "metad_idle_connections",
+		Help:      "Open connections to metad kept in the pool for reuse, by instance.",
+	}, []string{"instance"})
+
+	metadConnectErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_connect_errors_total",
+		Help:      "Connections to metad that could not be opened, by instance.",
+	}, []string{"instance"})
+
+	metadRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_retries_total",
+		Help:      "RPCs to metad retried after a transient failure, by instance and method.",
+	}, []string{"instance", "method"})
+
+	metadBreakerOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "metad_breaker_open",
+		Help:      "Whether the circuit breaker of an instance's metad is open (1) or closed (0).",
+	}, []string{"instance"})
+
+	metadBreakerRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_breaker_rejections_total",
+		Help:      "Operations failed fast because the circuit breaker of the instance was open.",
+	}, []string{"instance"})
+
+	metadCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "metad_cache_lookups_total",
+		Help:      "Reads of metad metadata looked up in the cache, by instance, kind (spaces, space, users, roles) and result (hit, miss, bypass).",
+	}, []string{"instance", "kind", "result"})
+
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "client_request_duration_seconds",
+		Help:      "Time taken by outgoing HTTP requests, by target (kubernetes, metrics, prometheus), method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"target", "method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		requests,
+		requestDuration,
+		metadRequests,
+		metadErrors,
+		metadDuration,
+		metadConnections,
+		metadIdleConnections,
+		metadConnectErrors,
+		metadRetries,
+		metadBreakerOpen,
+		metadBreakerRejections,
+		metadCacheLookups,
+		clientDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a request served by handler.
+func ObserveRequest(handler string, status, code int, latency time.Duration) {
+	codeLabel := strconv.Itoa(code)
+	requests.WithLabelValues(handler, strconv.Itoa(status), codeLabel).Inc()
+	requestDuration.WithLabelValues(handler, codeLabel).Observe(latency.Seconds())
+}
+
+// InstrumentRoundTripper records the latency of every request sent through
+// next under target.
+func InstrumentRoundTripper(target string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return promhttp.InstrumentRoundTripperDuration(clientDuration.MustCurryWith(prometheus.Labels{"target": target}), next)
+}
+
+// ObserveMetadCall records an RPC of method made to the metad of instance.
+func ObserveMetadCall(instance, method string, latency time.Duration, failed bool) {
+	metadRequests.WithLabelValues(instance, method).Inc()
+	metadDuration.WithLabelValues(instance, method).Observe(latency.Seconds())
+	if failed {
+		metadErrors.WithLabelValues(instance, method).Inc()
+	}
+}
+
+// MetadConnected records a connection to the metad of instance being opened,
+// or failing to be when err is set.
+func MetadConnected(instance string, err error) {
+	if err != nil {
+		metadConnectErrors.WithLabelValues(instance).Inc()
+		return
+	}
+	metadConnections.WithLabelValues(instance).Inc()
+}
+
+// MetadDisconnected records a connection to the metad of instance being
+// closed.
+func MetadDisconnected(instance string) {
+	metadConnections.WithLabelValues(instance).Dec()
+}
+
+// MetadIdle records delta connections to the metad of instance entering (or,
+// negative, leaving) the idle pool.
+func MetadIdle(instance string, delta float64) {
+	metadIdleConnections.WithLabelValues(instance).Add(delta)
+}
+
+// MetadRetried records an RPC of method to the metad of instance being
+// retried.
+func MetadRetried(instance, method string) {
+	metadRetries.WithLabelValues(instance, method).Inc()
+}
+
+// MetadBreaker records the circuit breaker of instance opening or closing.
+func MetadBreaker(instance string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	metadBreakerOpen.WithLabelValues(instance).Set(value)
+}
+
+// MetadRejected records an operation on instance failed fast by its open
+// circuit breaker.
+func MetadRejected(instance string) {
+	metadBreakerRejections.WithLabelValues(instance).Inc()
+}
+
+// ObserveCacheLookup records a read of kind from the metad of instance being
+// answered from the cache ("hit"), fetched ("miss"), or fetched because the
+// caller asked not to be served from the cache ("bypass").
+func ObserveCacheLookup(instance, kind, result string) {
+	metadCacheLookups.WithLabelValues(instance, kind, result).Inc()
+}
+
+// Metad records what the operations on metad do; it is the observer the
+// wrapper gives the utils package.
+var Metad metadObserver
+
+type metadObserver struct{}
+
+func (metadObserver) MetadCall(instance, method string, latency time.Duration, failed bool) {
+	ObserveMetadCall(instance, method, latency, failed)
+}
+
+func (metadObserver) MetadConnected(instance string, err error) { MetadConnected(instance, err) }
+func (metadObserver) MetadDisconnected(instance string)         { MetadDisconnected(instance) }
+func (metadObserver) MetadIdle(instance string, delta float64)  { MetadIdle(instance, delta) }
+func (metadObserver) MetadRetried(instance, method string)      { MetadRetried(instance, method) }
+func (metadObserver) MetadBreaker(instance string, open bool)   { MetadBreaker(instance, open) }
+func (metadObserver) MetadRejected(instance string)             { MetadRejected(instance) }
+func (metadObserver) CacheLookup(instance, kind, result string) {
+	ObserveCacheLookup(instance, kind, result)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+)
+
+// defaultPageSize is the page size of list requests that set no Limit.
+const defaultPageSize = 100
+
+// A cursor is the name of the last item of a page, encoded so callers treat
+// it as opaque. Pages are keyed on names rather than offsets, so an item
+// created or dropped between two requests doesn't shift the next page.
+
+func encodeCursor(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ValidationErrors{{Field: "Cursor", Rule: "cursor", Message: "is not a cursor returned by a previous page"}}
+	}
+	return string(name), nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	return limit
+}
+
+// namesAfter sorts names in order, asc unless "desc", and returns those
+// coming after the item named after, or all of them if after is empty.
+func namesAfter(names []string, order, after string) []string {
+	desc := order == "desc"
+	sort.Slice(names, func(i, j int) bool {
+		if desc {
+			return names[i] > names[j]
+		}
+		return names[i] < names[j]
+	})
+	if after == "" {
+		return names
+	}
+	return names[sort.Search(len(names), func(i int) bool {
+		if desc {
+			return names[i] < after
+		}
+		return names[i] > after
+	}):]
+}
+
+// pageOf returns the page of names coming after the item named after, in
+// order, and the cursor of the next page, empty if it is the last.
+func pageOf(names []string, limit int, order, after string) ([]string, string) {
+	names = namesAfter(names, order, after)
+	limit = pageSize(limit)
+	if len(names) <= limit {
+		return names, ""
+	}
+	return names[:limit], encodeCursor(names[limit-1])
+}
+
+// withPrefix keeps the names starting with prefix, all of them if it is
+// empty.
+func withPrefix(names []string, prefix string) []string {
+	kept := names[:0]
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
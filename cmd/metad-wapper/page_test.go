@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	for _, name := range []string{"", "space", "a/b+c=d", "名字", "user@example.com"} {
+		got, err := decodeCursor(encodeCursor(name))
+		if err != nil || got != name {
+			t.Errorf("decodeCursor(encodeCursor(%q)) = %q, %v", name, got, err)
+		}
+	}
+
+	for _, cursor := range []string{"not a cursor!", "c3BhY2U=", "a"} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded, want an error", cursor)
+		}
+	}
+}
+
+func TestNamesAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		order string
+		after string
+		want  []string
+	}{
+		{"asc", "", "", []string{"a", "b", "c", "d"}},
+		{"asc after", "asc", "b", []string{"c", "d"}},
+		// The item after was dropped since the last page.
+		{"asc after dropped", "asc", "bb", []string{"c", "d"}},
+		{"asc after the last", "asc", "d", []string{}},
+		{"desc", "desc", "", []string{"d", "c", "b", "a"}},
+		{"desc after", "desc", "c", []string{"b", "a"}},
+		{"desc after dropped", "desc", "bb", []string{"b", "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := namesAfter([]string{"c", "a", "d", "b"}, test.order, test.after)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("namesAfter = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	names := []string{"e", "b", "d", "a", "c"}
+
+	tests := []struct {
+		name     string
+		limit    int
+		order    string
+		after    string
+		want     []string
+		wantNext string
+	}{
+		{"first page", 2, "asc", "", []string{"a", "b"}, "b"},
+		{"middle page", 2, "asc", "b", []string{"c", "d"}, "d"},
+		{"last page", 2, "asc", "d", []string{"e"}, ""},
+		{"exactly the last page", 3, "asc", "b", []string{"c", "d", "e"}, ""},
+		{"desc", 2, "desc", "", []string{"e", "d"}, "d"},
+		{"default size", 0, "asc", "", []string{"a", "b", "c", "d", "e"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, next := pageOf(append([]string{}, names...), test.limit, test.order, test.after)
+			if !reflect.DeepEqual(page, test.want) {
+				t.Errorf("page = %v, want %v", page, test.want)
+			}
+			wantNext := ""
+			if test.wantNext != "" {
+				wantNext = encodeCursor(test.wantNext)
+			}
+			if next != wantNext {
+				t.Errorf("next cursor = %q, want the cursor of %q", next, test.wantNext)
+			}
+		})
+	}
+
+	// Walking the pages visits every name once.
+	many := []string{}
+	for i := 0; i < 2*defaultPageSize+1; i++ {
+		many = append(many, strings.Repeat("x", i+1))
+	}
+	seen, after := 0, ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pages never end")
+		}
+		page, next := pageOf(append([]string{}, many...), 0, "asc", after)
+		seen += len(page)
+		if next == "" {
+			break
+		}
+		var err error
+		if after, err = decodeCursor(next); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if seen != len(many) {
+		t.Errorf("pages held %d names, want %d", seen, len(many))
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{"no prefix", "", []string{"test", "team", "prod", "te"}},
+		{"prefix", "te", []string{"test", "team", "te"}},
+		{"whole name", "prod", []string{"prod"}},
+		{"none match", "dev", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := withPrefix([]string{"test", "team", "prod", "te"}, test.prefix)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("withPrefix = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
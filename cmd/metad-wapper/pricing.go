@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const gibibyte = 1024 * 1024 * 1024
+
+// prices is the price sheet costs are computed with, read from -price-sheet.
+// With none every resource is free.
+var prices = api.PriceSheet{Currency: "USD"}
+
+// bandwidthAnnotation is the Service annotation telling the bandwidth a load
+// balancer is provisioned with. Load balancers without it are counted with
+// defaultBandwidth.
+var bandwidthAnnotation = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-bandwidth"
+
+var defaultBandwidth int64 = 10
+
+// loadPriceSheet reads a price sheet from the JSON file at path.
+func loadPriceSheet(path string) (api.PriceSheet, error) {
+	sheet := api.PriceSheet{}
+	f, err := os.Open(path)
+	if err != nil {
+		return sheet, err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&sheet); err != nil {
+		return sheet, err
+	}
+	return sheet, sheet.Validate()
+}
+
+// billedHours is how long within window something created at created has
+// existed, in hours.
+func billedHours(window *api.BillingWindow, created time.Time) float64 {
+	start := window.Start
+	if created.After(start) {
+		start = created
+	}
+	if !start.Before(window.End) {
+		return 0
+	}
+	return window.End.Sub(start).Hours()
+}
+
+func loadBalancerBand(service *corev1.Service) int64 {
+	if band, err := strconv.ParseInt(service.Annotations[bandwidthAnnotation], 10, 64); err == nil && band >= 0 {
+		return band
+	}
+	return defaultBandwidth
+}
+
+func storageGiBMonth(pvc *corev1.PersistentVolumeClaim) float64 {
+	if pvc.Spec.StorageClassName != nil {
+		if price, ok := prices.StorageGiBMonth[*pvc.Spec.StorageClassName]; ok {
+			return price
+		}
+	}
+	return prices.DefaultStorageGiBMonth
+}
+
+// roundCost rounds to a hundredth of a cent, fine enough for per hour prices
+// of small resources to add up.
+func roundCost(cost float64) float64 {
+	return math.Round(cost*10000) / 10000
+}
+
+// priceInstance computes what the pods, PVCs and load balancers of an
+// instance cost over window: pods by the CPU and memory their containers
+// request, PVCs by their capacity. Only what exists now can be priced, so
+// resources deleted during the window are left out, and a pod is billed from
+// when its replica started, as podBillingStart tells.
+func priceInstance(window *api.BillingWindow, pods []corev1.Pod, pvcs []corev1.PersistentVolumeClaim, statefulSets []appsv1.StatefulSet, services []corev1.Service) api.Cost {
+	pvcCreated := map[string]time.Time{}
+	for i := range pvcs {
+		pvcCreated[pvcs[i].Name] = pvcs[i].CreationTimestamp.Time
+	}
+	statefulSetCreated := map[string]time.Time{}
+	for i := range statefulSets {
+		statefulSetCreated[statefulSets[i].Name] = statefulSets[i].CreationTimestamp.Time
+	}
+
+	cost := api.Cost{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		hours := billedHours(window, podBillingStart(pod, pvcCreated, statefulSetCreated))
+		var cpu, cpuLimit, memory, memoryLimit int64
+		addResources(pod, &cpu, &cpuLimit, &memory, &memoryLimit)
+		cost.Cpu += float64(cpu) / 1000 * hours * prices.VCPUHour
+		cost.Memory += float64(memory) / gibibyte * hours * prices.MemoryGiBHour
+	}
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		hours := billedHours(window, pvc.CreationTimestamp.Time)
+		size := pvc.Status.Capacity.Storage().Value()
+		cost.Storage += float64(size) / gibibyte * hours / api.HoursPerMonth * storageGiBMonth(pvc)
+	}
+	for i := range services {
+		service := &services[i]
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		hours := billedHours(window, service.CreationTimestamp.Time)
+		cost.LoadBalancer += hours * prices.LoadBalancerHour
+		cost.Bandwidth += float64(loadBalancerBand(service)) * hours * prices.BandwidthUnitHour
+	}
+
+	return roundCosts(cost)
+}
+
+// podBillingStart is when the replica a pod runs started, as told from what
+// exists now: a pod replaced, e.g. by a rolling upgrade or an eviction, is
+// still the same replica. That is the earliest creation of the PVCs the pod
+// mounts, which outlive its pods, or else of the StatefulSet owning it, or
+// else of the pod itself.
+func podBillingStart(pod *corev1.Pod, pvcCreated, statefulSetCreated map[string]time.Time) time.Time {
+	start := time.Time{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		created, ok := pvcCreated[volume.PersistentVolumeClaim.ClaimName]
+		if ok && (start.IsZero() || created.Before(start)) {
+			start = created
+		}
+	}
+	if !start.IsZero() {
+		return start
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "StatefulSet" {
+		if created, ok := statefulSetCreated[owner.Name]; ok {
+			return created
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// roundCosts rounds every part of cost, the total being the sum of the
+// rounded parts.
+func roundCosts(cost api.Cost) api.Cost {
+	cost.Cpu = roundCost(cost.Cpu)
+	cost.Memory = roundCost(cost.Memory)
+	cost.Storage = roundCost(cost.Storage)
+	cost.LoadBalancer = roundCost(cost.LoadBalancer)
+	cost.Bandwidth = roundCost(cost.Bandwidth)
+	cost.Total = roundCost(cost.Cpu + cost.Memory + cost.Storage + cost.LoadBalancer + cost.Bandwidth)
+	return cost
+}
+
+func apiLoadBalancer(service *corev1.Service, now time.Time) api.LoadBalacer {
+	age := now.Sub(service.CreationTimestamp.Time)
+	return api.LoadBalacer{
+		Duration:        age.String(),
+		DurationSeconds: int64(age.Seconds()),
+		Band:            loadBalancerBand(service),
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var windowStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// at is h hours into the billing window of the tests.
+func at(h float64) metav1.Time {
+	return metav1.NewTime(windowStart.Add(time.Duration(h * float64(time.Hour))))
+}
+
+func TestBilledHours(t *testing.T) {
+	window := &api.BillingWindow{Start: windowStart, End: windowStart.Add(10 * time.Hour)}
+
+	tests := []struct {
+		name    string
+		created metav1.Time
+		want    float64
+	}{
+		{"before the window", at(-5), 10},
+		{"at the start", at(0), 10},
+		{"within the window", at(7.5), 2.5},
+		{"at the end", at(10), 0},
+		{"after the window", at(12), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := billedHours(window, test.created.Time); got != test.want {
+				t.Errorf("billedHours = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRoundCosts(t *testing.T) {
+	tests := []struct {
+		name string
+		cost api.Cost
+		want api.Cost
+	}{
+		{"rounded to a hundredth of a cent",
+			api.Cost{Cpu: 1.23456, Memory: 0.00004, Storage: 2, LoadBalancer: 0.99995, Bandwidth: 0.12344},
+			api.Cost{Cpu: 1.2346, Memory: 0, Storage: 2, LoadBalancer: 1, Bandwidth: 0.1234, Total: 4.358}},
+		// The total adds up the parts as shown, not as computed.
+		{"total of the rounded parts",
+			api.Cost{Cpu: 0.00006, Memory: 0.00006, Total: 7},
+			api.Cost{Cpu: 0.0001, Memory: 0.0001, Total: 0.0002}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := roundCosts(test.cost); got != test.want {
+				t.Errorf("roundCosts = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPriceInstance(t *testing.T) {
+	previous := prices
+	prices = api.PriceSheet{
+		Currency:               "USD",
+		VCPUHour:               0.1,
+		MemoryGiBHour:          0.01,
+		StorageGiBMonth:        map[string]float64{"ssd": 14.6},
+		DefaultStorageGiBMonth: 7.3,
+		LoadBalancerHour:       0.5,
+		BandwidthUnitHour:      0.02,
+	}
+	defer func() { prices = previous }()
+
+	window := &api.BillingWindow{Start: windowStart, End: windowStart.Add(10 * time.Hour)}
+	requesting := func(cpu, memory string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)},
+		}}}}
+	}
+	mounting := func(spec corev1.PodSpec, claims ...string) corev1.PodSpec {
+		for _, claim := range claims {
+			spec.Volumes = append(spec.Volumes, corev1.Volume{VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			}})
+		}
+		return spec
+	}
+	ssd := "ssd"
+	isController := true
+
+	statefulSets := []appsv1.StatefulSet{{ObjectMeta: metav1.ObjectMeta{Name: "graphd", CreationTimestamp: at(-48)}}}
+	pods := []corev1.Pod{
+		// Replaced an hour ago, billed from when its StatefulSet was made: 10h.
+		{ObjectMeta: metav1.ObjectMeta{Name: "graphd-0", CreationTimestamp: at(9), OwnerReferences: []metav1.OwnerReference{
+			{Kind: "StatefulSet", Name: "graphd", Controller: &isController},
+		}}, Spec: requesting("1", "1Gi")},
+		// Billed from its older PVC: 8h.
+		{ObjectMeta: metav1.ObjectMeta{Name: "storaged-0", CreationTimestamp: at(9)},
+			Spec: mounting(requesting("500m", "2Gi"), "data", "logs")},
+		// Billed from its own creation: 1h.
+		{ObjectMeta: metav1.ObjectMeta{Name: "job", CreationTimestamp: at(9)}, Spec: requesting("1", "0")},
+		{ObjectMeta: metav1.ObjectMeta{Name: "done", CreationTimestamp: at(-1)}, Spec: requesting("8", "8Gi"),
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+	}
+	pvcs := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Name: "data", CreationTimestamp: at(5)},
+			Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: &ssd},
+			Status: corev1.PersistentVolumeClaimStatus{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "logs", CreationTimestamp: at(2)},
+			Status: corev1.PersistentVolumeClaimStatus{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")}}},
+	}
+	services := []corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "graphd-lb", CreationTimestamp: at(-1), Annotations: map[string]string{bandwidthAnnotation: "5"}},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "graphd", CreationTimestamp: at(-1)},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+	}
+
+	want := api.Cost{
+		// 1 vCPU for 10h, 0.5 for 8h and 1 for 1h.
+		Cpu: 1.5,
+		// 1GiB for 10h and 2GiB for 8h.
+		Memory: 0.26,
+		// 10GiB of ssd for 5h and 1GiB for 8h.
+		Storage:      1.08,
+		LoadBalancer: 5,
+		Bandwidth:    1,
+		Total:        8.84,
+	}
+	if got := priceInstance(window, pods, pvcs, statefulSets, services); got != want {
+		t.Errorf("priceInstance = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	corev1 "k8s.io/api/core/v1"
+	metricsv1beta1api "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const mebibyte = 1024 * 1024
+
+// addResources adds the requests and limits of the containers of pod to the
+// counters, CPU in millicores and memory in bytes.
+func addResources(pod *corev1.Pod, cpu, cpuLimit, memory, memoryLimit *int64) {
+	for _, container := range pod.Spec.Containers {
+		requests, limits := container.Resources.Requests, container.Resources.Limits
+		*cpu += requests.Cpu().MilliValue()
+		*memory += requests.Memory().Value()
+		*cpuLimit += limits.Cpu().MilliValue()
+		*memoryLimit += limits.Memory().Value()
+	}
+}
+
+// countResources fills in the resources of instance and of each of its
+// components from its pods and their metrics. Pods that are done running
+// hold nothing and are left out.
+func countResources(instance *api.Instance, pods []corev1.Pod, podMetrics []metricsv1beta1api.PodMetrics) {
+	type counters struct {
+		replicas                         int
+		cpu, cpuLimit, cpuUsage          int64
+		memory, memoryLimit, memoryUsage int64
+	}
+	total := &counters{}
+	components := map[string]*counters{}
+	podComponents := map[string]string{}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		component := objectComponent(pod)
+		podComponents[pod.Name] = component
+
+		counted := []*counters{total}
+		if component != "" {
+			if components[component] == nil {
+				components[component] = &counters{}
+			}
+			components[component].replicas++
+			counted = append(counted, components[component])
+		}
+		for _, c := range counted {
+			addResources(pod, &c.cpu, &c.cpuLimit, &c.memory, &c.memoryLimit)
+		}
+	}
+
+	for _, metric := range podMetrics {
+		component, ok := podComponents[metric.Name]
+		if !ok {
+			continue
+		}
+		counted := []*counters{total}
+		if component != "" {
+			counted = append(counted, components[component])
+		}
+		for _, container := range metric.Containers {
+			for _, c := range counted {
+				c.cpuUsage += container.Usage.Cpu().MilliValue()
+				c.memoryUsage += container.Usage.Memory().Value()
+			}
+		}
+	}
+
+	instance.Cpu = total.cpu
+	instance.CpuLimit = total.cpuLimit
+	instance.CpuUsage = total.cpuUsage
+	instance.Memory = total.memory / mebibyte
+	instance.MemoryLimit = total.memoryLimit / mebibyte
+	instance.MemoryUsage = total.memoryUsage / mebibyte
+
+	instance.Components = []api.ComponentResources{}
+	for _, component := range nebulaComponents {
+		c, ok := components[component]
+		if !ok {
+			continue
+		}
+		instance.Components = append(instance.Components, api.ComponentResources{
+			Component:   component,
+			Replicas:    c.replicas,
+			Cpu:         c.cpu,
+			CpuLimit:    c.cpuLimit,
+			CpuUsage:    c.cpuUsage,
+			Memory:      c.memory / mebibyte,
+			MemoryLimit: c.memoryLimit / mebibyte,
+			MemoryUsage: c.memoryUsage / mebibyte,
+		})
+	}
+}
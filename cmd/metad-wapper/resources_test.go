@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1api "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestCountResources(t *testing.T) {
+	resources := func(cpu, memory string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)}
+	}
+	pod := func(name, component string, phase corev1.PodPhase, requests, limits corev1.ResourceList) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{instances.componentLabel: component}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits}},
+			}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	usage := func(name string, containers ...corev1.ResourceList) metricsv1beta1api.PodMetrics {
+		metric := metricsv1beta1api.PodMetrics{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, usage := range containers {
+			metric.Containers = append(metric.Containers, metricsv1beta1api.ContainerMetrics{Usage: usage})
+		}
+		return metric
+	}
+
+	tests := []struct {
+		name       string
+		pods       []corev1.Pod
+		podMetrics []metricsv1beta1api.PodMetrics
+		want       api.Instance
+	}{
+		{
+			name: "components",
+			pods: []corev1.Pod{
+				pod("metad-0", "metad", corev1.PodRunning, resources("500m", "1Gi"), resources("1", "2Gi")),
+				pod("storaged-0", "storaged", corev1.PodRunning, resources("1", "2Gi"), resources("2", "4Gi")),
+				pod("storaged-1", "storaged", corev1.PodPending, resources("1", "2Gi"), resources("2", "4Gi")),
+				// Done running, so holding nothing.
+				pod("storaged-2", "storaged", corev1.PodFailed, resources("1", "2Gi"), resources("2", "4Gi")),
+				// Counted for the instance, though of no component; without limits.
+				pod("exporter", "exporter", corev1.PodRunning, resources("100m", "128Mi"), nil),
+			},
+			podMetrics: []metricsv1beta1api.PodMetrics{
+				usage("metad-0", resources("100m", "256Mi")),
+				usage("storaged-0", resources("150m", "256Mi"), resources("50m", "256Mi")),
+				usage("storaged-1", resources("200m", "512Mi")),
+				usage("storaged-2", resources("1", "1Gi")),
+				usage("exporter", resources("10m", "64Mi")),
+				// A pod gone since its pods were listed.
+				usage("graphd-0", resources("1", "1Gi")),
+			},
+			want: api.Instance{
+				Cpu: 2600, CpuLimit: 5000, CpuUsage: 510,
+				Memory: 5248, MemoryLimit: 10240, MemoryUsage: 1344,
+				Components: []api.ComponentResources{
+					{Component: "metad", Replicas: 1, Cpu: 500, CpuLimit: 1000, CpuUsage: 100,
+						Memory: 1024, MemoryLimit: 2048, MemoryUsage: 256},
+					{Component: "storaged", Replicas: 2, Cpu: 2000, CpuLimit: 4000, CpuUsage: 400,
+						Memory: 4096, MemoryLimit: 8192, MemoryUsage: 1024},
+				},
+			},
+		},
+		{
+			name: "no metrics",
+			pods: []corev1.Pod{
+				pod("graphd-0", "graphd", corev1.PodRunning, resources("1", "1Gi"), resources("1", "1Gi")),
+			},
+			want: api.Instance{
+				Cpu: 1000, CpuLimit: 1000, Memory: 1024, MemoryLimit: 1024,
+				Components: []api.ComponentResources{
+					{Component: "graphd", Replicas: 1, Cpu: 1000, CpuLimit: 1000, Memory: 1024, MemoryLimit: 1024},
+				},
+			},
+		},
+		{
+			name: "no pods",
+			want: api.Instance{Components: []api.ComponentResources{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := api.Instance{}
+			countResources(&got, test.pods, test.podMetrics)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("countResources = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+)
+
+// serve runs server until it fails or the process is asked to stop with
+// SIGTERM or SIGINT. It then stops accepting connections and waits up to
+// timeout for the requests in flight to finish, so a multi-step operation
+// such as changeGod is not cut in half.
+func serve(server *http.Server, timeout time.Duration) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	logging.Log.Info("Listening", "address", listener.Addr().String())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		logging.Log.Info("Shutting down", "signal", sig.String(), "timeout", timeout.Seconds())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("requests still in flight after %v: %v", timeout, err)
+	}
+	logging.Log.Info("Shut down")
+	return nil
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// snapshotNameLayout is how metad names snapshots after the time they are
+// taken, in the time zone of metad, which is taken to be UTC.
+const snapshotNameLayout = "SNAPSHOT_2006_01_02_15_04_05"
+
+// retentionCaller is the caller the audit trail records for the snapshots
+// the retention scheduler drops.
+const retentionCaller = "snapshot-retention"
+
+// The retention policy of every instance that has one is kept in the shared
+// state, so every replica reports and enforces the same.
+
+const snapshotPolicyPrefix = "snapshot-policy."
+
+func snapshotPolicy(ctx context.Context, instance string) (api.SnapshotPolicy, error) {
+	policy := api.SnapshotPolicy{}
+	_, err := sharedState.get(ctx, snapshotPolicyPrefix+instance, &policy)
+	return policy, err
+}
+
+// setSnapshotPolicy changes the policy of instance, a zero one removing it.
+func setSnapshotPolicy(ctx context.Context, instance string, policy api.SnapshotPolicy) error {
+	if policy == (api.SnapshotPolicy{}) {
+		return sharedState.set(ctx, snapshotPolicyPrefix+instance, nil)
+	}
+	return sharedState.set(ctx, snapshotPolicyPrefix+instance, policy)
+}
+
+// snapshotPolicies returns the policies of the instances that have one.
+func snapshotPolicies(ctx context.Context) (map[string]api.SnapshotPolicy, error) {
+	values, err := sharedState.list(ctx, snapshotPolicyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	policies := map[string]api.SnapshotPolicy{}
+	for instance, value := range values {
+		policy := api.SnapshotPolicy{}
+		if err := json.Unmarshal([]byte(value), &policy); err != nil {
+			return nil, fmt.Errorf("policy of %s: %v", instance, err)
+		}
+		policies[instance] = policy
+	}
+	return policies, nil
+}
+
+// snapshotTime returns when the snapshot named name was taken, false if
+// the name doesn't tell.
+func snapshotTime(name string) (time.Time, bool) {
+	t, err := time.Parse(snapshotNameLayout, name)
+	return t, err == nil
+}
+
+// listSnapshots returns the snapshots of the instance of metadClient, newest
+// first, and the code to fail with if that failed.
+func listSnapshots(metadClient *utils.Client, logger logr.Logger) ([]api.Snapshot, int) {
+	listSnapshotsResp, err := metadClient.ListSnapshots(nebula_metad.NewListSnapshotsReq())
+	if err != nil {
+		logger.Error(err, "List snapshots failed")
+		return nil, metadErrorCode(err, api.ErrInternalError)
+	}
+	if listSnapshotsResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("List snapshots failed", "metadCode", listSnapshotsResp.Code.String())
+		return nil, api.ErrInternalError
+	}
+
+	// The status of the hosts is best effort: the snapshots are listed
+	// without it if metad won't tell.
+	hostStatus := map[string]string{}
+	listHostsResp, err := metadClient.ListHosts(nebula_metad.NewListHostsReq())
+	switch {
+	case err != nil:
+		logger.Error(err, "List hosts failed")
+	case listHostsResp.Code != nebula_metad.ErrorCode_SUCCEEDED:
+		logger.Info("List hosts failed", "metadCode", listHostsResp.Code.String())
+	default:
+		for _, item := range listHostsResp.Hosts {
+			hostStatus[hostAddress(item.GetHostAddr())] = item.Status.String()
+		}
+	}
+
+	snapshots := []api.Snapshot{}
+	for _, item := range listSnapshotsResp.Snapshots {
+		snapshot := api.Snapshot{
+			Name:   item.Name,
+			Status: item.Status.String(),
+			Hosts:  []api.SnapshotHost{},
+		}
+		if t, ok := snapshotTime(item.Name); ok {
+			snapshot.CreateTime = t.Format(time.RFC3339)
+		}
+		// metad joins the hosts with commas.
+		for _, host := range strings.Split(item.Hosts, ",") {
+			if host = strings.TrimSpace(host); host == "" {
+				continue
+			}
+			status := hostStatus[host]
+			if status == "" {
+				status = nebula_metad.HostStatus_UNKNOWN.String()
+			}
+			snapshot.Hosts = append(snapshot.Hosts, api.SnapshotHost{Host: host, Status: snapshot.Status, HostStatus: status})
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	// Names carry the time, so they sort in the order snapshots are taken;
+	// those that don't go last.
+	sort.Slice(snapshots, func(i, j int) bool {
+		_, iTimed := snapshotTime(snapshots[i].Name)
+		_, jTimed := snapshotTime(snapshots[j].Name)
+		if iTimed != jTimed {
+			return iTimed
+		}
+		return snapshots[i].Name > snapshots[j].Name
+	})
+	return snapshots, 0
+}
+
+// expiredSnapshots returns the names of the snapshots, newest first, that
+// policy drops at now. Only VALID snapshots count towards KeepLast, and the
+// newest of them is kept whatever its age, so there is always one to restore
+// from. Snapshots whose names don't carry their time are left alone.
+func expiredSnapshots(snapshots []api.Snapshot, policy api.SnapshotPolicy, now time.Time) []string {
+	expired := []string{}
+	kept := 0
+	for _, snapshot := range snapshots {
+		t, ok := snapshotTime(snapshot.Name)
+		if !ok {
+			continue
+		}
+		valid := snapshot.Status == nebula_metad.SnapshotStatus_VALID.String()
+		newestValid := valid && kept == 0
+		tooMany := policy.KeepLast > 0 && kept >= policy.KeepLast
+		tooOld := policy.MaxAgeSeconds > 0 && now.Sub(t) > time.Duration(policy.MaxAgeSeconds)*time.Second
+		if !newestValid && (tooMany || tooOld) {
+			expired = append(expired, snapshot.Name)
+			continue
+		}
+		if valid {
+			kept++
+		}
+	}
+	return expired
+}
+
+// dropSnapshot drops the snapshot named name, returning the code to fail
+// with if that failed.
+func dropSnapshot(metadClient *utils.Client, logger logr.Logger, name string) int {
+	dropSnapshotReq := nebula_metad.NewDropSnapshotReq()
+	dropSnapshotReq.Name = name
+	dropSnapshotResp, err := metadClient.DropSnapshot(dropSnapshotReq)
+	if err != nil {
+		logger.Error(err, "Drop snapshot failed", "snapshot", name)
+		return metadErrorCode(err, api.ErrInternalError)
+	}
+	switch dropSnapshotResp.Code {
+	case nebula_metad.ErrorCode_SUCCEEDED:
+		return 0
+	case nebula_metad.ErrorCode_E_NOT_FOUND:
+		logger.Info("Snapshot not found", "snapshot", name)
+		return api.ErrSnapshotNotFound
+	}
+	logger.Info("Drop snapshot failed", "snapshot", name, "metadCode", dropSnapshotResp.Code.String())
+	return api.ErrInternalError
+}
+
+// enforceRetention drops the snapshots of instance its policy has expired,
+// recording each drop in the audit trail.
+func enforceRetention(ctx context.Context, instance string, policy api.SnapshotPolicy) {
+	logger := logging.Log.WithValues("instanceID", instance)
+	metadClient, err := makeMetadClient(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		return
+	}
+	defer metadClient.Close()
+
+	snapshots, code := listSnapshots(metadClient, logger)
+	if code != 0 {
+		return
+	}
+	for _, name := range expiredSnapshots(snapshots, policy, time.Now()) {
+		code := dropSnapshot(metadClient, logger, name)
+		if code == 0 {
+			logger.Info("Snapshot expired and dropped", "snapshot", name, "keepLast", policy.KeepLast, "maxAgeSeconds", policy.MaxAgeSeconds)
+		}
+		if auditLog == nil {
+			continue
+		}
+		event := &api.AuditEvent{
+			Time:       time.Now().UTC(),
+			Operation:  "DropSnapshot",
+			Caller:     retentionCaller,
+			InstanceID: instance,
+			Snapshot:   name,
+			Result:     api.AuditResultSuccess,
+			Status:     http.StatusOK,
+			Code:       code,
+		}
+		if code != 0 {
+			event.Result = api.AuditResultFailure
+			event.Status = http.StatusForbidden
+		}
+		if err := auditLog.Record(event); err != nil {
+			logger.Error(err, "Write audit event failed", "operation", event.Operation)
+		}
+	}
+}
+
+// runRetention enforces the snapshot policies every interval until stop is
+// closed, one instance after the other so metad isn't hit all at once.
+func runRetention(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		policies, err := snapshotPolicies(ctx)
+		cancel()
+		if err != nil {
+			logging.Log.Error(err, "Read snapshot policies failed")
+			continue
+		}
+		for instance, policy := range policies {
+			ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+			enforceRetention(ctx, instance, policy)
+			cancel()
+		}
+	}
+}
+
+// runElectedRetention runs the retention on the one replica holding lease
+// until ctx is done, so replicas don't drop the same snapshots, each
+// recording it in its audit trail. A replica losing the lease stands for it
+// again.
+func runElectedRetention(ctx context.Context, lease resourcelock.Interface, interval time.Duration) {
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lease,
+			LeaseDuration:   15 * time.Second,
+			RenewDeadline:   10 * time.Second,
+			RetryPeriod:     2 * time.Second,
+			ReleaseOnCancel: true,
+			Name:            "snapshot-retention",
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					logging.Log.Info("Snapshot retention lease acquired, enforcing policies", "identity", lease.Identity())
+					runRetention(interval, ctx.Done())
+				},
+				OnStoppedLeading: func() {
+					logging.Log.Info("Snapshot retention lease released", "identity", lease.Identity())
+				},
+			},
+		})
+	}
+}
+
+func CreateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	createSnapshotRequest := api.CreateSnapshotRequest{}
+	createSnapshotResponse := api.CreateSnapshotResponse{}
+	if !readRequest(w, r, &createSnapshotRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), createSnapshotRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		createSnapshotResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, createSnapshotResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	// metad doesn't tell the name of the snapshot it takes, so it is the one
+	// listed after that wasn't before.
+	before, code := listSnapshots(metadClient, logger)
+	if code != 0 {
+		createSnapshotResponse.Code = code
+		writeResponse(w, createSnapshotResponse, true)
+		return
+	}
+
+	createSnapshotResp, err := metadClient.CreateSnapshot(nebula_metad.NewCreateSnapshotReq())
+	if err != nil {
+		logger.Error(err, "Create snapshot failed")
+		createSnapshotResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, createSnapshotResponse, true)
+		return
+	}
+	if createSnapshotResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		logger.Info("Create snapshot failed", "metadCode", createSnapshotResp.Code.String())
+		createSnapshotResponse.Code = api.ErrInternalError
+		writeResponse(w, createSnapshotResponse, true)
+		return
+	}
+
+	after, code := listSnapshots(metadClient, logger)
+	if code != 0 {
+		createSnapshotResponse.Code = code
+		writeResponse(w, createSnapshotResponse, true)
+		return
+	}
+	existed := map[string]bool{}
+	for _, snapshot := range before {
+		existed[snapshot.Name] = true
+	}
+	for _, snapshot := range after {
+		if !existed[snapshot.Name] {
+			createSnapshotResponse.Snapshot = snapshot
+			break
+		}
+	}
+
+	logger.Info("Snapshot created", "snapshot", createSnapshotResponse.Snapshot.Name)
+	writeResponse(w, createSnapshotResponse, false)
+}
+
+func ListSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	snapshotRequest := api.SnapshotRequest{}
+	listSnapshotsResponse := api.ListSnapshotsResponse{}
+	if !readRequest(w, r, &snapshotRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), snapshotRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		listSnapshotsResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, listSnapshotsResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	snapshots, code := listSnapshots(metadClient, logger)
+	if code != 0 {
+		listSnapshotsResponse.Code = code
+		writeResponse(w, listSnapshotsResponse, true)
+		return
+	}
+	policy, err := snapshotPolicy(r.Context(), snapshotRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Read snapshot policy failed")
+		listSnapshotsResponse.Code = api.ErrInternalError
+		writeResponse(w, listSnapshotsResponse, true)
+		return
+	}
+	listSnapshotsResponse.Snapshots = snapshots
+	listSnapshotsResponse.Policy = policy
+	writeResponse(w, listSnapshotsResponse, false)
+}
+
+func DropSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	dropSnapshotRequest := api.DropSnapshotRequest{}
+	dropSnapshotResponse := api.DropSnapshotResponse{}
+	if !readRequest(w, r, &dropSnapshotRequest) {
+		return
+	}
+
+	metadClient, err := makeMetadClient(r.Context(), dropSnapshotRequest.InstanceID)
+	if err != nil {
+		logger.Error(err, "Create metad client failed")
+		dropSnapshotResponse.Code = metadErrorCode(err, api.ErrInternalError)
+		writeResponse(w, dropSnapshotResponse, true)
+		return
+	}
+	defer metadClient.Close()
+
+	if code := dropSnapshot(metadClient, logger, dropSnapshotRequest.Snapshot); code != 0 {
+		dropSnapshotResponse.Code = code
+		writeResponse(w, dropSnapshotResponse, true)
+		return
+	}
+	logger.Info("Snapshot dropped", "snapshot", dropSnapshotRequest.Snapshot)
+	writeResponse(w, dropSnapshotResponse, false)
+}
+
+func SnapshotPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+	setSnapshotPolicyRequest := api.SetSnapshotPolicyRequest{}
+	snapshotPolicyResponse := api.SnapshotPolicyResponse{}
+	if !readRequest(w, r, &setSnapshotPolicyRequest) {
+		return
+	}
+
+	policy := api.SnapshotPolicy{
+		KeepLast:      setSnapshotPolicyRequest.KeepLast,
+		MaxAgeSeconds: setSnapshotPolicyRequest.MaxAgeSeconds,
+	}
+	if err := setSnapshotPolicy(r.Context(), setSnapshotPolicyRequest.InstanceID, policy); err != nil {
+		logger.Error(err, "Save snapshot policy failed")
+		snapshotPolicyResponse.Code = api.ErrInternalError
+		writeResponse(w, snapshotPolicyResponse, true)
+		return
+	}
+
+	logger.Info("Snapshot policy set", "keepLast", policy.KeepLast, "maxAgeSeconds", policy.MaxAgeSeconds)
+	snapshotPolicyResponse.Policy = policy
+	writeResponse(w, snapshotPolicyResponse, false)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestExpiredSnapshots(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	// Newest first, as listSnapshots sorts them, a day apart.
+	snapshots := []api.Snapshot{
+		{Name: "SNAPSHOT_2020_05_10_00_00_00", Status: "INVALID"},
+		{Name: "SNAPSHOT_2020_05_09_00_00_00", Status: "VALID"},
+		{Name: "SNAPSHOT_2020_05_08_00_00_00", Status: "INVALID"},
+		{Name: "SNAPSHOT_2020_05_07_00_00_00", Status: "VALID"},
+		{Name: "SNAPSHOT_2020_05_06_00_00_00", Status: "VALID"},
+		{Name: "manual", Status: "VALID"},
+	}
+	day := int64(24 * 60 * 60)
+
+	tests := []struct {
+		name    string
+		policy  api.SnapshotPolicy
+		expired []string
+	}{
+		{"no policy", api.SnapshotPolicy{}, []string{}},
+		{"keep last counts valid only", api.SnapshotPolicy{KeepLast: 2},
+			[]string{"SNAPSHOT_2020_05_06_00_00_00"}},
+		{"keep last one", api.SnapshotPolicy{KeepLast: 1},
+			[]string{"SNAPSHOT_2020_05_08_00_00_00", "SNAPSHOT_2020_05_07_00_00_00", "SNAPSHOT_2020_05_06_00_00_00"}},
+		{"max age", api.SnapshotPolicy{MaxAgeSeconds: 2 * day},
+			[]string{"SNAPSHOT_2020_05_08_00_00_00", "SNAPSHOT_2020_05_07_00_00_00", "SNAPSHOT_2020_05_06_00_00_00"}},
+		// Every snapshot is too old, but the newest valid one is kept.
+		{"max age keeps the newest valid", api.SnapshotPolicy{MaxAgeSeconds: 60},
+			[]string{"SNAPSHOT_2020_05_10_00_00_00", "SNAPSHOT_2020_05_08_00_00_00", "SNAPSHOT_2020_05_07_00_00_00", "SNAPSHOT_2020_05_06_00_00_00"}},
+		{"both", api.SnapshotPolicy{KeepLast: 3, MaxAgeSeconds: 4 * day},
+			[]string{"SNAPSHOT_2020_05_06_00_00_00"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if expired := expiredSnapshots(snapshots, test.policy, now); !reflect.DeepEqual(expired, test.expired) {
+				t.Errorf("expiredSnapshots() = %v, want %v", expired, test.expired)
+			}
+		})
+	}
+}
+
+func TestExpiredSnapshotsNoneValid(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	snapshots := []api.Snapshot{
+		{Name: "SNAPSHOT_2020_05_10_00_00_00", Status: "INVALID"},
+		{Name: "SNAPSHOT_2020_05_09_00_00_00", Status: "INVALID"},
+	}
+	// With no valid snapshot to count, KeepLast drops nothing.
+	if expired := expiredSnapshots(snapshots, api.SnapshotPolicy{KeepLast: 1}, now); len(expired) != 0 {
+		t.Errorf("expiredSnapshots() = %v, want none", expired)
+	}
+}
+
+func TestSnapshotPolicies(t *testing.T) {
+	ctx := context.Background()
+	defer func(s *stateStore) { sharedState = s }(sharedState)
+	sharedState = newStateStore(newFakeConfigMaps(), "state")
+
+	policy := api.SnapshotPolicy{KeepLast: 3}
+	if err := setSnapshotPolicy(ctx, "nebula", policy); err != nil {
+		t.Fatal(err)
+	}
+	if err := setSnapshotPolicy(ctx, "other", api.SnapshotPolicy{MaxAgeSeconds: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setSnapshotPolicy(ctx, "other", api.SnapshotPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := snapshotPolicy(ctx, "nebula"); err != nil || got != policy {
+		t.Errorf("snapshotPolicy() = %+v, %v, want %+v", got, err, policy)
+	}
+	policies, err := snapshotPolicies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]api.SnapshotPolicy{"nebula": policy}; !reflect.DeepEqual(policies, want) {
+		t.Errorf("snapshotPolicies() = %v, want %v", policies, want)
+	}
+}
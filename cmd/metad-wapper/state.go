@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// sharedState holds what every replica of the wrapper must agree on, each
+// value JSON under a key of a ConfigMap. It is read from the API server on
+// every get, so a replica sees what another wrote, and survives restarts.
+// It is set up in main from -state-configmap; until then, as in tests, the
+// values are kept in memory.
+var sharedState = newStateStore(nil, "")
+
+type stateStore struct {
+	configMaps typedcorev1.ConfigMapInterface
+	name       string
+
+	mu     sync.Mutex
+	memory map[string]string
+}
+
+func newStateStore(configMaps typedcorev1.ConfigMapInterface, name string) *stateStore {
+	return &stateStore{configMaps: configMaps, name: name, memory: map[string]string{}}
+}
+
+// get reads the value of key into v, reporting whether there is one.
+func (s *stateStore) get(ctx context.Context, key string, v interface{}) (bool, error) {
+	data, ok, err := s.read(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), v)
+}
+
+// set changes the value of key to v, nil removing it.
+func (s *stateStore) set(ctx context.Context, key string, v interface{}) error {
+	data := ""
+	if v != nil {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+	return s.write(ctx, key, data, v != nil)
+}
+
+// list returns the values of the keys starting with prefix, by the rest of
+// the key, as JSON.
+func (s *stateStore) list(ctx context.Context, prefix string) (map[string]string, error) {
+	var data map[string]string
+	if s.configMaps == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		data = s.memory
+	} else {
+		configMap, err := s.configMaps.Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return map[string]string{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = configMap.Data
+	}
+
+	values := map[string]string{}
+	for key, value := range data {
+		if strings.HasPrefix(key, prefix) {
+			values[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return values, nil
+}
+
+func (s *stateStore) read(ctx context.Context, key string) (string, bool, error) {
+	if s.configMaps == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		data, ok := s.memory[key]
+		return data, ok, nil
+	}
+
+	configMap, err := s.configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	data, ok := configMap.Data[key]
+	return data, ok, nil
+}
+
+// write changes one key, leaving the others as they are. Replicas writing
+// at once conflict on the resource version, and the losers write again over
+// what the winner wrote.
+func (s *stateStore) write(ctx context.Context, key, data string, present bool) error {
+	if s.configMaps == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if present {
+			s.memory[key] = data
+		} else {
+			delete(s.memory, key)
+		}
+		return nil
+	}
+
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		configMap, err := s.configMaps.Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if !present {
+				return nil
+			}
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name},
+				Data:       map[string]string{key: data},
+			}
+			_, err = s.configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		if present {
+			configMap.Data[key] = data
+		} else if _, ok := configMap.Data[key]; ok {
+			delete(configMap.Data, key)
+		} else {
+			return nil
+		}
+		_, err = s.configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+var configMapsResource = schema.GroupResource{Resource: "configmaps"}
+
+// fakeConfigMaps keeps ConfigMaps as the API server would, refusing updates
+// made over a stale resource version.
+type fakeConfigMaps struct {
+	typedcorev1.ConfigMapInterface
+
+	mu         sync.Mutex
+	configMaps map[string]*corev1.ConfigMap
+	version    int
+	// conflicts fails that many updates with a conflict, as if another
+	// replica wrote first.
+	conflicts int
+}
+
+func newFakeConfigMaps() *fakeConfigMaps {
+	return &fakeConfigMaps{configMaps: map[string]*corev1.ConfigMap{}}
+}
+
+func (f *fakeConfigMaps) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.ConfigMap, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	configMap, ok := f.configMaps[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(configMapsResource, name)
+	}
+	return configMap.DeepCopy(), nil
+}
+
+func (f *fakeConfigMaps) Create(ctx context.Context, configMap *corev1.ConfigMap, opts metav1.CreateOptions) (*corev1.ConfigMap, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.configMaps[configMap.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(configMapsResource, configMap.Name)
+	}
+	return f.store(configMap), nil
+}
+
+func (f *fakeConfigMaps) Update(ctx context.Context, configMap *corev1.ConfigMap, opts metav1.UpdateOptions) (*corev1.ConfigMap, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, ok := f.configMaps[configMap.Name]
+	if !ok {
+		return nil, apierrors.NewNotFound(configMapsResource, configMap.Name)
+	}
+	if f.conflicts > 0 || current.ResourceVersion != configMap.ResourceVersion {
+		f.conflicts--
+		return nil, apierrors.NewConflict(configMapsResource, configMap.Name, nil)
+	}
+	return f.store(configMap), nil
+}
+
+func (f *fakeConfigMaps) store(configMap *corev1.ConfigMap) *corev1.ConfigMap {
+	f.version++
+	stored := configMap.DeepCopy()
+	stored.ResourceVersion = strconv.Itoa(f.version)
+	f.configMaps[stored.Name] = stored
+	return stored.DeepCopy()
+}
+
+func TestStateStoreSharedByReplicas(t *testing.T) {
+	ctx := context.Background()
+	configMaps := newFakeConfigMaps()
+	one := newStateStore(configMaps, "state")
+	other := newStateStore(configMaps, "state")
+
+	var id int64
+	if ok, err := one.get(ctx, "plan", &id); ok || err != nil {
+		t.Fatalf("get before the ConfigMap exists = %v, %v, want false, nil", ok, err)
+	}
+
+	if err := one.set(ctx, "plan", int64(7)); err != nil {
+		t.Fatal(err)
+	}
+	configMaps.conflicts = 2
+	if err := other.set(ctx, "other", "value"); err != nil {
+		t.Fatalf("set through conflicts: %v", err)
+	}
+	if ok, err := other.get(ctx, "plan", &id); !ok || err != nil || id != 7 {
+		t.Errorf("get from another replica = %d, %v, %v, want 7, true, nil", id, ok, err)
+	}
+
+	if err := other.set(ctx, "plan", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := one.get(ctx, "plan", &id); ok || err != nil {
+		t.Errorf("get after removal = %v, %v, want false, nil", ok, err)
+	}
+
+	data := configMaps.configMaps["state"].Data
+	if len(data) != 1 || data["other"] != `"value"` {
+		t.Errorf("ConfigMap data = %v, want only other", data)
+	}
+}
+
+func TestStateStoreInMemory(t *testing.T) {
+	ctx := context.Background()
+	s := newStateStore(nil, "")
+	if err := s.set(ctx, "plan", int64(3)); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if ok, err := s.get(ctx, "plan", &id); !ok || err != nil || id != 3 {
+		t.Errorf("get = %d, %v, %v, want 3, true, nil", id, ok, err)
+	}
+	if err := s.set(ctx, "plan", nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.get(ctx, "plan", &id); ok {
+		t.Error("get after removal found a value")
+	}
+}
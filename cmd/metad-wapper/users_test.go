@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+	"github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+func TestUserHandlersMetadUnavailable(t *testing.T) {
+	fake := &fakeMetad{}
+	startFakeMetad(t, fake)
+	utils.SetBreakerPolicy(utils.BreakerPolicy{Threshold: 1, Cooldown: time.Hour})
+	defer utils.SetBreakerPolicy(utils.BreakerPolicy{})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		drop    string
+		code    int
+	}{
+		// Reads are retried, finding the breaker opened by the first try.
+		{"list space users", ListSpaceUsersHandler,
+			`{"InstanceID": "list-space", "SpaceName": "nba", "Operator": "root"}`, "ListUsers", api.ErrInstanceUnavailable},
+		{"list root users", ListRootSpaceUsersHandler,
+			`{"InstanceID": "list-root"}`, "ListUsers", api.ErrInstanceUnavailable},
+		// Writes aren't, as metad may have done them.
+		{"create user", CreateUserHandler,
+			`{"InstanceID": "create", "UserName": "user", "Role": "USER", "SpaceName": "nba", "Account": "root"}`, "CreateUser", api.ErrInternalError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.drop = map[string]bool{test.drop: true}
+			fake.mu.Unlock()
+
+			w := httptest.NewRecorder()
+			test.handler(w, httptest.NewRequest("POST", "/", strings.NewReader(test.body)))
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+			if code := responseCode(w.Body.Bytes()); code != test.code {
+				t.Errorf("code = %d, want %d", code, test.code)
+			}
+
+			// The breaker is open now, failing the next request at once.
+			w = httptest.NewRecorder()
+			test.handler(w, httptest.NewRequest("POST", "/", strings.NewReader(test.body)))
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status with the breaker open = %d, want %d", w.Code, http.StatusForbidden)
+			}
+			if code := responseCode(w.Body.Bytes()); code != api.ErrInstanceUnavailable {
+				t.Errorf("code with the breaker open = %d, want %d", code, api.ErrInstanceUnavailable)
+			}
+		})
+	}
+}
+
+func TestLeaderChanged(t *testing.T) {
+	f := &fakeMetad{leaderChanges: map[string]int{"ListUsers": 1}}
+	startFakeMetad(t, f)
+
+	ctx := context.Background()
+	c, err := utils.Dial(ctx, "leader-changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.ListUsers(nebula_metad.NewListUsersReq())
+	c.Close()
+	if err != nil || resp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		t.Fatalf("ListUsers = %v, %v, want success after the leader changed", resp, err)
+	}
+	// The retry went to the leader named rather than over the connection to
+	// the former one.
+	if got := f.accepted(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+
+	// The connection to the former leader wasn't pooled: the next operation
+	// reuses the one to the leader.
+	c, err = utils.Dial(ctx, "leader-changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if resp, err := c.ListUsers(nebula_metad.NewListUsersReq()); err != nil || resp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		t.Fatalf("ListUsers = %v, %v, want success", resp, err)
+	}
+	if got := f.accepted(); got != 2 {
+		t.Errorf("connections = %d, want still 2", got)
+	}
+}
+
+func TestListSpaceHandler(t *testing.T) {
+	space := func(id nebula.GraphSpaceID, name string) *nebula_metad.IdName {
+		return &nebula_metad.IdName{Id: &nebula_metad.ID{SpaceID: &id}, Name: name}
+	}
+	fake := &fakeMetad{
+		roles: map[string][]*nebula.RoleItem{
+			"root": {{User: "root", SpaceID: 0, RoleType: nebula.RoleType_GOD}},
+			"user": {
+				{User: "user", SpaceID: 1, RoleType: nebula.RoleType_ADMIN},
+				{User: "user", SpaceID: 3, RoleType: nebula.RoleType_GUEST},
+				// In a space dropped since.
+				{User: "user", SpaceID: 4, RoleType: nebula.RoleType_ADMIN},
+			},
+		},
+		spaces: []*nebula_metad.IdName{space(1, "nba"), space(2, "nbl"), space(3, "cba")},
+	}
+	startFakeMetad(t, fake)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantSpaces []string
+	}{
+		{"spaces of the user", `{"InstanceID": "list-spaces", "UserName": "user"}`, []string{"cba", "nba"}},
+		{"role", `{"InstanceID": "list-spaces", "UserName": "user", "Role": "ADMIN"}`, []string{"nba"}},
+		{"prefix", `{"InstanceID": "list-spaces", "UserName": "user", "Prefix": "c"}`, []string{"cba"}},
+		{"god", `{"InstanceID": "list-spaces", "UserName": "root"}`, []string{"cba", "nba", "nbl"}},
+		{"user not found", `{"InstanceID": "list-spaces", "UserName": "nobody"}`, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ListSpaceHandler(w, httptest.NewRequest("POST", "/", strings.NewReader(test.body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			resp := api.ListSpaceResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp.Spaces, test.wantSpaces) || resp.Total != len(test.wantSpaces) {
+				t.Errorf("spaces = %v of %d, want %v", resp.Spaces, resp.Total, test.wantSpaces)
+			}
+		})
+	}
+
+	// The roles of the user not read fail the request, rather than leaving
+	// spaces out.
+	fake.mu.Lock()
+	fake.drop = map[string]bool{"GetUserRoles": true}
+	fake.mu.Unlock()
+	w := httptest.NewRecorder()
+	ListSpaceHandler(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"InstanceID": "list-spaces-failed", "UserName": "user"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if code := responseCode(w.Body.Bytes()); code != api.ErrInternalError {
+		t.Errorf("code = %d, want %d", code, api.ErrInternalError)
+	}
+}
+
+func TestChangeGod(t *testing.T) {
+	fake := &fakeMetad{}
+	startFakeMetad(t, fake)
+
+	tests := []struct {
+		name          string
+		body          string
+		listUsersCode nebula_metad.ErrorCode
+		dropUserCodes map[string]nebula_metad.ErrorCode
+		wantStatus    int
+		wantCode      int
+	}{
+		{"transferred", `{"InstanceID": "change-god", "UserName": "admin", "OldName": "god"}`,
+			0, nil, http.StatusOK, 0},
+		// The users not listed, whether one holds the new name is unknown.
+		{"users not listed", `{"InstanceID": "change-god", "UserName": "admin", "OldName": "god"}`,
+			nebula_metad.ErrorCode_E_STORE_FAILURE, nil, http.StatusForbidden, api.ErrInternalError},
+		// Retried once the old GOD was dropped.
+		{"old GOD gone", `{"InstanceID": "change-god", "UserName": "admin", "OldName": "god"}`,
+			0, map[string]nebula_metad.ErrorCode{"god": nebula_metad.ErrorCode_E_NOT_FOUND}, http.StatusOK, 0},
+		{"old GOD not dropped", `{"InstanceID": "change-god", "UserName": "admin", "OldName": "god"}`,
+			0, map[string]nebula_metad.ErrorCode{"god": nebula_metad.ErrorCode_E_STORE_FAILURE}, http.StatusForbidden, api.ErrDropUserFailed},
+		// The new name is taken by root, which is dropped first.
+		{"user of the name not dropped", `{"InstanceID": "change-god", "UserName": "root", "OldName": "god"}`,
+			0, map[string]nebula_metad.ErrorCode{"root": nebula_metad.ErrorCode_E_STORE_FAILURE}, http.StatusForbidden, api.ErrDropUserFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.listUsersCode = test.listUsersCode
+			fake.dropUserCodes = test.dropUserCodes
+			fake.mu.Unlock()
+
+			w := httptest.NewRecorder()
+			changeGod(w, httptest.NewRequest("POST", "/", strings.NewReader(test.body)))
+			if w.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, test.wantStatus)
+			}
+			if code := responseCode(w.Body.Bytes()); code != test.wantCode {
+				t.Errorf("code = %d, want %d", code, test.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInstanceUnavailable is wrapped by the errors of operations failed fast
+// because the metad of their instance kept failing and is taken to be down.
+var ErrInstanceUnavailable = errors.New("metad unavailable")
+
+// BreakerPolicy says when the circuit breaker of an instance opens: after
+// Threshold transient failures in a row, for Cooldown. Once Cooldown is
+// over a single operation is let through to probe metad; it closes the
+// breaker if it succeeds and opens it again if not.
+type BreakerPolicy struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+var breakerPolicy = BreakerPolicy{Threshold: 5, Cooldown: 30 * time.Second}
+
+// SetBreakerPolicy changes when the circuit breakers open. A zero Threshold
+// disables them.
+func SetBreakerPolicy(p BreakerPolicy) {
+	breakers.mu.Lock()
+	defer breakers.mu.Unlock()
+	breakerPolicy = p
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	// probing is set while the operation let through after the cooldown is
+	// in flight, until probeUntil.
+	probing    bool
+	probeUntil time.Time
+}
+
+type breakerSet struct {
+	mu       sync.Mutex
+	instance map[string]*breaker
+}
+
+var breakers = &breakerSet{instance: map[string]*breaker{}}
+
+func (s *breakerSet) get(instance string) *breaker {
+	b, ok := s.instance[instance]
+	if !ok {
+		b = &breaker{}
+		s.instance[instance] = b
+	}
+	return b
+}
+
+// allow reports whether an operation on instance may go ahead, or the error
+// to fail it with.
+func (s *breakerSet) allow(instance string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if breakerPolicy.Threshold <= 0 {
+		return nil
+	}
+	b := s.get(instance)
+	now := time.Now()
+	if b.failures < breakerPolicy.Threshold {
+		return nil
+	}
+	if now.Before(b.openUntil) || (b.probing && now.Before(b.probeUntil)) {
+		observer.MetadRejected(instance)
+		retryAt := b.openUntil
+		if b.probing {
+			retryAt = b.probeUntil
+		}
+		return fmt.Errorf("instance %s: %w, retry in %s", instance, ErrInstanceUnavailable, retryAt.Sub(now).Round(time.Second))
+	}
+	// A probe that never reported back doesn't keep the breaker half open.
+	b.probing = true
+	b.probeUntil = now.Add(breakerPolicy.Cooldown)
+	return nil
+}
+
+// success records metad of instance answering, closing its breaker.
+func (s *breakerSet) success(instance string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.get(instance)
+	if b.failures >= breakerPolicy.Threshold && breakerPolicy.Threshold > 0 {
+		observer.MetadBreaker(instance, false)
+		log().Info("Metad circuit breaker closed", "instanceID", instance)
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+// failure records a transient failure of metad of instance, opening its
+// breaker once there were too many in a row.
+func (s *breakerSet) failure(instance string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.get(instance)
+	b.failures++
+	b.probing = false
+	if breakerPolicy.Threshold > 0 && b.failures >= breakerPolicy.Threshold {
+		if b.failures == breakerPolicy.Threshold {
+			observer.MetadBreaker(instance, true)
+			log().Error(err, "Metad circuit breaker opened", "instanceID", instance, "failures", b.failures, "cooldown", breakerPolicy.Cooldown.Seconds())
+		}
+		b.openUntil = time.Now().Add(breakerPolicy.Cooldown)
+	}
+}
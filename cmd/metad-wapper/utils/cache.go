@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// CacheTTL says how long metadata read from metad is served from the cache:
+// the list of spaces and each space, the list of users, and the roles of
+// each user. A zero TTL disables caching of that kind.
+//
+// The cache is dropped for whatever the wrapper itself changes, so it
+// serves its own writes at once; changes made to metad elsewhere, such as
+// through another replica of the wrapper, show once the TTL is over.
+type CacheTTL struct {
+	Spaces time.Duration
+	Users  time.Duration
+	Roles  time.Duration
+}
+
+var cacheTTL = CacheTTL{Spaces: 30 * time.Second, Users: 10 * time.Second, Roles: 10 * time.Second}
+
+// SetCacheTTL changes how long metadata is cached, and empties the cache.
+func SetCacheTTL(ttl CacheTTL) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cacheTTL = ttl
+	cache.entries = map[cacheKey]cacheEntry{}
+}
+
+const (
+	cacheSpaces = "spaces"
+	cacheSpace  = "space"
+	cacheUsers  = "users"
+	cacheRoles  = "roles"
+)
+
+func (ttl CacheTTL) of(kind string) time.Duration {
+	switch kind {
+	case cacheSpaces, cacheSpace:
+		return ttl.Spaces
+	case cacheUsers:
+		return ttl.Users
+	case cacheRoles:
+		return ttl.Roles
+	}
+	return 0
+}
+
+// cacheKey names a cached response: the spaces or users of an instance, or
+// a space or the roles of a user, by name.
+type cacheKey struct {
+	instance string
+	kind     string
+	name     string
+}
+
+type cacheEntry struct {
+	resp    coded
+	expires time.Time
+}
+
+type metaCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+	// generation counts the invalidations, so a read that raced with a
+	// write doesn't put back what the write made stale.
+	generation uint64
+}
+
+var cache = &metaCache{entries: map[cacheKey]cacheEntry{}}
+
+// get returns the response cached under key, if any, and the generation to
+// put a fresh one with.
+func (m *metaCache) get(key cacheKey) (coded, bool, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, m.generation
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, m.generation
+	}
+	return entry.resp, true, m.generation
+}
+
+func (m *metaCache) put(key cacheKey, resp coded, generation uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ttl := cacheTTL.of(key.kind)
+	if ttl <= 0 || generation != m.generation {
+		return
+	}
+	m.entries[key] = cacheEntry{resp: resp, expires: time.Now().Add(ttl)}
+}
+
+// invalidate drops the cached responses under keys.
+func (m *metaCache) invalidate(keys ...cacheKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generation++
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns ctx telling the operations run with it to read
+// metad rather than the cache. What they read still refreshes the cache.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// cached answers a read of key from the cache, or with fetch, caching what
+// it returns if metad succeeded. Responses are shared between callers, who
+// must not change them.
+func (c *Client) cached(key cacheKey, fetch func() (coded, error)) (coded, error) {
+	cachedResp, ok, generation := cache.get(key)
+	result := "miss"
+	if cacheBypassed(c.ctx) {
+		result = "bypass"
+	} else if ok {
+		observer.CacheLookup(c.instance, key.kind, "hit")
+		return cachedResp, nil
+	}
+	observer.CacheLookup(c.instance, key.kind, result)
+
+	resp, err := fetch()
+	if err == nil && resp != nil && resp.GetCode() == nebula_metad.ErrorCode_SUCCEEDED {
+		cache.put(key, resp, generation)
+	}
+	return resp, err
+}
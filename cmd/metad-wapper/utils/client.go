@@ -0,0 +1,403 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
+	"github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// RetryPolicy says how an RPC failing transiently is retried: up to
+// Attempts times in all, waiting an exponential backoff from BaseDelay up
+// to MaxDelay, with jitter, between attempts.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var retryPolicy = RetryPolicy{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// SetRetryPolicy changes how RPCs are retried. Attempts of 1 or less
+// disables retries.
+func SetRetryPolicy(p RetryPolicy) {
+	retryPolicy = p
+}
+
+// backoff returns how long to wait before the attempt-th retry: the
+// exponential delay, less a random part of up to half of it so callers
+// failing together don't retry together.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// connectError is returned when metad could not be connected to, so no
+// request reached it.
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return e.err.Error() }
+func (e *connectError) Unwrap() error { return e.err }
+
+// transient reports whether err is a failure to reach metad or to hear
+// back from it, which another attempt may not meet, as opposed to the
+// operation being cancelled or metad rejecting it.
+func transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrInstanceUnavailable) {
+		return false
+	}
+	var connErr *connectError
+	if errors.As(err, &connErr) {
+		return true
+	}
+	switch err.(type) {
+	case thrift.TransportException, thrift.ProtocolException:
+		// Reading a reply off a broken connection fails either way.
+		return true
+	}
+	return false
+}
+
+// coded is a metad response, which all carry an error code.
+type coded interface {
+	GetCode() nebula_metad.ErrorCode
+}
+
+// led is a metad response naming the leader, which E_LEADER_CHANGED
+// responses set.
+type led interface {
+	GetLeader() *nebula.HostAddr
+}
+
+// Client makes RPCs to the metad of one instance. An RPC failing
+// transiently is retried on a new connection: reads whatever the failure,
+// writes only if metad cannot have received them, that is when connecting
+// failed or metad answered that its leader changed. While the circuit
+// breaker of the instance is open, RPCs fail fast with an error wrapping
+// ErrInstanceUnavailable.
+type Client struct {
+	ctx      context.Context
+	instance string
+	conn     *nebula_metad.MetaServiceClient
+	// leader is the address of the metad the next connection is made to,
+	// as named by the last E_LEADER_CHANGED; empty to go through the
+	// Service.
+	leader string
+}
+
+// Dial returns a Client of the metad of instance ns, connected, for the
+// RPCs of an operation bound by ctx. The caller closes it when done.
+func Dial(ctx context.Context, ns string) (*Client, error) {
+	c := &Client{ctx: ctx, instance: ns}
+	if _, err := c.do("connect", false, nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close hands the connection back to the idle pool.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Transport.Close()
+	c.conn = nil
+	return err
+}
+
+// do runs call, retrying it per the retry policy; with a nil call it only
+// connects. write is set for RPCs that change metad's state.
+func (c *Client) do(method string, write bool, call func(*nebula_metad.MetaServiceClient) (coded, error)) (coded, error) {
+	var resp coded
+	var err error
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			observer.MetadRetried(c.instance, method)
+			timer := time.NewTimer(retryPolicy.backoff(attempt - 1))
+			select {
+			case <-c.ctx.Done():
+				timer.Stop()
+				return nil, c.ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		resp, err = c.attempt(call)
+		retry := false
+		switch {
+		case err == nil:
+			retry = resp != nil && resp.GetCode() == nebula_metad.ErrorCode_E_LEADER_CHANGED
+			if retry {
+				c.leaderChanged(resp)
+			}
+		case transient(err):
+			var connErr *connectError
+			retry = !write || errors.As(err, &connErr)
+		}
+		if !retry || attempt >= retryPolicy.Attempts {
+			return resp, err
+		}
+		log().V(1).Info("Retry metad RPC", "instanceID", c.instance, "method", method, "attempt", attempt, "error", errorString(err, resp))
+	}
+}
+
+// attempt makes one try at call, connecting first if need be, and reports
+// the outcome to the circuit breaker.
+func (c *Client) attempt(call func(*nebula_metad.MetaServiceClient) (coded, error)) (coded, error) {
+	if err := breakers.allow(c.instance); err != nil {
+		return nil, err
+	}
+
+	if c.conn == nil {
+		conn, err := dialMetad(c.ctx, c.instance, c.leader)
+		if err != nil {
+			if transient(err) {
+				breakers.failure(c.instance, err)
+			}
+			// The leader named may be gone too; the Service knows better.
+			c.leader = ""
+			return nil, err
+		}
+		c.conn = conn
+	}
+	if call == nil {
+		breakers.success(c.instance)
+		return nil, nil
+	}
+
+	resp, err := call(c.conn)
+	if err != nil {
+		if transient(err) {
+			breakers.failure(c.instance, err)
+		}
+		// The connection may be out of step with metad; closing it drops it
+		// rather than pooling it.
+		c.Close()
+		return nil, err
+	}
+	breakers.success(c.instance)
+	return resp, nil
+}
+
+// leaderChanged drops the connection to a metad that is no longer the
+// leader, and the idle ones to the instance, which may lead to it as well.
+// The next attempt connects to the leader resp names, or else through the
+// Service again.
+func (c *Client) leaderChanged(resp coded) {
+	c.leader = ""
+	if r, ok := resp.(led); ok {
+		if addr := r.GetLeader(); addr != nil && addr.GetIp() != 0 && addr.GetPort() != 0 {
+			c.leader = formatHostAddr(addr)
+		}
+	}
+	if c.conn != nil {
+		if t, ok := c.conn.Transport.(*metadTransport); ok {
+			t.call.failed = true
+		}
+		c.Close()
+	}
+	idle.drop(c.instance)
+}
+
+// formatHostAddr formats a metad host address as ip:port.
+func formatHostAddr(addr *nebula.HostAddr) string {
+	ip := uint32(addr.GetIp())
+	ipString := net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+	return net.JoinHostPort(ipString, strconv.Itoa(int(addr.GetPort())))
+}
+
+func errorString(err error, resp coded) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.GetCode().String()
+}
+
+func (c *Client) ListSpaces(req *nebula_metad.ListSpacesReq) (*nebula_metad.ListSpacesResp, error) {
+	resp, err := c.cached(cacheKey{c.instance, cacheSpaces, ""}, func() (coded, error) {
+		return c.do("listSpaces", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+			return conn.ListSpaces(req)
+		})
+	})
+	r, _ := resp.(*nebula_metad.ListSpacesResp)
+	return r, err
+}
+
+func (c *Client) GetSpace(req *nebula_metad.GetSpaceReq) (*nebula_metad.GetSpaceResp, error) {
+	resp, err := c.cached(cacheKey{c.instance, cacheSpace, req.SpaceName}, func() (coded, error) {
+		return c.do("getSpace", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+			return conn.GetSpace(req)
+		})
+	})
+	r, _ := resp.(*nebula_metad.GetSpaceResp)
+	return r, err
+}
+
+func (c *Client) ListUsers(req *nebula_metad.ListUsersReq) (*nebula_metad.ListUsersResp, error) {
+	resp, err := c.cached(cacheKey{c.instance, cacheUsers, ""}, func() (coded, error) {
+		return c.do("listUsers", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+			return conn.ListUsers(req)
+		})
+	})
+	r, _ := resp.(*nebula_metad.ListUsersResp)
+	return r, err
+}
+
+func (c *Client) GetUserRoles(req *nebula_metad.GetUserRolesReq) (*nebula_metad.ListRolesResp, error) {
+	resp, err := c.cached(cacheKey{c.instance, cacheRoles, req.Account}, func() (coded, error) {
+		return c.do("getUserRoles", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+			return conn.GetUserRoles(req)
+		})
+	})
+	r, _ := resp.(*nebula_metad.ListRolesResp)
+	return r, err
+}
+
+// ListHosts and ListParts are not cached: the state they report changes
+// with every heartbeat.
+
+func (c *Client) ListHosts(req *nebula_metad.ListHostsReq) (*nebula_metad.ListHostsResp, error) {
+	resp, err := c.do("listHosts", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.ListHosts(req)
+	})
+	r, _ := resp.(*nebula_metad.ListHostsResp)
+	return r, err
+}
+
+func (c *Client) ListParts(req *nebula_metad.ListPartsReq) (*nebula_metad.ListPartsResp, error) {
+	resp, err := c.do("listParts", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.ListParts(req)
+	})
+	r, _ := resp.(*nebula_metad.ListPartsResp)
+	return r, err
+}
+
+// Balance starts a data balance plan, stops the running one, or, with an
+// Id, reports the tasks of a plan; only the last is retried as a read.
+func (c *Client) Balance(req *nebula_metad.BalanceReq) (*nebula_metad.BalanceResp, error) {
+	resp, err := c.do("balance", req.Id == nil, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.Balance(req)
+	})
+	r, _ := resp.(*nebula_metad.BalanceResp)
+	return r, err
+}
+
+func (c *Client) LeaderBalance(req *nebula_metad.LeaderBalanceReq) (*nebula_metad.ExecResp, error) {
+	return c.exec("leaderBalance", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.LeaderBalance(req)
+	})
+}
+
+// RunAdminJob submits, lists, shows or stops admin jobs; only listing and
+// showing are retried as reads.
+func (c *Client) RunAdminJob(req *nebula_metad.AdminJobReq) (*nebula_metad.AdminJobResp, error) {
+	read := req.Op == nebula_metad.AdminJobOp_SHOW_All || req.Op == nebula_metad.AdminJobOp_SHOW
+	resp, err := c.do("runAdminJob", !read, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.RunAdminJob(req)
+	})
+	r, _ := resp.(*nebula_metad.AdminJobResp)
+	return r, err
+}
+
+func (c *Client) ListSnapshots(req *nebula_metad.ListSnapshotsReq) (*nebula_metad.ListSnapshotsResp, error) {
+	resp, err := c.do("listSnapshots", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.ListSnapshots(req)
+	})
+	r, _ := resp.(*nebula_metad.ListSnapshotsResp)
+	return r, err
+}
+
+func (c *Client) CreateSnapshot(req *nebula_metad.CreateSnapshotReq) (*nebula_metad.ExecResp, error) {
+	return c.exec("createSnapshot", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.CreateSnapshot(req)
+	})
+}
+
+func (c *Client) DropSnapshot(req *nebula_metad.DropSnapshotReq) (*nebula_metad.ExecResp, error) {
+	return c.exec("dropSnapshot", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.DropSnapshot(req)
+	})
+}
+
+func (c *Client) ListConfigs(req *nebula_metad.ListConfigsReq) (*nebula_metad.ListConfigsResp, error) {
+	resp, err := c.do("listConfigs", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.ListConfigs(req)
+	})
+	r, _ := resp.(*nebula_metad.ListConfigsResp)
+	return r, err
+}
+
+func (c *Client) GetConfig(req *nebula_metad.GetConfigReq) (*nebula_metad.GetConfigResp, error) {
+	resp, err := c.do("getConfig", false, func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.GetConfig(req)
+	})
+	r, _ := resp.(*nebula_metad.GetConfigResp)
+	return r, err
+}
+
+func (c *Client) SetConfig(req *nebula_metad.SetConfigReq) (*nebula_metad.ExecResp, error) {
+	return c.exec("setConfig", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.SetConfig(req)
+	})
+}
+
+func (c *Client) CreateSpace(req *nebula_metad.CreateSpaceReq) (*nebula_metad.ExecResp, error) {
+	defer cache.invalidate(cacheKey{c.instance, cacheSpaces, ""}, cacheKey{c.instance, cacheSpace, req.GetProperties().GetSpaceName()})
+	return c.exec("createSpace", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.CreateSpace(req)
+	})
+}
+
+func (c *Client) CreateUser(req *nebula_metad.CreateUserReq) (*nebula_metad.ExecResp, error) {
+	defer cache.invalidate(cacheKey{c.instance, cacheUsers, ""}, cacheKey{c.instance, cacheRoles, req.Account})
+	return c.exec("createUser", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.CreateUser(req)
+	})
+}
+
+func (c *Client) DropUser(req *nebula_metad.DropUserReq) (*nebula_metad.ExecResp, error) {
+	defer cache.invalidate(cacheKey{c.instance, cacheUsers, ""}, cacheKey{c.instance, cacheRoles, req.Account})
+	return c.exec("dropUser", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.DropUser(req)
+	})
+}
+
+func (c *Client) GrantRole(req *nebula_metad.GrantRoleReq) (*nebula_metad.ExecResp, error) {
+	defer cache.invalidate(cacheKey{c.instance, cacheRoles, req.GetRoleItem().GetUser()})
+	return c.exec("grantRole", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.GrantRole(req)
+	})
+}
+
+func (c *Client) RevokeRole(req *nebula_metad.RevokeRoleReq) (*nebula_metad.ExecResp, error) {
+	defer cache.invalidate(cacheKey{c.instance, cacheRoles, req.GetRoleItem().GetUser()})
+	return c.exec("revokeRole", func(conn *nebula_metad.MetaServiceClient) (coded, error) {
+		return conn.RevokeRole(req)
+	})
+}
+
+func (c *Client) exec(method string, call func(*nebula_metad.MetaServiceClient) (coded, error)) (*nebula_metad.ExecResp, error) {
+	resp, err := c.do(method, true, call)
+	r, _ := resp.(*nebula_metad.ExecResp)
+	return r, err
+}
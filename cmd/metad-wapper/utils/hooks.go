@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Observer is told what the operations do to metad, e.g. to export it as
+// metrics. Until SetObserver is called nothing is observed.
+type Observer interface {
+	// MetadCall records an RPC of method made to the metad of instance.
+	MetadCall(instance, method string, latency time.Duration, failed bool)
+	// MetadConnected records a connection to the metad of instance being
+	// opened, or failing to be when err is set.
+	MetadConnected(instance string, err error)
+	// MetadDisconnected records a connection to the metad of instance being
+	// closed.
+	MetadDisconnected(instance string)
+	// MetadIdle records delta connections to the metad of instance entering
+	// (or, negative, leaving) the idle pool.
+	MetadIdle(instance string, delta float64)
+	// MetadRetried records an RPC of method to the metad of instance being
+	// retried.
+	MetadRetried(instance, method string)
+	// MetadBreaker records the circuit breaker of instance opening or
+	// closing.
+	MetadBreaker(instance string, open bool)
+	// MetadRejected records an operation on instance failed fast by its open
+	// circuit breaker.
+	MetadRejected(instance string)
+	// CacheLookup records a read of kind from the metad of instance being
+	// answered from the cache ("hit"), fetched ("miss"), or fetched because
+	// the caller asked not to be served from the cache ("bypass").
+	CacheLookup(instance, kind, result string)
+}
+
+var observer Observer = nopObserver{}
+
+// SetObserver sets what is told of the operations on metad.
+func SetObserver(o Observer) {
+	observer = o
+}
+
+type nopObserver struct{}
+
+func (nopObserver) MetadCall(string, string, time.Duration, bool) {}
+func (nopObserver) MetadConnected(string, error)                  {}
+func (nopObserver) MetadDisconnected(string)                      {}
+func (nopObserver) MetadIdle(string, float64)                     {}
+func (nopObserver) MetadRetried(string, string)                   {}
+func (nopObserver) MetadBreaker(string, bool)                     {}
+func (nopObserver) MetadRejected(string)                          {}
+func (nopObserver) CacheLookup(string, string, string)            {}
+
+var tracer = trace.NewNoopTracerProvider().Tracer("")
+
+// SetTracer sets what traces the connections made to metad and the RPCs
+// sent over them. Until it is called nothing is traced.
+func SetTracer(t trace.Tracer) {
+	tracer = t
+}
+
+// endSpan ends span, marking it failed if err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+var logger logr.Logger = nopLogger{}
+
+// SetLogger sets where operations log to. Until it is called nothing is
+// logged.
+func SetLogger(l logr.Logger) {
+	logger = l
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string, ...interface{})             {}
+func (nopLogger) Enabled() bool                           { return false }
+func (nopLogger) Error(error, string, ...interface{})     {}
+func (l nopLogger) V(int) logr.InfoLogger                 { return l }
+func (l nopLogger) WithValues(...interface{}) logr.Logger { return l }
+func (l nopLogger) WithName(string) logr.Logger           { return l }
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentMetad wraps the open socket and protocol factory of a metad
+// client of instance so that its connection is counted and every RPC it
+// makes is measured and traced as a child of the span in ctx, and cut short
+// when ctx is done.
+func instrumentMetad(ctx context.Context, instance string, socket *thrift.Socket, factory thrift.ProtocolFactory) (*metadTransport, thrift.ProtocolFactory) {
+	call := &metadCall{ctx: ctx, instance: instance, interrupt: socket.Interrupt}
+	return &metadTransport{Transport: socket, call: call, open: true},
+		&metadProtocolFactory{ProtocolFactory: factory, call: call}
+}
+
+// metadTransport tracks whether its connection is open. Closing it hands
+// the connection to the idle pool when it is still usable.
+type metadTransport struct {
+	thrift.Transport
+	call *metadCall
+	open bool
+	// client is the metad client using the transport, handed out again when
+	// the connection is reused.
+	client    *nebula_metad.MetaServiceClient
+	idleSince time.Time
+	// released is set once the caller closed the transport, so closing it
+	// twice doesn't pool it twice.
+	released bool
+}
+
+func (t *metadTransport) Close() error {
+	if t.released {
+		return nil
+	}
+	t.released = true
+	t.call.end(errUnfinished)
+	if t.open && !t.call.failed && t.client != nil && idle.put(t) {
+		return nil
+	}
+	return t.close()
+}
+
+// close closes the connection for good.
+func (t *metadTransport) close() error {
+	if t.open {
+		t.open = false
+		observer.MetadDisconnected(t.call.instance)
+	}
+	return t.Transport.Close()
+}
+
+// errUnfinished ends a call whose reply was never read to the end.
+var errUnfinished = errors.New("metad call unfinished")
+
+// metadCall is the RPC in flight on a client. A client's input and output
+// protocols share it: the call starts when its request is written and ends
+// when the reply has been read or either side fails.
+type metadCall struct {
+	ctx      context.Context
+	instance string
+	method   string
+	start    time.Time
+	span     trace.Span
+	// interrupt cuts the connection, failing the read or write in progress.
+	interrupt func() error
+	// unwatch stops watching ctx once the call is over.
+	unwatch func()
+	// exception is set when metad answered with an application exception.
+	exception bool
+	// failed is set once a call failed; the connection may then be out of
+	// step with metad and is not reused.
+	failed bool
+}
+
+// begin starts a call, failing it at once if ctx is already done.
+func (c *metadCall) begin(method string) error {
+	c.end(errUnfinished)
+	c.method = method
+	c.exception = false
+	c.start = time.Now()
+	_, c.span = tracer.Start(c.ctx, "metad."+method, trace.WithAttributes(
+		attribute.String("rpc.system", "thrift"),
+		attribute.String("rpc.method", method),
+		attribute.String("instance", c.instance)))
+	if err := c.ctx.Err(); err != nil {
+		c.end(err)
+		return err
+	}
+	c.watch()
+	return nil
+}
+
+// watch interrupts the connection if ctx is done before the call is over.
+func (c *metadCall) watch() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func(ctx context.Context) {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			c.interrupt()
+		case <-stop:
+		}
+	}(c.ctx)
+	c.unwatch = func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (c *metadCall) end(err error) {
+	if c.unwatch != nil {
+		c.unwatch()
+		c.unwatch = nil
+		// The connection may have been cut as the call ended.
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			c.failed = true
+			if err != nil {
+				err = ctxErr
+			}
+		}
+	}
+	if c.method == "" {
+		return
+	}
+	if err == nil && c.exception {
+		err = errors.New("metad answered with an exception")
+	}
+	observer.MetadCall(c.instance, c.method, time.Since(c.start), err != nil)
+	if err != nil {
+		c.failed = true
+	}
+	endSpan(c.span, err)
+	c.method = ""
+	c.span = nil
+}
+
+// fail ends the call if err is set, leaving it running otherwise. A call
+// that failed because ctx is done returns the error of ctx.
+func (c *metadCall) fail(err error) error {
+	if err != nil {
+		c.end(err)
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
+
+type metadProtocolFactory struct {
+	thrift.ProtocolFactory
+	call *metadCall
+}
+
+func (f *metadProtocolFactory) GetProtocol(trans thrift.Transport) thrift.Protocol {
+	return &metadProtocol{Protocol: f.ProtocolFactory.GetProtocol(trans), call: f.call}
+}
+
+type metadProtocol struct {
+	thrift.Protocol
+	call *metadCall
+}
+
+func (p *metadProtocol) WriteMessageBegin(name string, typeID thrift.MessageType, seqID int32) error {
+	if err := p.call.begin(name); err != nil {
+		return err
+	}
+	return p.call.fail(p.Protocol.WriteMessageBegin(name, typeID, seqID))
+}
+
+func (p *metadProtocol) WriteMessageEnd() error {
+	return p.call.fail(p.Protocol.WriteMessageEnd())
+}
+
+func (p *metadProtocol) Flush() error {
+	return p.call.fail(p.Protocol.Flush())
+}
+
+func (p *metadProtocol) ReadMessageBegin() (string, thrift.MessageType, int32, error) {
+	name, typeID, seqID, err := p.Protocol.ReadMessageBegin()
+	if typeID == thrift.EXCEPTION {
+		p.call.exception = true
+	}
+	return name, typeID, seqID, p.call.fail(err)
+}
+
+func (p *metadProtocol) ReadMessageEnd() error {
+	if err := p.Protocol.ReadMessageEnd(); err != nil {
+		return p.call.fail(err)
+	}
+	p.call.end(nil)
+	return nil
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// maxIdlePerInstance is how many unused connections are kept per
+	// instance; more are closed when released.
+	maxIdlePerInstance = 4
+	// idleTimeout is how long an unused connection is kept. It is shorter
+	// than metad's own idle timeout so a reused connection is still open.
+	idleTimeout = 30 * time.Second
+)
+
+// idlePool keeps connections to metad that operations are done with, so the
+// next operation on the same instance skips the Service lookup and the
+// connect.
+type idlePool struct {
+	mu     sync.Mutex
+	closed bool
+	conns  map[string][]*metadTransport
+}
+
+var idle = &idlePool{conns: map[string][]*metadTransport{}}
+
+// get takes an idle connection to instance, or returns nil if there is none.
+func (p *idlePool) get(instance string) *metadTransport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.conns[instance]
+	for len(conns) > 0 {
+		t := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		observer.MetadIdle(instance, -1)
+		if time.Since(t.idleSince) < idleTimeout {
+			p.conns[instance] = conns
+			t.released = false
+			return t
+		}
+		t.close()
+	}
+	delete(p.conns, instance)
+	return nil
+}
+
+// put keeps t for reuse, reporting false if the pool is full or closed.
+func (p *idlePool) put(t *metadTransport) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	instance := t.call.instance
+	if p.closed || len(p.conns[instance]) >= maxIdlePerInstance {
+		return false
+	}
+	t.idleSince = time.Now()
+	p.conns[instance] = append(p.conns[instance], t)
+	observer.MetadIdle(instance, 1)
+	return true
+}
+
+// drop closes the idle connections to instance.
+func (p *idlePool) drop(instance string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range p.conns[instance] {
+		observer.MetadIdle(instance, -1)
+		t.close()
+	}
+	delete(p.conns, instance)
+}
+
+// dropAll closes every idle connection, leaving the pool open.
+func (p *idlePool) dropAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for instance, conns := range p.conns {
+		for _, t := range conns {
+			observer.MetadIdle(instance, -1)
+			t.close()
+		}
+	}
+	p.conns = map[string][]*metadTransport{}
+}
+
+// CloseIdleConnections closes every idle connection to metad. Connections
+// in use are closed, not pooled, once their operation is done.
+func CloseIdleConnections() {
+	idle.dropAll()
+
+	idle.mu.Lock()
+	defer idle.mu.Unlock()
+	idle.closed = true
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vesoft-inc/nebula-go/nebula"
+	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
+)
+
+// MetadError is returned when metad answers a request with a failure code.
+type MetadError struct {
+	Op   string
+	Code nebula_metad.ErrorCode
+}
+
+func (e *MetadError) Error() string {
+	return fmt.Sprintf("metad %s failed: %s", e.Op, e.Code)
+}
+
+// ParseRole converts a role name such as "ADMIN" to its RoleType.
+func ParseRole(name string) (nebula.RoleType, error) {
+	role, err := nebula.RoleTypeFromString(name)
+	if err != nil {
+		return nebula.RoleType_GUEST, fmt.Errorf("unknown role %q", name)
+	}
+	return role, nil
+}
+
+// RoleName converts a RoleType to the name used by the HTTP API.
+func RoleName(role nebula.RoleType) string {
+	if name, ok := nebula.RoleTypeToName[role]; ok {
+		return name
+	}
+	return "GUEST"
+}
+
+// ListUserRoles returns every role user holds. A role with SpaceID 0 is
+// global, which is how GOD is granted.
+func ListUserRoles(ctx context.Context, ns, user string) ([]*nebula.RoleItem, error) {
+	metadClient, err := Dial(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if metadClient != nil {
+			metadClient.Close()
+		}
+	}()
+
+	getUserRolesReq := nebula_metad.NewGetUserRolesReq()
+	getUserRolesReq.Account = user
+
+	roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if roleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return nil, &MetadError{Op: "GetUserRoles", Code: roleResp.Code}
+	}
+
+	return roleResp.Roles, nil
+}
+
+// GrantRole grants user role in spaceName, or globally if spaceName is empty.
+func GrantRole(ctx context.Context, ns, user, spaceName string, role nebula.RoleType) error {
+	roleItem, err := makeRoleItem(ctx, ns, user, spaceName, role)
+	if err != nil {
+		return err
+	}
+
+	metadClient, err := Dial(ctx, ns)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if metadClient != nil {
+			metadClient.Close()
+		}
+	}()
+
+	grantRoleReq := nebula_metad.NewGrantRoleReq()
+	grantRoleReq.RoleItem = roleItem
+
+	grantRoleResp, err := metadClient.GrantRole(grantRoleReq)
+	if err != nil {
+		return err
+	}
+
+	if grantRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return &MetadError{Op: "GrantRole", Code: grantRoleResp.Code}
+	}
+
+	return nil
+}
+
+// RevokeRole revokes role of user in spaceName, or globally if spaceName is
+// empty.
+func RevokeRole(ctx context.Context, ns, user, spaceName string, role nebula.RoleType) error {
+	roleItem, err := makeRoleItem(ctx, ns, user, spaceName, role)
+	if err != nil {
+		return err
+	}
+
+	metadClient, err := Dial(ctx, ns)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if metadClient != nil {
+			metadClient.Close()
+		}
+	}()
+
+	revokeRoleReq := nebula_metad.NewRevokeRoleReq()
+	revokeRoleReq.RoleItem = roleItem
+
+	revokeRoleResp, err := metadClient.RevokeRole(revokeRoleReq)
+	if err != nil {
+		return err
+	}
+
+	if revokeRoleResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return &MetadError{Op: "RevokeRole", Code: revokeRoleResp.Code}
+	}
+
+	return nil
+}
+
+func makeRoleItem(ctx context.Context, ns, user, spaceName string, role nebula.RoleType) (*nebula.RoleItem, error) {
+	roleItem := nebula.NewRoleItem()
+	roleItem.User = user
+	roleItem.RoleType = role
+
+	if spaceName != "" {
+		spaceID, err := GetSpaceID(ctx, ns, spaceName)
+		if err != nil {
+			return nil, err
+		}
+		roleItem.SpaceID = spaceID
+	}
+
+	return roleItem, nil
+}
@@ -1,108 +1,188 @@
+// Package utils runs user, role and space operations against the metad of a
+// Nebula instance. An instance is addressed by its namespace; by default its
+// metad is found through the nebula-metad Service, but callers without
+// Kubernetes can point every instance at a fixed address with SetMetadAddress.
 package utils
 
 import (
 	"fmt"
 	"context"
-	"log"
+	"github.com/go-logr/logr"
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 	"github.com/vesoft-inc/nebula-go/nebula"
 	nebula_metad "github.com/vesoft-inc/nebula-go/nebula/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/client-go/kubernetes"
+	"net"
 	"time"
 )
 
-var client *kubernetes.Clientset
+var client kubernetes.Interface
 
-func SetK8sClient(cli *kubernetes.Clientset) {
+func log() logr.Logger {
+	return logger
+}
+
+// resolveMetadAddress returns the host:port of the metad serving ns.
+var resolveMetadAddress = serviceMetadAddress
+
+const metadPort = "44500"
+
+// metadTimeout bounds connecting to metad and every read and write on the
+// connection, whatever the deadline of the operation.
+const metadTimeout = 5 * time.Second
+
+func SetK8sClient(cli kubernetes.Interface) {
 	client = cli
+	resolveMetadAddress = serviceMetadAddress
 }
 
-func makeMetadClient(ns string) (*nebula_metad.MetaServiceClient, error) {
-	metadSvc, err := client.CoreV1().Services(ns).Get(context.Background(),"nebula-metad", metav1.GetOptions{})
+// SetMetadAddress makes every operation talk to the metad at addr, whatever
+// namespace it is given. It is meant for tools running outside the cluster.
+// The idle connections made to the previous address are closed.
+func SetMetadAddress(addr string) {
+	resolveMetadAddress = func(context.Context, string) (string, error) {
+		return addr, nil
+	}
+	idle.dropAll()
+}
+
+func serviceMetadAddress(ctx context.Context, ns string) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("no kubernetes client and no metad address configured")
+	}
+
+	metadSvc, err := client.CoreV1().Services(ns).Get(ctx,"nebula-metad", metav1.GetOptions{})
 
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	metadSvcIp := metadSvc.Spec.ClusterIP
+	return metadSvc.Spec.ClusterIP + ":" + metadPort, nil
+}
 
-	timeoutOption := thrift.SocketTimeout(time.Second * 5)
-	addressOption := thrift.SocketAddr(metadSvcIp + ":44500")
+// NewMetadClient returns a client to the metad of instance ns, reusing an
+// idle connection if there is one. Its RPCs are traced as children of the
+// span in ctx, and fail as soon as ctx is done: the connection is then cut
+// rather than left to wait out metadTimeout. The caller closes its
+// Transport when done, which hands the connection back to the idle pool.
+func NewMetadClient(ctx context.Context, ns string) (*nebula_metad.MetaServiceClient, error) {
+	return dialMetad(ctx, ns, "")
+}
 
-	transport, err := thrift.NewSocket(timeoutOption, addressOption)
-	protocol := thrift.NewBinaryProtocolFactoryDefault()
-	if err != nil {
+// dialMetad connects to the metad of instance ns at metadAddr, or, if it is
+// empty, reuses an idle connection or connects through the Service.
+func dialMetad(ctx context.Context, ns, metadAddr string) (metadClient *nebula_metad.MetaServiceClient, err error) {
+	ctx, span := tracer.Start(ctx, "makeMetadClient", trace.WithAttributes(attribute.String("instance", ns)))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	metadClient := nebula_metad.NewMetaServiceClientFactory(transport, protocol)
+	if metadAddr == "" {
+		if t := idle.get(ns); t != nil {
+			t.call.ctx = ctx
+			span.SetAttributes(attribute.Bool("pooled", true))
+			return t.client, nil
+		}
 
-	err = metadClient.Transport.Open()
+		metadAddr, err = resolveMetadAddress(ctx, ns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	span.SetAttributes(attribute.String("net.peer.name", metadAddr))
+
+	dialer := net.Dialer{Timeout: metadTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", metadAddr)
+	observer.MetadConnected(ns, err)
+	if err != nil {
+		log().Error(err, "Open metad transport failed", "instanceID", ns, "address", metadAddr)
+		return nil, &connectError{err}
+	}
 
+	socket, err := thrift.NewSocket(thrift.SocketConn(conn), thrift.SocketTimeout(metadTimeout))
 	if err != nil {
-		fmt.Println("MetaThrift Cant Open " + err.Error())
+		conn.Close()
+		observer.MetadDisconnected(ns)
 		return nil, err
 	}
 
+	transport, protocol := instrumentMetad(ctx, ns, socket, thrift.NewBinaryProtocolFactoryDefault())
+	metadClient = nebula_metad.NewMetaServiceClientFactory(transport, protocol)
+	transport.client = metadClient
+
 	return metadClient, nil
 }
 
-func DropUser(ns, user string) error {
-	metadClient, err := makeMetadClient(ns)
+func DropUser(ctx context.Context, ns, user string) error {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
 	dropUserReq := nebula_metad.NewDropUserReq()
 	dropUserReq.Account = user
-	_, err = metadClient.DropUser(dropUserReq)
+	dropUserResp, err := metadClient.DropUser(dropUserReq)
 	if err != nil {
-		fmt.Println("MetadClient Drop User Failed !", err.Error())
+		log().Error(err, "Drop user failed", "instanceID", ns, "user", user)
 		return err
 	}
 
+	if dropUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return &MetadError{Op: "DropUser", Code: dropUserResp.Code}
+	}
+
 	return nil
 }
 
-func CreateUser(ns, user string) error {
-	metadClient, err := makeMetadClient(ns)
+func CreateUser(ctx context.Context, ns, user string) error {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
 	createUserReq := nebula_metad.NewCreateUserReq()
 	createUserReq.Account = user
-	_, err = metadClient.CreateUser(createUserReq)
+	createUserResp, err := metadClient.CreateUser(createUserReq)
 	if err != nil {
-		fmt.Println("MetadClient Create User Failed !", err.Error())
+		log().Error(err, "Create user failed", "instanceID", ns, "user", user)
 		return err
 	}
 
+	if createUserResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return &MetadError{Op: "CreateUser", Code: createUserResp.Code}
+	}
+
 	return nil
 }
 
-func ListUsers(ns string) ([]string, error ) {
-	metadClient, err := makeMetadClient(ns)
+func ListUsers(ctx context.Context, ns string) ([]string, error ) {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
 		return []string{}, err
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -112,6 +192,10 @@ func ListUsers(ns string) ([]string, error ) {
 		return []string{}, err
 	}
 
+	if listUsersResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return []string{}, &MetadError{Op: "ListUsers", Code: listUsersResp.Code}
+	}
+
 	users := listUsersResp.Users
 
 	res := []string{}
@@ -123,20 +207,20 @@ func ListUsers(ns string) ([]string, error ) {
 	return res, nil
 }
 
-func IsUserInSpace(spaceName, userName, ns string) bool {
-	metadClient, err := makeMetadClient(ns)
+func IsUserInSpace(ctx context.Context, spaceName, userName, ns string) bool {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
-		log.Println("Create Metad Client Error: %v", err)
+		log().Error(err, "Create metad client failed", "instanceID", ns)
 		return false
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
-	_, err = GetUserRoles(userName, spaceName, ns)
+	_, err = GetUserRoles(ctx, userName, spaceName, ns)
 	if err != nil {
 
 		return false
@@ -144,16 +228,16 @@ func IsUserInSpace(spaceName, userName, ns string) bool {
 	return true
 }
 
-func GetUserRoles(user, spaceName, ns string) (nebula.RoleType, error) {
-	metadClient, err := makeMetadClient(ns)
+func GetUserRoles(ctx context.Context, user, spaceName, ns string) (nebula.RoleType, error) {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
-		log.Println("Create Metad Client Error: %v", err)
-		return nebula.RoleType_GUEST, fmt.Errorf("Internal Error")
+		log().Error(err, "Create metad client failed", "instanceID", ns)
+		return nebula.RoleType_GUEST, fmt.Errorf("Internal Error: %w", err)
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -164,21 +248,21 @@ func GetUserRoles(user, spaceName, ns string) (nebula.RoleType, error) {
 
 	roleResp, err := metadClient.GetUserRoles(getUserRolesReq)
 	if err != nil {
-		fmt.Println("Get User " + user + " error " + err.Error())
-		return -1, fmt.Errorf("Inner Error")
+		log().Error(err, "Get user roles failed", "instanceID", ns, "user", user)
+		return -1, fmt.Errorf("Inner Error: %w", err)
 	}
 
 	for _, role := range roleResp.Roles {
 		if role.SpaceID == spaceID {
-			fmt.Println("Account Role is: ", role.RoleType)
+			log().V(1).Info("User role found", "instanceID", ns, "user", user, "role", RoleName(role.RoleType))
 			return role.RoleType, nil
 		}
 	}
 
-	spaceID, err = GetSpaceID(ns, spaceName)
+	spaceID, err = GetSpaceID(ctx, ns, spaceName)
 	if err != nil {
-		fmt.Println("List User Failed")
-		return -1, fmt.Errorf("Inner Error")
+		log().Error(err, "Get space failed", "instanceID", ns, "space", spaceName)
+		return -1, fmt.Errorf("Inner Error: %w", err)
 	}
 
 	getUserRolesReq = nebula_metad.NewGetUserRolesReq()
@@ -186,13 +270,13 @@ func GetUserRoles(user, spaceName, ns string) (nebula.RoleType, error) {
 
 	roleResp, err = metadClient.GetUserRoles(getUserRolesReq)
 	if err != nil {
-		fmt.Println("Get User " + user + " error " + err.Error())
-		return -1, fmt.Errorf("Inner Error")
+		log().Error(err, "Get user roles failed", "instanceID", ns, "user", user)
+		return -1, fmt.Errorf("Inner Error: %w", err)
 	}
 
 	for _, role := range roleResp.Roles {
 		if role.SpaceID == spaceID {
-			fmt.Println("Account Role is: ", role.RoleType)
+			log().V(1).Info("User role found", "instanceID", ns, "user", user, "role", RoleName(role.RoleType))
 			return role.RoleType, nil
 		}
 	}
@@ -200,19 +284,18 @@ func GetUserRoles(user, spaceName, ns string) (nebula.RoleType, error) {
 	return nebula.RoleType_GUEST, fmt.Errorf("Not Found")
 }
 
-func GetSpaceID(ns, spaceName string) (nebula.GraphSpaceID, error) {
+func GetSpaceID(ctx context.Context, ns, spaceName string) (nebula.GraphSpaceID, error) {
 	getSpaceReq := nebula_metad.NewGetSpaceReq()
-	fmt.Println("SpaceName is ", spaceName)
 	getSpaceReq.SpaceName = spaceName
-	metadClient, err := makeMetadClient(ns)
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
-		log.Println("Create Metad Client Error: %v", err)
-		return 0, fmt.Errorf("Internal Error")
+		log().Error(err, "Create metad client failed", "instanceID", ns)
+		return 0, fmt.Errorf("Internal Error: %w", err)
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -228,19 +311,37 @@ func GetSpaceID(ns, spaceName string) (nebula.GraphSpaceID, error) {
 	}
 
 	spaceID := getSpaceResp.Item.SpaceID
-	fmt.Println("SpaceID is ", getSpaceResp.Item.SpaceID)
+	log().V(1).Info("Space found", "instanceID", ns, "space", spaceName, "spaceID", spaceID)
 	return spaceID, nil
 }
 
-func ListSpaces(ns string) ([]string, error ) {
-	metadClient, err := makeMetadClient(ns)
+// Ping checks that the metad of instance ns can be reached and answers.
+func Ping(ctx context.Context, ns string) error {
+	metadClient, err := NewMetadClient(ctx, ns)
+	if err != nil {
+		return err
+	}
+	defer metadClient.Transport.Close()
+
+	listSpacesResp, err := metadClient.ListSpaces(&nebula_metad.ListSpacesReq{})
+	if err != nil {
+		return err
+	}
+	if listSpacesResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return &MetadError{Op: "ListSpaces", Code: listSpacesResp.Code}
+	}
+	return nil
+}
+
+func ListSpaces(ctx context.Context, ns string) ([]string, error ) {
+	metadClient, err := Dial(ctx, ns)
 	if err != nil {
 		return []string{}, err
 	}
 
 	defer func() {
 		if metadClient != nil {
-			metadClient.Transport.Close()
+			metadClient.Close()
 		}
 	}()
 
@@ -250,6 +351,10 @@ func ListSpaces(ns string) ([]string, error ) {
 		return []string{}, err
 	}
 
+	if listSpacesResp.Code != nebula_metad.ErrorCode_SUCCEEDED {
+		return []string{}, &MetadError{Op: "ListSpaces", Code: listSpacesResp.Code}
+	}
+
 	ids := listSpacesResp.GetSpaces()
 
 	res := []string{}
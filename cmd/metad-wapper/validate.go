@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/logging"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Request structs declare their constraints with a `validate` tag, a comma
+// separated list of rules:
+//
+//	required   the field must not be empty
+//	instance   the field must be a valid instance (namespace) name
+//	name       the field must be a valid Nebula identifier
+//	role       the field must be one of GOD, ADMIN, DBA, USER, GUEST
+//	max=N      the field must not be longer than N characters
+//	order      the field must be asc or desc
+//	job        the field must be an admin job command, compact or flush
+//	module     the field must be a config module, GRAPH, META or STORAGE
+//
+// Rules other than required are skipped for empty fields, so optional fields
+// are only checked when they are set.
+//
+// Integer fields take min=N and max=N, which bound their value.
+
+var nebulaNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var knownRoles = []string{"GOD", "ADMIN", "DBA", "USER", "GUEST"}
+
+var knownOrders = []string{"asc", "desc"}
+
+var knownJobs = []string{api.JobCompact, api.JobFlush}
+
+var knownModules = []string{"GRAPH", "META", "STORAGE"}
+
+type ValidationErrors []api.FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Code maps the first failing field to the error code the console expects.
+func (errs ValidationErrors) Code() int {
+	if len(errs) == 0 {
+		return 0
+	}
+	if errs[0].Rule == "required" {
+		switch errs[0].Field {
+		case "InstanceID":
+			return api.ErrEmptyInstanceID
+		case "SpaceName", "Space":
+			return api.ErrEmptySpaceName
+		}
+	}
+	return api.ErrInvalidRequestBody
+}
+
+// Validate checks every tagged field of the struct pointed to by req.
+func Validate(req interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: expected struct, got %s", v.Kind())
+	}
+
+	errs := ValidationErrors{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		var err *api.FieldError
+		switch field.Type.Kind() {
+		case reflect.String:
+			err = validateField(field.Name, v.Field(i).String(), strings.Split(tag, ","))
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			err = validateInt(field.Name, v.Field(i).Int(), strings.Split(tag, ","))
+		}
+		if err != nil {
+			errs = append(errs, *err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateField(name, value string, rules []string) *api.FieldError {
+	for _, rule := range rules {
+		if rule == "required" {
+			if value == "" {
+				return &api.FieldError{Field: name, Rule: rule, Message: "is required"}
+			}
+			continue
+		}
+
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case rule == "instance":
+			if msgs := validation.IsDNS1123Label(value); len(msgs) != 0 {
+				return &api.FieldError{Field: name, Rule: rule, Message: strings.Join(msgs, ", ")}
+			}
+		case rule == "name":
+			if !nebulaNameRegexp.MatchString(value) {
+				return &api.FieldError{Field: name, Rule: rule,
+					Message: "must start with a letter or underscore and contain only letters, digits and underscores"}
+			}
+		case rule == "role":
+			if !isKnownRole(value) {
+				return &api.FieldError{Field: name, Rule: rule,
+					Message: "must be one of " + strings.Join(knownRoles, ", ")}
+			}
+		case rule == "order":
+			if value != "asc" && value != "desc" {
+				return &api.FieldError{Field: name, Rule: rule,
+					Message: "must be one of " + strings.Join(knownOrders, ", ")}
+			}
+		case rule == "job":
+			if value != api.JobCompact && value != api.JobFlush {
+				return &api.FieldError{Field: name, Rule: rule,
+					Message: "must be one of " + strings.Join(knownJobs, ", ")}
+			}
+		case rule == "module":
+			if value != "GRAPH" && value != "META" && value != "STORAGE" {
+				return &api.FieldError{Field: name, Rule: rule,
+					Message: "must be one of " + strings.Join(knownModules, ", ")}
+			}
+		case strings.HasPrefix(rule, "max="):
+			max, err := strconv.Atoi(strings.TrimPrefix(rule, "max="))
+			if err != nil {
+				panic("validate: bad rule " + rule)
+			}
+			if len(value) > max {
+				return &api.FieldError{Field: name, Rule: "max",
+					Message: fmt.Sprintf("must be at most %d characters", max)}
+			}
+		default:
+			panic("validate: unknown rule " + rule)
+		}
+	}
+	return nil
+}
+
+func validateInt(name string, value int64, rules []string) *api.FieldError {
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			panic("validate: unknown rule " + rule)
+		}
+		bound, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			panic("validate: bad rule " + rule)
+		}
+
+		switch parts[0] {
+		case "min":
+			if value < bound {
+				return &api.FieldError{Field: name, Rule: parts[0], Message: fmt.Sprintf("must be at least %d", bound)}
+			}
+		case "max":
+			if value > bound {
+				return &api.FieldError{Field: name, Rule: parts[0], Message: fmt.Sprintf("must be at most %d", bound)}
+			}
+		default:
+			panic("validate: unknown rule " + rule)
+		}
+	}
+	return nil
+}
+
+func isKnownRole(role string) bool {
+	for _, known := range knownRoles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRequest unmarshals a request body into req and validates it.
+func decodeRequest(bodyData []byte, req interface{}) error {
+	if err := json.Unmarshal(bodyData, req); err != nil {
+		return err
+	}
+	return Validate(req)
+}
+
+// writeRequestError reports a body that could not be decoded or validated.
+func writeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	errorResponse := api.ErrorResponse{Code: api.ErrInvalidRequestBody}
+
+	if errs, ok := err.(ValidationErrors); ok {
+		errorResponse.Code = errs.Code()
+		errorResponse.Errors = errs
+	} else {
+		errorResponse.Errors = []api.FieldError{{Message: err.Error()}}
+	}
+
+	logging.FromContext(r.Context()).Info("Invalid request body", "error", err.Error(), "code", errorResponse.Code)
+	body, _ := json.Marshal(errorResponse)
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
+
+// writeResponse writes resp with 403 Forbidden if failed, 200 OK otherwise.
+func writeResponse(w http.ResponseWriter, resp interface{}, failed bool) {
+	body, _ := json.Marshal(resp)
+	if failed {
+		w.WriteHeader(http.StatusForbidden)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.Write(body)
+}
+
+// readRequest decodes the body of r into req, writing the error
+// response if it can't.
+func readRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	bodyData, err := requestBody(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Error(err, "Read request body failed")
+		writeResponse(w, api.ErrorResponse{Code: api.ErrInvalidRequestBody}, true)
+		return false
+	}
+	if err := decodeRequest(bodyData, req); err != nil {
+		writeRequestError(w, r, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+type validated struct {
+	InstanceID string `validate:"required,instance"`
+	Name       string `validate:"name,max=8"`
+	Role       string `validate:"role"`
+	Order      string `validate:"order"`
+	Job        string `validate:"job"`
+	Module     string `validate:"module"`
+	Limit      int    `validate:"min=0,max=10"`
+	JobID      int32  `validate:"min=1"`
+	Free       string
+}
+
+func validRequest() validated {
+	return validated{InstanceID: "nebula", JobID: 1}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*validated)
+		field  string
+		rule   string
+	}{
+		{"valid", func(v *validated) {}, "", ""},
+		{"all set", func(v *validated) {
+			*v = validated{InstanceID: "nebula-1", Name: "_user1", Role: "ADMIN", Order: "desc",
+				Job: api.JobFlush, Module: "STORAGE", Limit: 10, JobID: 3, Free: "anything"}
+		}, "", ""},
+		{"required", func(v *validated) { v.InstanceID = "" }, "InstanceID", "required"},
+		{"instance", func(v *validated) { v.InstanceID = "Nebula_1" }, "InstanceID", "instance"},
+		{"name", func(v *validated) { v.Name = "1user" }, "Name", "name"},
+		{"name characters", func(v *validated) { v.Name = "us-er" }, "Name", "name"},
+		{"max", func(v *validated) { v.Name = "user_name" }, "Name", "max"},
+		{"role", func(v *validated) { v.Role = "admin" }, "Role", "role"},
+		{"order", func(v *validated) { v.Order = "up" }, "Order", "order"},
+		{"job", func(v *validated) { v.Job = "stats" }, "Job", "job"},
+		{"module", func(v *validated) { v.Module = "ALL" }, "Module", "module"},
+		{"int min", func(v *validated) { v.Limit = -1 }, "Limit", "min"},
+		{"int max", func(v *validated) { v.Limit = 11 }, "Limit", "max"},
+		{"int32 min", func(v *validated) { v.JobID = 0 }, "JobID", "min"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := validRequest()
+			test.modify(&req)
+			err := Validate(&req)
+			if test.field == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != 1 {
+				t.Fatalf("Validate() = %v, want one error", err)
+			}
+			if errs[0].Field != test.field || errs[0].Rule != test.rule {
+				t.Errorf("Validate() failed %s on %s, want %s on %s", errs[0].Rule, errs[0].Field, test.rule, test.field)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	req := validated{Role: "nobody", Limit: 100}
+	errs, ok := Validate(&req).(ValidationErrors)
+	if !ok {
+		t.Fatalf("Validate() did not return ValidationErrors")
+	}
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if got, want := strings.Join(fields, ","), "InstanceID,Role,Limit,JobID"; got != want {
+		t.Errorf("failing fields = %s, want %s", got, want)
+	}
+}
+
+func TestValidateRejectsNonStruct(t *testing.T) {
+	if err := Validate("nebula"); err == nil {
+		t.Error("Validate(string) = nil, want an error")
+	}
+}
+
+func TestValidateUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Validate did not panic on an unknown rule")
+		}
+	}()
+	Validate(&struct {
+		Name string `validate:"nonsense"`
+	}{Name: "x"})
+}
+
+func TestValidationErrorsCode(t *testing.T) {
+	tests := []struct {
+		errs ValidationErrors
+		code int
+	}{
+		{ValidationErrors{}, 0},
+		{ValidationErrors{{Field: "InstanceID", Rule: "required"}}, api.ErrEmptyInstanceID},
+		{ValidationErrors{{Field: "SpaceName", Rule: "required"}}, api.ErrEmptySpaceName},
+		{ValidationErrors{{Field: "Space", Rule: "required"}}, api.ErrEmptySpaceName},
+		{ValidationErrors{{Field: "InstanceID", Rule: "instance"}}, api.ErrInvalidRequestBody},
+		{ValidationErrors{{Field: "UserName", Rule: "required"}}, api.ErrInvalidRequestBody},
+		// Only the first failing field decides.
+		{ValidationErrors{{Field: "Role", Rule: "role"}, {Field: "InstanceID", Rule: "required"}}, api.ErrInvalidRequestBody},
+	}
+	for _, test := range tests {
+		if code := test.errs.Code(); code != test.code {
+			t.Errorf("%v.Code() = %d, want %d", test.errs, code, test.code)
+		}
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	tests := []struct {
+		body   string
+		ok     bool
+		status int
+	}{
+		{`{"InstanceID": "nebula", "JobID": 1}`, true, http.StatusOK},
+		{`{"InstanceID": "nebula"`, false, http.StatusBadRequest},
+		{`{"JobID": 1}`, false, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		req := validated{}
+		if ok := readRequest(w, r, &req); ok != test.ok {
+			t.Errorf("readRequest(%s) = %v, want %v", test.body, ok, test.ok)
+		}
+		if w.Code != test.status {
+			t.Errorf("readRequest(%s) status = %d, want %d", test.body, w.Code, test.status)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/metrics"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/tracing"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// componentHTTPPorts are the ports the Nebula components serve their
+// status endpoint on, set in main from -metad-http-port and the like.
+var componentHTTPPorts = map[string]int{
+	"metad":    11000,
+	"storaged": 12000,
+	"graphd":   13000,
+}
+
+// nebulaComponents lists the components in the order they are reported.
+var nebulaComponents = []string{"metad", "storaged", "graphd"}
+
+var nebulaStatusClient = &http.Client{
+	Timeout:   time.Second * 5,
+	Transport: metrics.InstrumentRoundTripper("nebula-status", tracing.Transport(nil)),
+}
+
+// NebulaVersionResponse is what a Nebula component answers on /status.
+// Components that don't report their version leave Version empty.
+type NebulaVersionResponse struct {
+	Status      string `json:"status"`
+	BuildTime   string `json:"build_time"`
+	GitCommitID string `json:"git_info_sha"`
+	Version     string `json:"version"`
+}
+
+// getNebulaStatus reads the status endpoint of the component running in pod.
+func getNebulaStatus(ctx context.Context, pod *corev1.Pod, component string) (*NebulaVersionResponse, error) {
+	address := net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(componentHTTPPorts[component]))
+	req, err := http.NewRequest("GET", "http://"+address+"/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := nebulaStatusClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status endpoint answered %s", resp.Status)
+	}
+	bodyData, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &NebulaVersionResponse{}
+	if err := json.Unmarshal(bodyData, status); err != nil {
+		return nil, fmt.Errorf("status endpoint answered %q: %v", bodyData, err)
+	}
+	return status, nil
+}
+
+// imageTag returns the tag of the image the component runs in pod, which
+// stands for its version when it doesn't report one.
+func imageTag(pod *corev1.Pod, component string) string {
+	for _, container := range pod.Spec.Containers {
+		if len(pod.Spec.Containers) > 1 && !strings.Contains(container.Name, component) {
+			continue
+		}
+		image := container.Image
+		if i := strings.Index(image, "@"); i >= 0 {
+			image = image[:i]
+		}
+		if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+			return image[i+1:]
+		}
+		return "latest"
+	}
+	return ""
+}
+
+// podVersions returns the version info of every Nebula pod in pods, asking
+// their status endpoints all at once. A pod that isn't running, or whose
+// endpoint can't be read, is reported with the reason in Error.
+func podVersions(ctx context.Context, pods []corev1.Pod) []api.InstanceInfo {
+	infos := []api.InstanceInfo{}
+	for i := range pods {
+		pod := &pods[i]
+		component := objectComponent(pod)
+		if component == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		infos = append(infos, api.InstanceInfo{
+			Component: component,
+			Pod:       pod.Name,
+			Version:   imageTag(pod, component),
+			Status:    strings.ToLower(string(pod.Status.Phase)),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Component != infos[j].Component {
+			return componentIndex(infos[i].Component) < componentIndex(infos[j].Component)
+		}
+		return infos[i].Pod < infos[j].Pod
+	})
+
+	podsByName := map[string]*corev1.Pod{}
+	for i := range pods {
+		podsByName[pods[i].Name] = &pods[i]
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range infos {
+		info := &infos[i]
+		pod := podsByName[info.Pod]
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			info.Error = "pod is not running"
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := getNebulaStatus(ctx, pod, info.Component)
+			if err != nil {
+				info.Status = "unreachable"
+				info.Error = err.Error()
+				return
+			}
+			info.Status = status.Status
+			info.CommitID = status.GitCommitID
+			info.BuildTime = status.BuildTime
+			if status.Version != "" {
+				info.Version = status.Version
+			}
+		}()
+	}
+	wg.Wait()
+
+	return infos
+}
+
+func componentIndex(component string) int {
+	for i, known := range nebulaComponents {
+		if component == known {
+			return i
+		}
+	}
+	return len(nebulaComponents)
+}
+
+// markVersionSkew flags the replicas of a component running another build
+// than most of its replicas, telling them apart by version and commit, and
+// reports whether any was flagged. With no build run by most, as half way
+// through a rolling upgrade of an even number of replicas, every replica of
+// the component is flagged: there is no telling which build is the odd one.
+// Replicas whose build is unknown are left out.
+func markVersionSkew(infos []api.InstanceInfo) bool {
+	builds := map[string]map[string]int{}
+	build := func(info *api.InstanceInfo) string {
+		return info.Version + "@" + info.CommitID
+	}
+	for i := range infos {
+		info := &infos[i]
+		if info.CommitID == "" {
+			continue
+		}
+		if builds[info.Component] == nil {
+			builds[info.Component] = map[string]int{}
+		}
+		builds[info.Component][build(info)]++
+	}
+
+	// common is the build most replicas of each component run, "" if
+	// several tie.
+	common := map[string]string{}
+	for component, counts := range builds {
+		most := 0
+		for b, count := range counts {
+			switch {
+			case count > most:
+				most, common[component] = count, b
+			case count == most:
+				common[component] = ""
+			}
+		}
+	}
+
+	skew := false
+	for i := range infos {
+		info := &infos[i]
+		if info.CommitID == "" || len(builds[info.Component]) < 2 {
+			continue
+		}
+		if build(info) != common[info.Component] {
+			info.VersionSkew = true
+			skew = true
+		}
+	}
+	return skew
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func TestMarkVersionSkew(t *testing.T) {
+	replica := func(component, version, commit string) api.InstanceInfo {
+		return api.InstanceInfo{Component: component, Version: version, CommitID: commit}
+	}
+
+	tests := []struct {
+		name  string
+		infos []api.InstanceInfo
+		// want flags the skewed replicas, in the order of infos.
+		want string
+	}{
+		{"one build", []api.InstanceInfo{
+			replica("graphd", "v2.0.0", "a"), replica("graphd", "v2.0.0", "a"),
+		}, "--"},
+		{"odd one out", []api.InstanceInfo{
+			replica("storaged", "v2.0.0", "a"), replica("storaged", "v2.0.1", "b"), replica("storaged", "v2.0.0", "a"),
+		}, "-x-"},
+		// Half way through upgrading: either build may be the odd one.
+		{"tie", []api.InstanceInfo{
+			replica("storaged", "v2.0.0", "a"), replica("storaged", "v2.0.1", "b"),
+			replica("storaged", "v2.0.1", "b"), replica("storaged", "v2.0.0", "a"),
+		}, "xxxx"},
+		{"tie between the lesser builds", []api.InstanceInfo{
+			replica("metad", "v2.0.0", "a"), replica("metad", "v2.0.1", "b"),
+			replica("metad", "v2.0.2", "c"), replica("metad", "v2.0.2", "c"),
+		}, "xx--"},
+		{"same version, other commit", []api.InstanceInfo{
+			replica("graphd", "v2.0.0", "a"), replica("graphd", "v2.0.0", "b"), replica("graphd", "v2.0.0", "b"),
+		}, "x--"},
+		{"components apart", []api.InstanceInfo{
+			replica("graphd", "v2.0.0", "a"), replica("metad", "v2.0.1", "b"),
+		}, "--"},
+		{"unknown build left out", []api.InstanceInfo{
+			replica("graphd", "v2.0.0", "a"), replica("graphd", "v2.0.1", ""),
+		}, "--"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			skew := markVersionSkew(test.infos)
+			got := ""
+			for _, info := range test.infos {
+				if info.VersionSkew {
+					got += "x"
+				} else {
+					got += "-"
+				}
+			}
+			if got != test.want {
+				t.Errorf("flagged %s, want %s", got, test.want)
+			}
+			if wantSkew := strings.Contains(test.want, "x"); skew != wantSkew {
+				t.Errorf("markVersionSkew = %v, want %v", skew, wantSkew)
+			}
+		})
+	}
+}
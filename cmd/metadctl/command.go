@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/client"
+)
+
+// globalOptions are accepted by every command that talks to the wrapper.
+type globalOptions struct {
+	profile  string
+	endpoint string
+	token    string
+	output   string
+	timeout  time.Duration
+	noCache  bool
+}
+
+type command struct {
+	name  string
+	short string
+	// flags registers the command's own flags; it may be nil.
+	flags func(fs *flag.FlagSet)
+	run   func(env *environment, args []string) error
+	sub   []*command
+}
+
+// environment is what a command runs with once flags are parsed.
+type environment struct {
+	opts   globalOptions
+	ctx    context.Context
+	config *config
+	root   *command
+}
+
+func (e *environment) client() (*client.Client, error) {
+	endpoint, token := e.opts.endpoint, e.opts.token
+	if endpoint == "" || token == "" {
+		profile, err := e.config.profile(e.opts.profile)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint == "" {
+			endpoint = profile.Endpoint
+		}
+		if token == "" {
+			token = profile.Token
+		}
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("no endpoint: pass --endpoint or configure a profile with 'metadctl profile set'")
+	}
+	c := client.New(endpoint)
+	c.NoCache = e.opts.noCache
+	c.Token = token
+	return c, nil
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.sub {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *command) execute(args []string) error {
+	return c.executePath(c, args, []string{"metadctl"})
+}
+
+func (c *command) executePath(root *command, args []string, path []string) error {
+	if len(c.sub) != 0 {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+			c.printUsage(path)
+			return nil
+		}
+		if strings.HasPrefix(args[0], "-") {
+			return fmt.Errorf("flags must follow a command, see '%s --help'", strings.Join(path, " "))
+		}
+		sub := c.find(args[0])
+		if sub == nil {
+			return fmt.Errorf("unknown command %q for '%s'", args[0], strings.Join(path, " "))
+		}
+		return sub.executePath(root, args[1:], append(path, sub.name))
+	}
+
+	opts := globalOptions{}
+	fs := c.flagSet(path, &opts)
+
+	// flag stops at the first positional argument; keep going so flags may
+	// follow positionals, as in "profile set prod --url ...".
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				c.printFlags(path, fs)
+				return nil
+			}
+			return err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	switch opts.output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q", opts.output)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	return c.run(&environment{opts: opts, ctx: ctx, config: cfg, root: root}, positional)
+}
+
+func (c *command) flagSet(path []string, opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&opts.profile, "profile", os.Getenv("METADCTL_PROFILE"), "profile to use from the config file")
+	fs.StringVar(&opts.endpoint, "endpoint", os.Getenv("METADCTL_ENDPOINT"), "wrapper URL, overrides the profile")
+	fs.StringVar(&opts.token, "token", os.Getenv("METADCTL_TOKEN"), "bearer token to authenticate with, overrides the profile")
+	fs.StringVar(&opts.output, "o", "table", "output format: table, json or yaml")
+	fs.DurationVar(&opts.timeout, "timeout", time.Minute, "request timeout")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "read metad rather than the wrapper's metadata cache")
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	return fs
+}
+
+func (c *command) printUsage(path []string) {
+	fmt.Printf("%s\n\nUsage:\n  %s <command> [flags]\n\nCommands:\n", c.short, strings.Join(path, " "))
+	names := make([]string, 0, len(c.sub))
+	for _, sub := range c.sub {
+		names = append(names, sub.name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-12s %s\n", name, c.find(name).short)
+	}
+}
+
+func (c *command) printFlags(path []string, fs *flag.FlagSet) {
+	fmt.Printf("%s\n\nUsage:\n  %s [flags]\n\nFlags:\n", c.short, strings.Join(path, " "))
+	fs.VisitAll(func(f *flag.Flag) {
+		fmt.Printf("  %-14s %s", flagName(f), f.Usage)
+		if f.DefValue != "" {
+			fmt.Printf(" (default %q)", f.DefValue)
+		}
+		fmt.Println()
+	})
+}
+
+// requireFlags returns an error naming the first empty flag value.
+func requireFlags(values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if values[name] == "" {
+			return fmt.Errorf("--%s is required", name)
+		}
+	}
+	return nil
+}
+
+// flagName renders a flag the way users type it: -o, --instance.
+func flagName(f *flag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
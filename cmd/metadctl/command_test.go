@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/client"
+)
+
+// execute runs metadctl with args, returning what it printed.
+func execute(t *testing.T, args ...string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		printed <- string(data)
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	err = newRootCommand().execute(args)
+	os.Stdout = stdout
+	w.Close()
+	return <-printed, err
+}
+
+// wrapper is a fake metad wrapper answering each path with a canned reply.
+type wrapper struct {
+	*httptest.Server
+	status  int
+	replies map[string]interface{}
+	// The last request served.
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func fakeWrapper(t *testing.T, replies map[string]interface{}) *wrapper {
+	fake := &wrapper{status: http.StatusOK, replies: replies}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.path, fake.header = r.URL.Path, r.Header
+		fake.body, _ = ioutil.ReadAll(r.Body)
+		data, err := json.Marshal(fake.replies[r.URL.Path])
+		if err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(fake.status)
+		w.Write(data)
+	}))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func TestExecuteErrors(t *testing.T) {
+	isolate(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown command", []string{"spaces", "drop"}, `unknown command "drop" for 'metadctl spaces'`},
+		{"flags before the command", []string{"--endpoint", "http://localhost", "status"}, "flags must follow a command"},
+		{"unknown flag", []string{"status", "--instance", "nebula"}, "flag provided but not defined: -instance"},
+		{"required flag", []string{"spaces", "list", "--instance", "nebula"}, "--user is required"},
+		{"output format", []string{"status", "-o", "xml"}, `unknown output format "xml"`},
+		{"no endpoint", []string{"status"}, "no endpoint"},
+		{"no profile", []string{"status", "--profile", "prod"}, `profile "prod" not found`},
+		{"timestamp", []string{"cost", "--start", "yesterday", "--endpoint", "http://localhost"}, "--start:"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := execute(t, test.args...)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error = %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	isolate(t)
+
+	for _, args := range [][]string{{}, {"help"}, {"spaces", "--help"}} {
+		out, err := execute(t, args...)
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if !strings.Contains(out, "Commands:") {
+			t.Errorf("%v printed %q, want the subcommands", args, out)
+		}
+	}
+
+	out, err := execute(t, "spaces", "list", "--help")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--instance", "--user", "--limit", "-o", `(default "table")`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help printed %q, want it to list %s", out, want)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	isolate(t)
+	fake := fakeWrapper(t, map[string]interface{}{
+		api.PathListSpaces: api.ListSpaceResponse{InstanceID: "nebula", Spaces: []string{"graph", "social"}, Total: 3, NextCursor: "c29jaWFs"},
+		api.PathLimits:     api.LimitsResponse{Limits: api.Limits{CallerRate: 10, CallerBurst: 20}},
+	})
+
+	out, err := execute(t, "spaces", "list", "--instance", "nebula", "--user", "root", "--limit", "2",
+		"--endpoint", fake.URL, "--token", "s3cr3t", "--no-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.path != api.PathListSpaces {
+		t.Errorf("path = %s, want %s", fake.path, api.PathListSpaces)
+	}
+	sent := api.ListSpaceRequest{}
+	if err := json.Unmarshal(fake.body, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if want := (api.ListSpaceRequest{InstanceID: "nebula", UserName: "root", Limit: 2}); sent != want {
+		t.Errorf("request = %+v, want %+v", sent, want)
+	}
+	if auth := fake.header.Get("Authorization"); auth != "Bearer s3cr3t" {
+		t.Errorf("Authorization = %q, want the token", auth)
+	}
+	if cache := fake.header.Get("Cache-Control"); cache != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cache)
+	}
+	want := "SPACE\ngraph\nsocial\n\nShowing 2 of 3: run again with --cursor c29jaWFs for more\n"
+	if out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+
+	// json prints the reply itself, every field of it.
+	out, err = execute(t, "limits", "get", "-o", "json", "--endpoint", fake.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printed := api.LimitsResponse{}
+	if err := json.Unmarshal([]byte(out), &printed); err != nil {
+		t.Fatalf("-o json printed %q: %v", out, err)
+	}
+	if !reflect.DeepEqual(printed, fake.replies[api.PathLimits]) {
+		t.Errorf("-o json printed %+v, want %+v", printed, fake.replies[api.PathLimits])
+	}
+
+	// The wrapper's refusal reaches the user with its code.
+	fake.status = http.StatusUnauthorized
+	fake.replies[api.PathLimits] = api.ErrorResponse{Code: api.ErrUnauthorized}
+	_, err = execute(t, "limits", "get", "--endpoint", fake.URL)
+	if e, ok := err.(*client.Error); !ok || e.Code != api.ErrUnauthorized {
+		t.Errorf("error = %v, want code %d", err, api.ErrUnauthorized)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	isolate(t)
+	prod := fakeWrapper(t, map[string]interface{}{api.PathLimits: api.LimitsResponse{}})
+	staging := fakeWrapper(t, map[string]interface{}{api.PathLimits: api.LimitsResponse{}})
+
+	// Flags may follow the profile name.
+	for _, args := range [][]string{
+		{"profile", "set", "prod", "--url", prod.URL, "--profile-token", "s3cr3t"},
+		{"profile", "set", "staging", "--url", staging.URL},
+	} {
+		if _, err := execute(t, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	// The first profile set is the current one.
+	out, err := execute(t, "profile", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "CURRENT   NAME      ENDPOINT\n*         prod      " + prod.URL + "\n          staging   " + staging.URL + "\n"; out != want {
+		t.Errorf("profile list printed %q, want %q", out, want)
+	}
+	if _, err := execute(t, "limits", "get"); err != nil {
+		t.Fatal(err)
+	}
+	if prod.path != api.PathLimits || prod.header.Get("Authorization") != "Bearer s3cr3t" {
+		t.Errorf("prod served %s with %q, want limits with the profile's token", prod.path, prod.header.Get("Authorization"))
+	}
+
+	// --profile, and the environment, pick another one.
+	if _, err := execute(t, "limits", "get", "--profile", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if staging.path != api.PathLimits || staging.header.Get("Authorization") != "" {
+		t.Errorf("staging served %s with %q, want limits without a token", staging.path, staging.header.Get("Authorization"))
+	}
+	staging.path = ""
+	setenv(t, "METADCTL_PROFILE", "staging")
+	if _, err := execute(t, "limits", "get"); err != nil {
+		t.Fatal(err)
+	}
+	if staging.path != api.PathLimits {
+		t.Errorf("METADCTL_PROFILE=staging sent limits to %q, want staging", staging.path)
+	}
+	setenv(t, "METADCTL_PROFILE", "")
+
+	if _, err := execute(t, "profile", "use", "dev"); err == nil {
+		t.Error("profile use dev succeeded, want an error")
+	}
+	if _, err := execute(t, "profile", "use", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := loadConfig(); err != nil || cfg.Current != "staging" {
+		t.Errorf("current profile = %+v, %v, want staging", cfg, err)
+	}
+
+	// Deleting the current profile leaves none current.
+	if _, err := execute(t, "profile", "delete", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, "limits", "get"); err == nil || !strings.Contains(err.Error(), "no endpoint") {
+		t.Errorf("error = %v, want no endpoint", err)
+	}
+}
+
+func TestRequireFlags(t *testing.T) {
+	if err := requireFlags(map[string]string{"instance": "nebula", "user": "root"}); err != nil {
+		t.Errorf("requireFlags with every flag set = %v", err)
+	}
+	// The first empty flag by name, whatever the map's order.
+	for i := 0; i < 10; i++ {
+		err := requireFlags(map[string]string{"user": "", "instance": "", "space": ""})
+		if err == nil || err.Error() != "--instance is required" {
+			t.Fatalf("requireFlags = %v, want --instance is required", err)
+		}
+	}
+}
@@ -0,0 +1,1067 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/api"
+)
+
+func newRootCommand() *command {
+	return &command{
+		name:  "metadctl",
+		short: "metadctl administers Nebula instances through the metad wrapper.",
+		sub: []*command{
+			{name: "spaces", short: "Work with graph spaces", sub: []*command{listSpacesCommand()}},
+			{name: "users", short: "Work with user accounts", sub: []*command{listUsersCommand()}},
+			{name: "roles", short: "Work with role grants", sub: []*command{listRolesCommand()}},
+			grantCommand(),
+			revokeCommand(),
+			{name: "god", short: "Manage the GOD account", sub: []*command{transferGodCommand()}},
+			costCommand(),
+			versionCommand(),
+			hostsCommand(),
+			balanceCommand(),
+			jobsCommand(),
+			snapshotsCommand(),
+			configsCommand(),
+			auditCommand(),
+			statusCommand(),
+			limitsCommand(),
+			directCommand(),
+			profileCommand(),
+			completionCommand(),
+		},
+	}
+}
+
+func listSpacesCommand() *command {
+	var instance, user string
+	list := &listOptions{}
+	return &command{
+		name:  "list",
+		short: "List the spaces a user holds a role in",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&instance, "instance", "", "instance ID")
+			fs.StringVar(&user, "user", "", "account whose spaces are listed")
+			list.flags(fs, "spaces", "role the user holds in the space")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": instance, "user": user}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.ListSpaces(env.ctx, api.ListSpaceRequest{InstanceID: instance, UserName: user,
+				Limit: list.limit, Cursor: list.cursor, Order: list.order, Prefix: list.prefix, Role: list.role})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"SPACE"}, footer: pageFooter(len(resp.Spaces), resp.Total, resp.NextCursor)}
+			for _, space := range resp.Spaces {
+				t.add(space)
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func listUsersCommand() *command {
+	req := api.ListUsersRequest{}
+	list := &listOptions{}
+	return &command{
+		name:  "list",
+		short: "List users holding a role in any space, with their roles",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&req.InstanceID, "instance", "", "instance ID")
+			list.flags(fs, "users", "role the user holds in some space")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": req.InstanceID}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			req.Limit, req.Cursor, req.Order, req.Prefix, req.Role = list.limit, list.cursor, list.order, list.prefix, list.role
+			resp, err := c.ListUsers(env.ctx, req)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"USER", "ROLES"}, footer: pageFooter(len(resp.Users), resp.Total, resp.NextCursor)}
+			for _, user := range resp.Users {
+				roles := make([]string, 0, len(user.Roles))
+				for _, role := range user.Roles {
+					if role.Space == "" {
+						roles = append(roles, role.Role)
+					} else {
+						roles = append(roles, role.Role+"@"+role.Space)
+					}
+				}
+				t.add(user.Name, strings.Join(roles, ","))
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+// listOptions are the paging and filter flags of the list commands.
+type listOptions struct {
+	limit                       int
+	cursor, order, prefix, role string
+}
+
+func (o *listOptions) flags(fs *flag.FlagSet, what, role string) {
+	fs.IntVar(&o.limit, "limit", 0, what+" per page, 100 if 0")
+	fs.StringVar(&o.cursor, "cursor", "", "cursor of the page to list, printed below the previous one")
+	fs.StringVar(&o.order, "order", "", "asc or desc by name")
+	fs.StringVar(&o.prefix, "prefix", "", "list only the "+what+" whose name starts with this")
+	fs.StringVar(&o.role, "role", "", "list only the "+what+" with this "+role)
+}
+
+// pageFooter tells how much of the total a page shows, and how to list the
+// next one.
+func pageFooter(shown, total int, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf("Showing %d of %d: run again with --cursor %s for more", shown, total, cursor)
+}
+
+func listRolesCommand() *command {
+	var instance, space, operator string
+	var root bool
+	list := &listOptions{}
+	return &command{
+		name:  "list",
+		short: "List role grants of a space, or global grants with --root",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&instance, "instance", "", "instance ID")
+			fs.StringVar(&space, "space", "", "space whose grants are listed")
+			fs.StringVar(&operator, "operator", "", "account the listing is performed as")
+			fs.BoolVar(&root, "root", false, "list global (GOD) grants instead of a space's")
+			list.flags(fs, "grants", "role")
+		},
+		run: func(env *environment, args []string) error {
+			required := map[string]string{"instance": instance}
+			if !root {
+				required["space"] = space
+				required["operator"] = operator
+			}
+			if err := requireFlags(required); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+
+			var resp *api.ListUserResponse
+			if root {
+				resp, err = c.ListRootSpaceUsers(env.ctx, api.ListRootUserRequest{InstanceID: instance,
+					Limit: list.limit, Cursor: list.cursor, Order: list.order, Prefix: list.prefix, Role: list.role})
+			} else {
+				resp, err = c.ListSpaceUsers(env.ctx, api.ListUserRequest{InstanceID: instance, SpaceName: space, Operator: operator,
+					Limit: list.limit, Cursor: list.cursor, Order: list.order, Prefix: list.prefix, Role: list.role})
+			}
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"USER", "ROLE"}, footer: pageFooter(len(resp.Users), resp.Total, resp.NextCursor)}
+			for _, userRole := range resp.Users {
+				t.add(userRole.User, userRole.Role)
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func grantCommand() *command {
+	req := api.CreateUserRequest{}
+	return &command{
+		name:  "grant",
+		short: "Create a user if needed and grant it a role in a space",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&req.InstanceID, "instance", "", "instance ID")
+			fs.StringVar(&req.UserName, "user", "", "account receiving the role")
+			fs.StringVar(&req.SpaceName, "space", "", "space the role applies to")
+			fs.StringVar(&req.Role, "role", "", "GOD, ADMIN, DBA, USER or GUEST")
+			fs.StringVar(&req.Account, "account", "", "account performing the grant")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": req.InstanceID, "user": req.UserName,
+				"space": req.SpaceName, "role": req.Role, "account": req.Account}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.CreateUser(env.ctx, req)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"USER", "SPACE", "ROLE"}}
+			t.add(req.UserName, req.SpaceName, req.Role)
+			return env.print(resp, t)
+		},
+	}
+}
+
+func revokeCommand() *command {
+	req := api.RevokeUserRequest{}
+	return &command{
+		name:  "revoke",
+		short: "Revoke a user's role in a space",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&req.InstanceID, "instance", "", "instance ID")
+			fs.StringVar(&req.UserName, "user", "", "account losing the role")
+			fs.StringVar(&req.Space, "space", "", "space the role applies to")
+			fs.StringVar(&req.Role, "role", "", "GOD, ADMIN, DBA, USER or GUEST")
+			fs.StringVar(&req.Account, "account", "", "account performing the revoke")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": req.InstanceID, "user": req.UserName,
+				"space": req.Space, "role": req.Role, "account": req.Account}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.RevokeUser(env.ctx, req)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"USER", "SPACE", "REVOKED"}}
+			t.add(req.UserName, req.Space, req.Role)
+			return env.print(resp, t)
+		},
+	}
+}
+
+func transferGodCommand() *command {
+	req := api.TransferGodUserRequest{}
+	return &command{
+		name:  "transfer",
+		short: "Move the GOD role from one account to a new one",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&req.InstanceID, "instance", "", "instance ID")
+			fs.StringVar(&req.UserName, "to", "", "account receiving GOD")
+			fs.StringVar(&req.OldName, "from", "", "current GOD account, dropped afterwards")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": req.InstanceID, "to": req.UserName,
+				"from": req.OldName}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.ChangeGod(env.ctx, req)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"INSTANCE", "GOD"}}
+			t.add(req.InstanceID, req.UserName)
+			return env.print(resp, t)
+		},
+	}
+}
+
+func costCommand() *command {
+	window := api.BillingWindow{}
+	var start, end string
+	return &command{
+		name:  "cost",
+		short: "Show resource usage and cost of the cluster and its instances",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&start, "start", "", "RFC3339 start of the billing window, the beginning of the month if empty")
+			fs.StringVar(&end, "end", "", "RFC3339 end of the billing window, now if empty")
+		},
+		run: func(env *environment, args []string) error {
+			for _, opt := range []struct {
+				name, value string
+				t           *time.Time
+			}{{"start", start, &window.Start}, {"end", end, &window.End}} {
+				if opt.value == "" {
+					continue
+				}
+				parsed, err := time.Parse(time.RFC3339, opt.value)
+				if err != nil {
+					return fmt.Errorf("--%s: %v", opt.name, err)
+				}
+				*opt.t = parsed
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.ClusterCost(env.ctx, window)
+			if err != nil {
+				return err
+			}
+
+			cost := func(c api.Cost) string {
+				return strconv.FormatFloat(c.Total, 'f', 2, 64) + " " + resp.ClusterCost.Currency
+			}
+			t := &table{header: []string{"INSTANCE", "COMPONENT", "REPLICAS", "CPU (m)", "CPU LIMIT", "CPU USAGE", "MEMORY (MiB)", "MEMORY LIMIT", "MEMORY USAGE", "DISKS", "COST"}}
+			for _, instance := range resp.ClusterCost.Instances {
+				t.add(instance.InstanceName, "", "",
+					strconv.FormatInt(instance.Cpu, 10),
+					strconv.FormatInt(instance.CpuLimit, 10),
+					strconv.FormatInt(instance.CpuUsage, 10),
+					strconv.FormatInt(instance.Memory, 10),
+					strconv.FormatInt(instance.MemoryLimit, 10),
+					strconv.FormatInt(instance.MemoryUsage, 10),
+					strconv.Itoa(len(instance.Disks)),
+					cost(instance.Cost))
+				for _, component := range instance.Components {
+					t.add("", component.Component, strconv.Itoa(component.Replicas),
+						strconv.FormatInt(component.Cpu, 10),
+						strconv.FormatInt(component.CpuLimit, 10),
+						strconv.FormatInt(component.CpuUsage, 10),
+						strconv.FormatInt(component.Memory, 10),
+						strconv.FormatInt(component.MemoryLimit, 10),
+						strconv.FormatInt(component.MemoryUsage, 10),
+						"", "")
+				}
+			}
+			t.add("TOTAL", "", "", "", "", "", "", "", "", "", cost(resp.ClusterCost.Cost))
+			return env.print(resp, t)
+		},
+	}
+}
+
+func statusCommand() *command {
+	var instances bool
+	return &command{
+		name:  "status",
+		short: "Show whether the wrapper's dependencies are reachable",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&instances, "instances", false, "also check the metad of every instance")
+		},
+		run: func(env *environment, args []string) error {
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.Status(env.ctx, instances)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"NAME", "STATUS", "LATENCY", "ERROR"}}
+			for _, status := range append(resp.Dependencies, resp.Instances...) {
+				t.add(status.Name, status.Status, strconv.FormatFloat(status.Latency, 'f', 3, 64)+"s", status.Error)
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func limitsCommand() *command {
+	limits := api.Limits{}
+	limitsTable := func(resp *api.LimitsResponse) *table {
+		t := &table{header: []string{"LIMIT", "VALUE"}}
+		t.add("caller rate", strconv.FormatFloat(resp.Limits.CallerRate, 'f', -1, 64))
+		t.add("caller burst", strconv.Itoa(resp.Limits.CallerBurst))
+		t.add("instance rate", strconv.FormatFloat(resp.Limits.InstanceRate, 'f', -1, 64))
+		t.add("instance burst", strconv.Itoa(resp.Limits.InstanceBurst))
+		t.add("instance concurrency", strconv.Itoa(resp.Limits.InstanceConcurrency))
+		return t
+	}
+	return &command{
+		name:  "limits",
+		short: "Show or change the wrapper's rate and concurrency limits",
+		sub: []*command{
+			{
+				name:  "get",
+				short: "Show the limits in force",
+				run: func(env *environment, args []string) error {
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.Limits(env.ctx)
+					if err != nil {
+						return err
+					}
+					return env.print(resp, limitsTable(resp))
+				},
+			},
+			{
+				name:  "set",
+				short: "Replace the limits on every replica, with an admin token; a zero rate or concurrency means no limit",
+				flags: func(fs *flag.FlagSet) {
+					fs.Float64Var(&limits.CallerRate, "caller-rate", 10, "requests per second allowed to each caller")
+					fs.IntVar(&limits.CallerBurst, "caller-burst", 20, "requests each caller may send at once")
+					fs.Float64Var(&limits.InstanceRate, "instance-rate", 20, "requests per second allowed to each instance")
+					fs.IntVar(&limits.InstanceBurst, "instance-burst", 40, "requests each instance may receive at once")
+					fs.IntVar(&limits.InstanceConcurrency, "instance-concurrency", 8, "requests working on each instance at the same time")
+				},
+				run: func(env *environment, args []string) error {
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.SetLimits(env.ctx, limits)
+					if err != nil {
+						return err
+					}
+					return env.print(resp, limitsTable(resp))
+				},
+			},
+		},
+	}
+}
+
+func versionCommand() *command {
+	var instance string
+	return &command{
+		name:  "version",
+		short: "Show component versions and disk usage of an instance",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&instance, "instance", "", "instance ID")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.InstanceVersion(env.ctx, api.InstanceInfoRequest{InstanceID: instance})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"COMPONENT", "POD", "STATUS", "VERSION", "COMMIT", "BUILD TIME", "DISK USAGE"}}
+			for _, info := range resp.Infos {
+				version := info.Version
+				if info.VersionSkew {
+					version += " (skew)"
+				}
+				t.add(info.Component, info.Pod, info.Status, version, info.CommitID, info.BuildTime, strconv.FormatInt(info.DiskUsage, 10))
+			}
+			if resp.VersionSkew {
+				t.footer = "Replicas marked (skew) run another build than the rest of their component."
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func hostsCommand() *command {
+	var instance string
+	return &command{
+		name:  "hosts",
+		short: "Show the storage hosts of an instance and their partitions",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&instance, "instance", "", "instance ID")
+		},
+		run: func(env *environment, args []string) error {
+			if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+				return err
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.StorageHosts(env.ctx, api.StorageHostsRequest{InstanceID: instance})
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"HOST", "STATUS", "LAST SEEN ONLINE", "POD", "NODE", "LEADERS", "PARTITIONS", "SPACES"}}
+			for _, host := range resp.Hosts {
+				spaces := make([]string, 0, len(host.Spaces))
+				for _, space := range host.Spaces {
+					spaces = append(spaces, fmt.Sprintf("%s:%d/%d", space.Space, space.Leaders, space.Partitions))
+				}
+				t.add(host.Host, host.Status, host.LastSeenOnline, host.Pod, host.Node,
+					strconv.Itoa(host.Leaders), strconv.Itoa(host.Partitions), strings.Join(spaces, ","))
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func balanceCommand() *command {
+	var instance string
+	var plan int64
+	instanceFlag := func(fs *flag.FlagSet) {
+		fs.StringVar(&instance, "instance", "", "instance ID")
+	}
+	planTable := func(resp *api.BalanceResponse) *table {
+		t := &table{header: []string{"TASK", "RESULT"}}
+		for _, task := range resp.Plan.Tasks {
+			t.add(task.ID, task.Result)
+		}
+		if resp.Balanced {
+			t.footer = "Partitions are balanced already."
+		} else {
+			t.footer = fmt.Sprintf("Plan %d %s: %d/%d tasks succeeded, %d failed, %d in progress.", resp.Plan.ID,
+				strings.ToLower(resp.Plan.Status), resp.Plan.Succeeded, resp.Plan.Total, resp.Plan.Failed, resp.Plan.InProgress)
+		}
+		return t
+	}
+	return &command{
+		name:  "balance",
+		short: "Balance partitions or leaders across the storage hosts",
+		sub: []*command{
+			{
+				name:  "data",
+				short: "Start moving partitions evenly across the storage hosts",
+				flags: instanceFlag,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.BalanceData(env.ctx, api.BalanceRequest{InstanceID: instance})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, planTable(resp))
+				},
+			},
+			{
+				name:  "leader",
+				short: "Spread the partition leaders evenly across the storage hosts",
+				flags: instanceFlag,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.BalanceLeader(env.ctx, api.BalanceRequest{InstanceID: instance})
+					if err != nil {
+						return err
+					}
+					t := &table{header: []string{"INSTANCE", "LEADERS"}}
+					t.add(instance, "balanced")
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "stop",
+				short: "Stop the running data balance plan once its current task is done",
+				flags: instanceFlag,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.StopBalance(env.ctx, api.BalanceRequest{InstanceID: instance})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, planTable(resp))
+				},
+			},
+			{
+				name:  "status",
+				short: "Show the progress of a data balance plan",
+				flags: func(fs *flag.FlagSet) {
+					instanceFlag(fs)
+					fs.Int64Var(&plan, "plan", 0, "plan ID, the last one started through the wrapper if 0")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.BalanceStatus(env.ctx, api.BalanceStatusRequest{InstanceID: instance, PlanID: plan})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, planTable(resp))
+				},
+			},
+		},
+	}
+}
+
+func jobsCommand() *command {
+	var instance, space, jobCommand string
+	var job int
+	return &command{
+		name:  "jobs",
+		short: "Run compaction and flush jobs on spaces",
+		sub: []*command{
+			{
+				name:  "submit",
+				short: "Submit a job on a space",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&instance, "instance", "", "instance ID")
+					fs.StringVar(&space, "space", "", "space to run the job on")
+					fs.StringVar(&jobCommand, "command", "", "compact or flush")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance, "space": space, "command": jobCommand}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.SubmitJob(env.ctx, api.SubmitJobRequest{InstanceID: instance, SpaceName: space, Command: jobCommand})
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"JOB", "COMMAND", "SPACE"}}
+					t.add(strconv.Itoa(int(resp.JobID)), jobCommand, space)
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "list",
+				short: "List the jobs of an instance, newest first",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&instance, "instance", "", "instance ID")
+					fs.StringVar(&space, "space", "", "only jobs on this space")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.ListJobs(env.ctx, api.ListJobsRequest{InstanceID: instance, SpaceName: space})
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"JOB", "COMMAND", "SPACE", "STATUS", "STARTED", "STOPPED"}}
+					for _, job := range resp.Jobs {
+						t.add(strconv.Itoa(int(job.ID)), job.Command, job.Space, job.Status, job.StartTime, job.StopTime)
+					}
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "show",
+				short: "Show a job and the task each storage host runs for it",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&instance, "instance", "", "instance ID")
+					fs.IntVar(&job, "job", 0, "job ID")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.ShowJob(env.ctx, api.JobRequest{InstanceID: instance, JobID: int32(job)})
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"TASK", "HOST", "STATUS", "STARTED", "STOPPED"}}
+					for _, task := range resp.Tasks {
+						t.add(strconv.Itoa(int(task.ID)), task.Host, task.Status, task.StartTime, task.StopTime)
+					}
+					t.footer = fmt.Sprintf("Job %d (%s on %s) %s.", resp.Job.ID, resp.Job.Command, resp.Job.Space, strings.ToLower(resp.Job.Status))
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "stop",
+				short: "Stop a queued or running job",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&instance, "instance", "", "instance ID")
+					fs.IntVar(&job, "job", 0, "job ID")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.StopJob(env.ctx, api.JobRequest{InstanceID: instance, JobID: int32(job)})
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"JOB", "STOPPED"}}
+					t.add(strconv.Itoa(job), "true")
+					return env.print(resp, t)
+				},
+			},
+		},
+	}
+}
+
+func snapshotsCommand() *command {
+	var instance, name string
+	policy := api.SetSnapshotPolicyRequest{}
+	var maxAge time.Duration
+	instanceFlag := func(fs *flag.FlagSet) {
+		fs.StringVar(&instance, "instance", "", "instance ID")
+	}
+	snapshotTable := func(snapshots ...api.Snapshot) *table {
+		t := &table{header: []string{"SNAPSHOT", "STATUS", "CREATED", "HOSTS"}}
+		for _, snapshot := range snapshots {
+			hosts := make([]string, 0, len(snapshot.Hosts))
+			for _, host := range snapshot.Hosts {
+				hosts = append(hosts, host.Host+"("+strings.ToLower(host.HostStatus)+")")
+			}
+			t.add(snapshot.Name, snapshot.Status, snapshot.CreateTime, strings.Join(hosts, ","))
+		}
+		return t
+	}
+	return &command{
+		name:  "snapshots",
+		short: "Take, list and drop snapshots of an instance and set their retention",
+		sub: []*command{
+			{
+				name:  "create",
+				short: "Take a snapshot of an instance",
+				flags: instanceFlag,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.CreateSnapshot(env.ctx, api.CreateSnapshotRequest{InstanceID: instance})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, snapshotTable(resp.Snapshot))
+				},
+			},
+			{
+				name:  "list",
+				short: "List the snapshots of an instance, newest first",
+				flags: instanceFlag,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.ListSnapshots(env.ctx, api.SnapshotRequest{InstanceID: instance})
+					if err != nil {
+						return err
+					}
+
+					t := snapshotTable(resp.Snapshots...)
+					if resp.Policy != (api.SnapshotPolicy{}) {
+						t.footer = fmt.Sprintf("Retention: keep last %d, drop older than %v (0 is no limit).",
+							resp.Policy.KeepLast, time.Duration(resp.Policy.MaxAgeSeconds)*time.Second)
+					}
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "drop",
+				short: "Drop a snapshot",
+				flags: func(fs *flag.FlagSet) {
+					instanceFlag(fs)
+					fs.StringVar(&name, "snapshot", "", "snapshot name")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance, "snapshot": name}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.DropSnapshot(env.ctx, api.DropSnapshotRequest{InstanceID: instance, Snapshot: name})
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"SNAPSHOT", "DROPPED"}}
+					t.add(name, "true")
+					return env.print(resp, t)
+				},
+			},
+			{
+				name:  "policy",
+				short: "Set the retention of the snapshots of an instance; zero keeps every snapshot",
+				flags: func(fs *flag.FlagSet) {
+					instanceFlag(fs)
+					fs.IntVar(&policy.KeepLast, "keep-last", 0, "number of newest snapshots to keep, 0 for no limit")
+					fs.DurationVar(&maxAge, "max-age", 0, "drop snapshots older than this, 0 for no limit")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					policy.InstanceID = instance
+					policy.MaxAgeSeconds = int64(maxAge / time.Second)
+					resp, err := c.SetSnapshotPolicy(env.ctx, policy)
+					if err != nil {
+						return err
+					}
+
+					t := &table{header: []string{"INSTANCE", "KEEP LAST", "MAX AGE"}}
+					t.add(instance, strconv.Itoa(resp.Policy.KeepLast), (time.Duration(resp.Policy.MaxAgeSeconds) * time.Second).String())
+					return env.print(resp, t)
+				},
+			},
+		},
+	}
+}
+
+func configsCommand() *command {
+	var instance, module, name, value string
+	var all bool
+	configTable := func(configs ...api.ConfigItem) *table {
+		t := &table{header: []string{"MODULE", "NAME", "TYPE", "MODE", "VALUE"}}
+		for _, config := range configs {
+			t.add(config.Module, config.Name, config.Type, config.Mode, config.Value)
+		}
+		return t
+	}
+	configFlags := func(fs *flag.FlagSet) {
+		fs.StringVar(&instance, "instance", "", "instance ID")
+		fs.StringVar(&module, "module", "", "GRAPH, META or STORAGE")
+		fs.StringVar(&name, "name", "", "flag name")
+	}
+	return &command{
+		name:  "configs",
+		short: "Show and change the flags of the Nebula components at runtime",
+		sub: []*command{
+			{
+				name:  "list",
+				short: "List the mutable flags of the components",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&instance, "instance", "", "instance ID")
+					fs.StringVar(&module, "module", "", "only flags of GRAPH, META or STORAGE")
+					fs.BoolVar(&all, "all", false, "list the flags that can't be changed too")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.ListConfigs(env.ctx, api.ListConfigsRequest{InstanceID: instance, Module: module, All: all})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, configTable(resp.Configs...))
+				},
+			},
+			{
+				name:  "get",
+				short: "Show a flag",
+				flags: configFlags,
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance, "module": module, "name": name}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.GetConfig(env.ctx, api.GetConfigRequest{InstanceID: instance, Module: module, Name: name})
+					if err != nil {
+						return err
+					}
+					return env.print(resp, configTable(resp.Config))
+				},
+			},
+			{
+				name:  "set",
+				short: "Change a mutable flag",
+				flags: func(fs *flag.FlagSet) {
+					configFlags(fs)
+					fs.StringVar(&value, "value", "", "new value, of the flag's type")
+				},
+				run: func(env *environment, args []string) error {
+					if err := requireFlags(map[string]string{"instance": instance, "module": module, "name": name}); err != nil {
+						return err
+					}
+					c, err := env.client()
+					if err != nil {
+						return err
+					}
+					resp, err := c.SetConfig(env.ctx, api.SetConfigRequest{InstanceID: instance, Module: module, Name: name, Value: value})
+					if err != nil {
+						return err
+					}
+
+					t := configTable(resp.Config)
+					t.footer = "Previous value: " + resp.PreviousValue
+					return env.print(resp, t)
+				},
+			},
+		},
+	}
+}
+
+func auditCommand() *command {
+	filter := api.AuditFilter{}
+	var since, until string
+	return &command{
+		name:  "audit",
+		short: "Show the audit trail of mutating operations, with an admin token",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&filter.InstanceID, "instance", "", "only events of this instance")
+			fs.StringVar(&filter.Operation, "operation", "", "only events of this operation, e.g. CreateUser")
+			fs.StringVar(&filter.Caller, "caller", "", "only events sent by this caller")
+			fs.StringVar(&filter.User, "user", "", "only events acting as or on this account")
+			fs.StringVar(&filter.Result, "result", "", "success or failure")
+			fs.StringVar(&since, "since", "", "only events at or after this RFC3339 time")
+			fs.StringVar(&until, "until", "", "only events before this RFC3339 time")
+			fs.IntVar(&filter.Limit, "limit", 100, "only the most recent events, 0 for all")
+		},
+		run: func(env *environment, args []string) error {
+			for _, opt := range []struct {
+				name, value string
+				t           *time.Time
+			}{{"since", since, &filter.Since}, {"until", until, &filter.Until}} {
+				if opt.value == "" {
+					continue
+				}
+				parsed, err := time.Parse(time.RFC3339, opt.value)
+				if err != nil {
+					return fmt.Errorf("--%s: %v", opt.name, err)
+				}
+				*opt.t = parsed
+			}
+			c, err := env.client()
+			if err != nil {
+				return err
+			}
+			resp, err := c.Audit(env.ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			t := &table{header: []string{"TIME", "OPERATION", "CALLER", "INSTANCE", "TARGETS", "RESULT"}}
+			for _, event := range resp.Events {
+				targets := []string{}
+				for _, target := range event.Targets {
+					targets = append(targets, target.User)
+				}
+				t.add(event.Time.Format(time.RFC3339), event.Operation, event.Caller, event.InstanceID,
+					strings.Join(targets, ","), event.Result)
+			}
+			return env.print(resp, t)
+		},
+	}
+}
+
+func profileCommand() *command {
+	var endpoint, token string
+	return &command{
+		name:  "profile",
+		short: "Manage wrapper endpoint profiles",
+		sub: []*command{
+			{
+				name:  "list",
+				short: "List configured profiles",
+				run: func(env *environment, args []string) error {
+					t := &table{header: []string{"CURRENT", "NAME", "ENDPOINT"}}
+					for _, name := range env.config.names() {
+						current := ""
+						if name == env.config.Current {
+							current = "*"
+						}
+						t.add(current, name, env.config.Profiles[name].Endpoint)
+					}
+					return env.print(env.config, t)
+				},
+			},
+			{
+				name:  "set",
+				short: "Create or update a profile: profile set NAME --url URL [--profile-token TOKEN]",
+				flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&endpoint, "url", "", "wrapper URL of the profile")
+					fs.StringVar(&token, "profile-token", "", "bearer token the profile authenticates with")
+				},
+				run: func(env *environment, args []string) error {
+					if len(args) != 1 {
+						return fmt.Errorf("expected exactly one profile name")
+					}
+					if err := requireFlags(map[string]string{"url": endpoint}); err != nil {
+						return err
+					}
+					env.config.Profiles[args[0]] = &profile{Endpoint: endpoint, Token: token}
+					if env.config.Current == "" {
+						env.config.Current = args[0]
+					}
+					return env.config.save()
+				},
+			},
+			{
+				name:  "use",
+				short: "Make a profile the default: profile use NAME",
+				run: func(env *environment, args []string) error {
+					if len(args) != 1 {
+						return fmt.Errorf("expected exactly one profile name")
+					}
+					if _, ok := env.config.Profiles[args[0]]; !ok {
+						return fmt.Errorf("profile %q not found", args[0])
+					}
+					env.config.Current = args[0]
+					return env.config.save()
+				},
+			},
+			{
+				name:  "delete",
+				short: "Delete a profile: profile delete NAME",
+				run: func(env *environment, args []string) error {
+					if len(args) != 1 {
+						return fmt.Errorf("expected exactly one profile name")
+					}
+					delete(env.config.Profiles, args[0])
+					if env.config.Current == args[0] {
+						env.config.Current = ""
+					}
+					return env.config.save()
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func completionCommand() *command {
+	return &command{
+		name:  "completion",
+		short: "Print a shell completion script: completion bash|zsh",
+		run: func(env *environment, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected one shell name: bash or zsh")
+			}
+			switch args[0] {
+			case "bash":
+				fmt.Print(bashCompletion(env.root))
+			case "zsh":
+				fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(env.root))
+			default:
+				return fmt.Errorf("unsupported shell %q", args[0])
+			}
+			return nil
+		},
+	}
+}
+
+// bashCompletion renders a completion function with one case per command
+// path, listing the subcommands or flags valid at that point.
+func bashCompletion(root *command) string {
+	cases := map[string][]string{}
+	var walk func(c *command, path []string)
+	walk = func(c *command, path []string) {
+		key := strings.Join(path, " ")
+		if len(c.sub) != 0 {
+			for _, sub := range c.sub {
+				cases[key] = append(cases[key], sub.name)
+				walk(sub, append(append([]string{}, path...), sub.name))
+			}
+			return
+		}
+		fs := c.flagSet(path, &globalOptions{})
+		fs.VisitAll(func(f *flag.Flag) {
+			cases[key] = append(cases[key], flagName(f))
+		})
+	}
+	walk(root, []string{"metadctl"})
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := &strings.Builder{}
+	b.WriteString(`_metadctl() {
+    local cur path word
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    path="metadctl"
+    for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do
+        case "$word" in
+            -*) ;;
+            *) case "$path $word" in
+`)
+	for _, key := range keys {
+		if key != "metadctl" {
+			fmt.Fprintf(b, "                %q) path=\"%s\" ;;\n", key, key)
+		}
+	}
+	b.WriteString(`               esac ;;
+        esac
+    done
+    case "$path" in
+`)
+	for _, key := range keys {
+		fmt.Fprintf(b, "        %q) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", key, strings.Join(cases[key], " "))
+	}
+	b.WriteString(`    esac
+}
+complete -F _metadctl metadctl
+`)
+	return b.String()
+}
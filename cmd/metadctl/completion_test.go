@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBashCompletion(t *testing.T) {
+	script := bashCompletion(newRootCommand())
+
+	for _, want := range []string{
+		// Subcommands are completed at every level.
+		`"metadctl") COMPREPLY=($(compgen -W "spaces users roles grant revoke god`,
+		`"metadctl direct users") COMPREPLY=($(compgen -W "list create drop" -- "$cur")) ;;`,
+		// Words typed move the completion down the tree.
+		`"metadctl jobs submit") path="metadctl jobs submit" ;;`,
+		// Commands complete their own flags and the global ones.
+		`"metadctl spaces list") COMPREPLY=($(compgen -W "--cursor --endpoint --instance --limit --no-cache -o --order --prefix --profile --role --timeout --token --user" -- "$cur")) ;;`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("completion script lacks %s", want)
+		}
+	}
+}
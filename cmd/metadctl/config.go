@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// config is stored in $METADCTL_CONFIG or ~/.metadctl.yaml:
+//
+//	current: prod
+//	profiles:
+//	  prod:
+//	    endpoint: http://metad-wapper.prod:8880
+//	    token: s3cr3t
+//	  staging:
+//	    endpoint: http://metad-wapper.staging:8880
+type config struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string]*profile `json:"profiles,omitempty"`
+}
+
+type profile struct {
+	Endpoint string `json:"endpoint"`
+	// Token is the bearer token sent to the wrapper, which the admin
+	// commands need.
+	Token string `json:"token,omitempty"`
+}
+
+func configPath() string {
+	if path := os.Getenv("METADCTL_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".metadctl.yaml"
+	}
+	return filepath.Join(home, ".metadctl.yaml")
+}
+
+func loadConfig() (*config, error) {
+	cfg := &config{Profiles: map[string]*profile{}}
+
+	data, err := ioutil.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", configPath(), err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	return cfg, nil
+}
+
+func (c *config) save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configPath(), data, 0600)
+}
+
+// profile returns the named profile, or the current one if name is empty.
+func (c *config) profile(name string) (*profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return &profile{}, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in %s", name, configPath())
+	}
+	return p, nil
+}
+
+func (c *config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// isolate points metadctl at a config file of its own, absent until saved,
+// and clears the environment that would override it.
+func isolate(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "metadctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	setenv(t, "METADCTL_CONFIG", path)
+	for _, key := range []string{"METADCTL_PROFILE", "METADCTL_ENDPOINT", "METADCTL_TOKEN", "METADCTL_METAD"} {
+		setenv(t, key, "")
+	}
+	return path
+}
+
+// setenv sets an environment variable for the rest of the test.
+func setenv(t *testing.T, key, value string) {
+	previous, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	path := isolate(t)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig without a file: %v", err)
+	}
+	if cfg.Current != "" || len(cfg.Profiles) != 0 {
+		t.Errorf("loadConfig without a file = %+v, want no profiles", cfg)
+	}
+	if p, err := cfg.profile(""); err != nil || *p != (profile{}) {
+		t.Errorf("profile(\"\") without a current one = %+v, %v, want an empty profile", p, err)
+	}
+
+	cfg.Profiles["staging"] = &profile{Endpoint: "http://metad-wapper.staging:8880"}
+	cfg.Profiles["prod"] = &profile{Endpoint: "http://metad-wapper.prod:8880", Token: "s3cr3t"}
+	cfg.Current = "prod"
+	if err := cfg.save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, %v, want 0600 as it holds tokens", info.Mode().Perm(), err)
+	}
+
+	loaded, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("loadConfig = %+v, want %+v", loaded, cfg)
+	}
+	if names := loaded.names(); !reflect.DeepEqual(names, []string{"prod", "staging"}) {
+		t.Errorf("names = %v, want them sorted", names)
+	}
+	if p, err := loaded.profile(""); err != nil || p.Token != "s3cr3t" {
+		t.Errorf("profile(\"\") = %+v, %v, want the current one", p, err)
+	}
+	if p, err := loaded.profile("staging"); err != nil || p.Endpoint != "http://metad-wapper.staging:8880" {
+		t.Errorf("profile(\"staging\") = %+v, %v", p, err)
+	}
+	if _, err := loaded.profile("dev"); err == nil {
+		t.Error("profile(\"dev\") succeeded, want an error")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("profiles: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig of invalid YAML succeeded, want an error")
+	}
+}
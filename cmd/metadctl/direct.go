@@ -0,0 +1,248 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/vesoft-inc-private/nebula-operator/cmd/metad-wapper/utils"
+)
+
+// The direct commands talk to a metad over Thrift instead of going through
+// the wrapper, for when the wrapper itself is unavailable. They need neither
+// Kubernetes nor a profile, only the metad's host:port.
+
+func directCommand() *command {
+	return &command{
+		name:  "direct",
+		short: "Run break-glass operations directly against a metad",
+		sub: []*command{
+			{name: "users", short: "Manage accounts", sub: []*command{
+				directListUsersCommand(),
+				directCreateUserCommand(),
+				directDropUserCommand(),
+			}},
+			{name: "spaces", short: "Inspect graph spaces", sub: []*command{directListSpacesCommand()}},
+			{name: "roles", short: "Inspect an account's roles", sub: []*command{directUserRolesCommand()}},
+			directGrantCommand(),
+			directRevokeCommand(),
+		},
+	}
+}
+
+// metadFlag registers --metad on fs; useMetad then points the utils library
+// at the given address.
+func metadFlag(fs *flag.FlagSet, addr *string) {
+	fs.StringVar(addr, "metad", os.Getenv("METADCTL_METAD"), "metad host:port, e.g. 10.0.0.5:44500")
+}
+
+func useMetad(addr string) error {
+	if err := requireFlags(map[string]string{"metad": addr}); err != nil {
+		return err
+	}
+	utils.SetMetadAddress(addr)
+	return nil
+}
+
+func directListUsersCommand() *command {
+	var addr string
+	return &command{
+		name:  "list",
+		short: "List every account",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			users, err := utils.ListUsers(env.ctx, "")
+			if err != nil {
+				return err
+			}
+			sort.Strings(users)
+
+			t := &table{header: []string{"USER"}}
+			for _, user := range users {
+				t.add(user)
+			}
+			return env.print(users, t)
+		},
+	}
+}
+
+func directCreateUserCommand() *command {
+	var addr, user string
+	return &command{
+		name:  "create",
+		short: "Create an account",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+			fs.StringVar(&user, "user", "", "account to create")
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			if err := requireFlags(map[string]string{"user": user}); err != nil {
+				return err
+			}
+			if err := utils.CreateUser(env.ctx, "", user); err != nil {
+				return err
+			}
+			fmt.Printf("user %s created\n", user)
+			return nil
+		},
+	}
+}
+
+func directDropUserCommand() *command {
+	var addr, user string
+	return &command{
+		name:  "drop",
+		short: "Drop an account and all of its roles",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+			fs.StringVar(&user, "user", "", "account to drop")
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			if err := requireFlags(map[string]string{"user": user}); err != nil {
+				return err
+			}
+			if err := utils.DropUser(env.ctx, "", user); err != nil {
+				return err
+			}
+			fmt.Printf("user %s dropped\n", user)
+			return nil
+		},
+	}
+}
+
+func directListSpacesCommand() *command {
+	var addr string
+	return &command{
+		name:  "list",
+		short: "List every space",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			spaces, err := utils.ListSpaces(env.ctx, "")
+			if err != nil {
+				return err
+			}
+			sort.Strings(spaces)
+
+			t := &table{header: []string{"SPACE"}}
+			for _, space := range spaces {
+				t.add(space)
+			}
+			return env.print(spaces, t)
+		},
+	}
+}
+
+func directUserRolesCommand() *command {
+	var addr, user string
+	return &command{
+		name:  "get",
+		short: "Show every role an account holds",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+			fs.StringVar(&user, "user", "", "account to inspect")
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			if err := requireFlags(map[string]string{"user": user}); err != nil {
+				return err
+			}
+			roles, err := utils.ListUserRoles(env.ctx, "", user)
+			if err != nil {
+				return err
+			}
+
+			type userRole struct {
+				SpaceID int32  `json:"spaceID"`
+				Role    string `json:"role"`
+			}
+			res := []userRole{}
+			t := &table{header: []string{"SPACE ID", "ROLE"}}
+			for _, role := range roles {
+				res = append(res, userRole{SpaceID: int32(role.SpaceID), Role: utils.RoleName(role.RoleType)})
+				t.add(fmt.Sprint(role.SpaceID), utils.RoleName(role.RoleType))
+			}
+			return env.print(res, t)
+		},
+	}
+}
+
+func directGrantCommand() *command {
+	var addr, user, space, role string
+	return &command{
+		name:  "grant",
+		short: "Grant a role in a space, or a global role if --space is omitted",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+			fs.StringVar(&user, "user", "", "account receiving the role")
+			fs.StringVar(&space, "space", "", "space the role applies to")
+			fs.StringVar(&role, "role", "", "GOD, ADMIN, DBA, USER or GUEST")
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			if err := requireFlags(map[string]string{"user": user, "role": role}); err != nil {
+				return err
+			}
+			roleType, err := utils.ParseRole(role)
+			if err != nil {
+				return err
+			}
+			if err := utils.GrantRole(env.ctx, "", user, space, roleType); err != nil {
+				return err
+			}
+			fmt.Printf("granted %s to %s\n", role, user)
+			return nil
+		},
+	}
+}
+
+func directRevokeCommand() *command {
+	var addr, user, space, role string
+	return &command{
+		name:  "revoke",
+		short: "Revoke a role in a space, or a global role if --space is omitted",
+		flags: func(fs *flag.FlagSet) {
+			metadFlag(fs, &addr)
+			fs.StringVar(&user, "user", "", "account losing the role")
+			fs.StringVar(&space, "space", "", "space the role applies to")
+			fs.StringVar(&role, "role", "", "GOD, ADMIN, DBA, USER or GUEST")
+		},
+		run: func(env *environment, args []string) error {
+			if err := useMetad(addr); err != nil {
+				return err
+			}
+			if err := requireFlags(map[string]string{"user": user, "role": role}); err != nil {
+				return err
+			}
+			roleType, err := utils.ParseRole(role)
+			if err != nil {
+				return err
+			}
+			if err := utils.RevokeRole(env.ctx, "", user, space, roleType); err != nil {
+				return err
+			}
+			fmt.Printf("revoked %s from %s\n", role, user)
+			return nil
+		},
+	}
+}
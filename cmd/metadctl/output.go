@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+// table is the tabular rendering of a result; the raw value is used for
+// json and yaml output so those show every field the wrapper returned.
+type table struct {
+	header []string
+	rows   [][]string
+	// footer is printed below the rows, such as how to get the next page.
+	footer string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (e *environment) print(raw interface{}, t *table) error {
+	switch e.opts.output {
+	case "json":
+		data, err := json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 3, ' ', 0)
+		printRow(w, t.header)
+		for _, row := range t.rows {
+			printRow(w, row)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if t.footer != "" {
+			fmt.Println()
+			fmt.Println(t.footer)
+		}
+	}
+	return nil
+}
+
+func printRow(w *tabwriter.Writer, cells []string) {
+	for i, cell := range cells {
+		if i != 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}